@@ -7,6 +7,7 @@
 package dns
 
 import (
+	"bytes"
 	"fmt"
 	"github.com/cznic/mathutil"
 	"net"
@@ -289,6 +290,168 @@ func TestRevLookupName(t *testing.T) {
 	}
 }
 
+func TestCanonicalName(t *testing.T) {
+	tab := []struct{ in, out string }{
+		{"", "."},
+		{".", "."},
+		{"Example.COM", "example.com."},
+		{"example.com.", "example.com."},
+		{`A\.B.Example.`, `a\.b.example.`},
+		{`Foo\.`, `foo\..`},
+		{`\065BC.example`, `\065bc.example.`},
+		{`\ABC.`, `\Abc.`},
+		{"ÄBC.", "Äbc."},
+		{`Tail\`, `tail\.`},
+	}
+	for i, v := range tab {
+		if g, e := CanonicalName(v.in), v.out; g != e {
+			t.Errorf("%d: %q: got %q, expected %q", i, v.in, g, e)
+		}
+	}
+}
+
+func TestNamesEqual(t *testing.T) {
+	tab := []struct {
+		a, b  string
+		equal bool
+	}{
+		{"", ".", true},
+		{"Example.COM", "example.com.", true},
+		{`A\.B.Example.`, `a\.b.example`, true},
+		{"example.com.", "example.org.", false},
+		{"example.com.", "www.example.com.", false},
+		{"ÄBC.", "äbc.", false},
+	}
+	for i, v := range tab {
+		if g, e := NamesEqual(v.a, v.b), v.equal; g != e {
+			t.Errorf("%d: %q %q: %t != %t", i, v.a, v.b, g, e)
+		}
+	}
+}
+
+func TestRandomizeCase(t *testing.T) {
+	const name = `www.long-label-with-many-letters.Example.COM.`
+	seen := map[string]bool{}
+	for i := 0; i < 10; i++ {
+		r := RandomizeCase(name)
+		if !NamesEqual(r, name) {
+			t.Fatalf("%q != %q", r, name)
+		}
+
+		seen[r] = true
+	}
+
+	if len(seen) < 2 {
+		t.Errorf("%q not randomized", name)
+	}
+
+	for _, s := range []string{`5bc.x.`, `a\.Z.`, "", ".", "123.example-4."} {
+		r := RandomizeCase(s)
+		if !NamesEqual(r, s) {
+			t.Errorf("%q != %q", r, s)
+		}
+
+		if strings.ToLower(r) != strings.ToLower(s) || len(r) != len(s) {
+			t.Errorf("%q: %q", s, r)
+		}
+	}
+
+	if g, e := RandomizeCase(`5\.`), `5\.`; g != e {
+		t.Errorf("%q != %q", g, e)
+	}
+}
+
+func TestCountLabels(t *testing.T) {
+	tab := []struct {
+		name     string
+		n        int
+		wildcard bool
+	}{
+		{"", 0, false},
+		{".", 0, false},
+		{"*", 0, true},
+		{"*.", 0, true},
+		{"com", 1, false},
+		{"example.com.", 2, false},
+		{"*.example.com.", 2, true},
+		{"*.example.com", 2, true},
+		{`a\.b.example.com.`, 3, false},
+		{`\*.example.com.`, 3, false},
+		{"a.*.example.com.", 4, false},
+		{`foo\\.example.`, 2, false},
+		{`a\046b.example.`, 2, false},
+		{`a\.`, 1, false},
+	}
+	for i, v := range tab {
+		if g, e := CountLabels(v.name), v.n; g != e {
+			t.Errorf("%d: %q: %d != %d", i, v.name, g, e)
+		}
+
+		if g, e := IsWildcard(v.name), v.wildcard; g != e {
+			t.Errorf("%d: %q: %t != %t", i, v.name, g, e)
+		}
+	}
+}
+
+func TestDomainName(t *testing.T) {
+	tab := []struct {
+		wire []byte
+		name string
+	}{
+		{[]byte{0}, "."},
+		{[]byte{1, 'a', 7, 'e', 'x', 'a', 'm', 'p', 'l', 'e', 0}, "a.example."},
+		{[]byte{3, 'a', '.', 'b', 7, 'e', 'x', 'a', 'm', 'p', 'l', 'e', 0}, `a\.b.example.`},
+		{[]byte{3, 'a', '\\', 'b', 0}, `a\\b.`},
+		{[]byte{4, 0, ' ', 0x7f, 200, 0}, `\000 \127\200.`},
+	}
+	for i, v := range tab {
+		var name DomainName
+		pos := 0
+		if err := name.Decode(v.wire, &pos, nil); err != nil {
+			t.Fatal(i, err)
+		}
+
+		if g, e := string(name), v.name; g != e {
+			t.Errorf("%d: %q != %q", i, g, e)
+		}
+
+		w := NewWirebuf()
+		w.DisableCompression()
+		name.Encode(w)
+		if g, e := w.Buf, v.wire; !bytes.Equal(g, e) {
+			t.Errorf("%d: % x != % x", i, g, e)
+		}
+	}
+
+	// Other escapes are resolved as well.
+	w := NewWirebuf()
+	DomainName(`\a\098\046.`).Encode(w)
+	if g, e := w.Buf, []byte{3, 'a', 'b', '.', 0}; !bytes.Equal(g, e) {
+		t.Errorf("% x != % x", g, e)
+	}
+}
+
+func TestWireDecodeSniffed(t *testing.T) {
+	// The values are exported, new tags must not renumber the old ones.
+	for _, v := range []struct {
+		tag WireDecodeSniffed
+		n   int
+	}{
+		{SniffCharString, 1},
+		{SniffRData, 15},
+		{SniffRDataGPOS, 26},
+		{SniffRDataTXT, 66},
+		{SniffRDataX25, 69},
+		{SniffRR, 70},
+		{SniffType, 71},
+		{SniffRDataHTTPS, 72},
+	} {
+		if g, e := int(v.tag), v.n; g != e {
+			t.Errorf("%d != %d", g, e)
+		}
+	}
+}
+
 func TestSeconds2String(t *testing.T) {
 	ti := time.Date(2012, 1, 2, 3, 4, 5, 0, time.UTC)
 	secs := ti.Unix()
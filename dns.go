@@ -8,6 +8,7 @@
 package dns
 
 import (
+	"crypto/rand"
 	"fmt"
 	"math"
 	"net"
@@ -32,9 +33,17 @@ func Host2domain(hostname string) (domainname string) {
 	return hostname[i+1:]
 }
 
-// IsRooted returns true if name ends in a '.'.
+// IsRooted returns true if name ends in a '.' which is not escaped.
 func IsRooted(name string) bool {
-	return name != "" && name[len(name)-1] == '.'
+	if name == "" || name[len(name)-1] != '.' {
+		return false
+	}
+
+	n := 0 // Backslashes before the dot.
+	for i := len(name) - 2; i >= 0 && name[i] == '\\'; i-- {
+		n++
+	}
+	return n%2 == 0
 }
 
 // Labels returns a domain name labels or an Error if any.
@@ -109,6 +118,104 @@ func RootedName(name string) string {
 	return name + "."
 }
 
+// CanonicalName returns name with ASCII letters folded to lower case and with
+// a trailing dot appended if name is not already rooted. Escaped octets, like
+// `\.` or `\065`, and non ASCII bytes are copied verbatim and no labels are
+// collapsed.
+func CanonicalName(name string) string {
+	b := make([]byte, 0, len(name)+1)
+	escaped := false
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		escaped = false
+		switch {
+		case c == '\\':
+			n := 1
+			if i+3 < len(name) && isDigit(name[i+1]) && isDigit(name[i+2]) && isDigit(name[i+3]) {
+				n = 3
+			}
+			if i+n >= len(name) {
+				n = len(name) - 1 - i
+			}
+			b = append(b, name[i:i+n+1]...)
+			i += n
+			escaped = true
+			continue
+		case c >= 'A' && c <= 'Z':
+			c += 'a' - 'A'
+		}
+		b = append(b, c)
+	}
+	if len(b) == 0 || escaped || b[len(b)-1] != '.' {
+		b = append(b, '.')
+	}
+	return string(b)
+}
+
+// NamesEqual reports whether the domain names a and b are the same name, i.e.
+// whether they compare equal after CanonicalName. The comparison ignores ASCII
+// case and a missing trailing dot.
+func NamesEqual(a, b string) bool {
+	return CanonicalName(a) == CanonicalName(b)
+}
+
+// RandomizeCase returns name with every ASCII letter randomly changed to upper
+// or lower case, as used by the "0x20" resolver hardening
+// (draft-vixie-dnsext-dns0x20-00). The result compares equal to name by
+// NamesEqual, escaped octets are copied verbatim. The random bits come from
+// crypto/rand.
+func RandomizeCase(name string) string {
+	bits := make([]byte, (len(name)+7)/8)
+	if _, err := rand.Read(bits); err != nil {
+		panic(fmt.Errorf("RandomizeCase: %s", err))
+	}
+
+	b := []byte(name)
+	for i := 0; i < len(b); i++ {
+		c := b[i]
+		switch {
+		case c == '\\':
+			if i+3 < len(b) && isDigit(b[i+1]) && isDigit(b[i+2]) && isDigit(b[i+3]) {
+				i += 3
+				break
+			}
+
+			i++
+		case c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z':
+			if bits[i/8]&(1<<uint(i%8)) != 0 {
+				b[i] = c ^ 0x20
+			}
+		}
+	}
+	return string(b)
+}
+
+// CountLabels returns the number of labels of name not counting the root
+// label and a leading wildcard label (RFC 4034/3.1.3). Escaped dots don't
+// separate labels, i.e. `a\.b.example.` and `a\046b.example.` have two
+// labels. The root name has no labels.
+func CountLabels(name string) (n int) {
+	if IsWildcard(name) {
+		name = name[1:]
+	}
+
+	// Labels are split the same way DomainName.Encode does it.
+	for name = strings.TrimPrefix(RootedName(name), "."); name != ""; n++ {
+		_, i := unescapeLabel(name)
+		name = name[i+1:]
+	}
+	return
+}
+
+// IsWildcard returns true if the leftmost label of name is `*`.
+func IsWildcard(name string) bool {
+	return name == "*" || strings.HasPrefix(name, "*.")
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
 // Seconds2String converts epoch seconds to a string with the YYYYMMDDHHmmSS
 // format.
 func Seconds2String(epochSecs int64) string {
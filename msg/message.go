@@ -633,16 +633,29 @@ const (
 	QTYPE_DHCID      // 49 DHCID                                       [RFC4701]
 	QTYPE_NSEC3      // 50 NSEC3                                       [RFC5155]
 	QTYPE_NSEC3PARAM // 51 NSEC3PARAM                                  [RFC5155]
+	_                // 52 TLSA, see QTYPE_TLSA                        [RFC6698]
+	QTYPE_SMIMEA     // 53 S/MIME cert association                     [RFC8162]
 )
 
 const (
 	_ QType = iota + 54
 
-	QTYPE_HIP    // 55 Host Identity Protocol                      [RFC5205]
-	QTYPE_NINFO  // 56 NINFO                                       [Reid]
-	QTYPE_RKEY   // 57 RKEY                                        [Reid]
-	QTYPE_TALINK // 58 Trust Anchor LINK                           [Wijngaards]
-	QTYPE_CDS    // 59 Child DS                                    [Barwood]
+	QTYPE_HIP        // 55 Host Identity Protocol                      [RFC5205]
+	QTYPE_NINFO      // 56 NINFO                                       [Reid]
+	QTYPE_RKEY       // 57 RKEY                                        [Reid]
+	QTYPE_TALINK     // 58 Trust Anchor LINK                           [Wijngaards]
+	QTYPE_CDS        // 59 Child DS                                    [Barwood]
+	_                // 60 Child DNSKEY                                [RFC7344]
+	QTYPE_OPENPGPKEY // 61 OpenPGP Key                                 [RFC7929]
+	QTYPE_CSYNC      // 62 Child-To-Parent Synchronization             [RFC7477]
+	QTYPE_ZONEMD     // 63 Message Digest Over Zone Data               [RFC8976]
+)
+
+const (
+	_ QType = iota + 63
+
+	QTYPE_SVCB  // 64 General Purpose Service Binding             [RFC9460]
+	QTYPE_HTTPS // 65 HTTPS Binding                               [RFC9460]
 )
 
 const (
@@ -699,6 +712,7 @@ var qtypeStr = map[QType]string{
 	QTYPE_CDS:        "CDS",
 	QTYPE_CERT:       "CERT",
 	QTYPE_CNAME:      "CNAME",
+	QTYPE_CSYNC:      "CSYNC",
 	QTYPE_DHCID:      "DHCID",
 	QTYPE_DLV:        "DLV",
 	QTYPE_DNAME:      "DNAME",
@@ -709,6 +723,7 @@ var qtypeStr = map[QType]string{
 	QTYPE_GPOS:       "GPOS",
 	QTYPE_HINFO:      "HINFO",
 	QTYPE_HIP:        "HIP",
+	QTYPE_HTTPS:      "HTTPS",
 	QTYPE_IPSECKEY:   "IPSECKEY",
 	QTYPE_ISDN:       "ISDN",
 	QTYPE_IXFR:       "IXFR",
@@ -737,6 +752,7 @@ var qtypeStr = map[QType]string{
 	QTYPE_NULL:       "NULL",
 	QTYPE_NXDOMAIN:   "NXDOMAIN",
 	QTYPE_NXT:        "NXT",
+	QTYPE_OPENPGPKEY: "OPENPGPKEY",
 	QTYPE_PTR:        "PTR",
 	QTYPE_PX:         "PX",
 	QTYPE_RKEY:       "RKEY",
@@ -745,10 +761,12 @@ var qtypeStr = map[QType]string{
 	QTYPE_RT:         "RT",
 	QTYPE_SIG:        "SIG",
 	QTYPE_SINK:       "SINK",
+	QTYPE_SMIMEA:     "SMIMEA",
 	QTYPE_SOA:        "SOA",
 	QTYPE_SPF:        "SPF",
 	QTYPE_SRV:        "SRV",
 	QTYPE_SSHFP:      "SSHFP",
+	QTYPE_SVCB:       "SVCB",
 	QTYPE_STAR:       "*",
 	QTYPE_TA:         "TA",
 	QTYPE_TALINK:     "TALINK",
@@ -762,6 +780,7 @@ var qtypeStr = map[QType]string{
 	QTYPE_URI:        "URI",
 	QTYPE_WKS:        "WKS",
 	QTYPE_X25:        "X25",
+	QTYPE_ZONEMD:     "ZONEMD",
 }
 
 func (n QType) String() (s string) {
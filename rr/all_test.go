@@ -8,17 +8,26 @@ package rr
 
 import (
 	"bytes"
+	"crypto"
+	crand "crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
 	"encoding/hex"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"github.com/cznic/dns"
 	"github.com/cznic/strutil"
+	"math"
+	"math/big"
 	"math/rand"
 	"net"
+	"sort"
 	"strconv"
 	"strings"
 	"testing"
 	"time"
+	"unsafe"
 )
 
 var optDev = flag.Bool("dev", false, "enable dev helpers")
@@ -163,7 +172,8 @@ func TestLOC(t *testing.T) {
 	}
 }
 
-func Test0(t *testing.T) {
+// allTypes returns a RR set covering all RR types supported by this package.
+func allTypes() RRs {
 	loc := &LOC{}
 	loc.Size = loc.EncPrec(123)                    // 1m
 	loc.HorizPre = loc.EncPrec(4567)               // 40m
@@ -171,19 +181,26 @@ func Test0(t *testing.T) {
 	loc.Latitude = loc.EncDMTS(1, 2, 3456, true)   // 1 2 3.456 N
 	loc.Longitude = loc.EncDMTS(2, 3, 4567, false) // 2 3 4.567 W
 	loc.EncAlt(-34567)                             // -345.67 m
-	data := RRs{
+	return RRs{
 		&RR{"nA.example.com.", TYPE_A, CLASS_IN, -1,
 			&A{net.ParseIP("1.2.3.4")}},
 		&RR{"nAAAA.example.com.", TYPE_AAAA, CLASS_IN, -1,
 			&AAAA{net.ParseIP("::1")}},
 		&RR{"nAFSDB.example.com.", TYPE_AFSDB, CLASS_IN, -1,
 			&AFSDB{12345, "exchange.example.com."}},
+		&RR{"nAPL.example.com.", TYPE_APL, CLASS_IN, -1,
+			&APL{[]APLItem{{1, 24, false, []byte{192, 0, 2}}, {2, 32, true, []byte{0x20, 0x01, 0x0d, 0xb8}}}}},
 		&RR{"nCNAME.example.com.", TYPE_CNAME, CLASS_IN, -1,
 			&CNAME{"cname.example.com."}},
+		&RR{"nATMA.example.com.", TYPE_ATMA, CLASS_IN, -1,
+			&ATMA{ATMAFormatE164, []byte("14085551212")}},
 		&RR{"nCERT.example.com.", TYPE_CERT, CLASS_IN, -1,
 			&CERT{CertPKIX, 0x1234, AlgorithmDSA_SHA1,
 				[]byte{0, 6, 0x40, 0x01, 0x00, 0x00, 0x00, 0x03}},
 		},
+		&RR{"nCSYNC.example.com.", TYPE_CSYNC, CLASS_IN, -1,
+			&CSYNC{0x12345678, CSYNC_IMMEDIATE | CSYNC_SOAMINIMUM,
+				TypesEncode([]Type{TYPE_A, TYPE_NS, TYPE_AAAA})}},
 		&RR{"nDHCID.example.com.", TYPE_DHCID, CLASS_IN, -1,
 			&DHCID{[]byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17, 18, 19}}},
 		&RR{"nDLV.example.com.", TYPE_DLV, CLASS_IN, -1,
@@ -197,8 +214,10 @@ func Test0(t *testing.T) {
 		&RR{"nDS.example.com.", TYPE_DS, CLASS_IN, -1,
 			&DS{0x1234, 0x56, HashAlgorithmSHA1,
 				[]byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17, 18, 19}}},
+		&RR{"nEID.example.com.", TYPE_EID, CLASS_IN, -1,
+			&EID{[]byte{0x12, 0x34, 0x56}}},
 		&RR{"nGPOS.example.com.", TYPE_GPOS, CLASS_IN, -1,
-			&GPOS{-32.6882, 116.8652, 10.0}},
+			&GPOS{"-32.6882", "116.8652", "10.0"}},
 		&RR{"nHINFO.example.com.", TYPE_HINFO, CLASS_IN, -1,
 			&HINFO{"x86_64", "Linux"}},
 		&RR{"nHIP.example.com.", TYPE_HIP, CLASS_IN, -1,
@@ -229,6 +248,9 @@ func Test0(t *testing.T) {
 				[]string{"a.example.com.", "b.example.com.", "c.example.com."},
 			},
 		},
+		&RR{"nHTTPS.example.com.", TYPE_HTTPS, CLASS_IN, -1,
+			&HTTPS{SVCB{1, ".", []SvcParam{{SvcIPv4Hint, []byte{192, 0, 2, 1}}}}},
+		},
 		&RR{"nIPSECKEY.example.com.", TYPE_IPSECKEY, CLASS_IN, -1,
 			&IPSECKEY{10, GatewayNone, IPSECKEYAlgorithmRSA,
 				nil,
@@ -274,6 +296,10 @@ func Test0(t *testing.T) {
 			&MX{0x1234, "exchange.example.com."}},
 		&RR{"nNAPTR.example.com.", TYPE_NAPTR, CLASS_IN, -1,
 			&NAPTR{1, 2, "U", "E2U+sip", "!^.*$!sip:customer-service@example.com!", "."}},
+		&RR{"nNINFO.example.com.", TYPE_NINFO, CLASS_IN, -1,
+			&NINFO{[]string{"Zone is being maintained", "ETA 1h"}}},
+		&RR{"nNIMLOC.example.com.", TYPE_NIMLOC, CLASS_IN, -1,
+			&NIMLOC{[]byte{0xab, 0xcd}}},
 		&RR{"nNS.example.com.", TYPE_NS, CLASS_IN, -1,
 			&NS{"ns.example.com."}},
 		&RR{"nNSAP.example.com.", TYPE_NSAP, CLASS_IN, -1,
@@ -300,6 +326,8 @@ func Test0(t *testing.T) {
 			&NULL{[]byte{}}},
 		&RR{"nNULL.example.com.", TYPE_NULL, CLASS_IN, -1,
 			&NULL{[]byte{3, 7, 31, 127}}},
+		&RR{"nOPENPGPKEY.example.com.", TYPE_OPENPGPKEY, CLASS_IN, -1,
+			&OPENPGPKEY{[]byte{0x99, 0x01, 0x0d, 0x04, 0x50, 0x8a, 0x7b, 0x9c}}},
 		&RR{"nOPT.example.com.", TYPE_OPT, Class(4096), -1,
 			&OPT{}},
 		&RR{"nOPT.example.com.", TYPE_OPT, Class(4096), -1,
@@ -322,6 +350,12 @@ func Test0(t *testing.T) {
 			&SIG{TYPE_A, AlgorithmDSA_SHA1, 2, 3, 0x87654321, 0x12345678, 0x1234, "signer.example.com.",
 				[]byte{0, 6, 0x40, 0x01, 0x00, 0x00, 0x00, 0x03}},
 		},
+		&RR{"nSMIMEA.example.com.", TYPE_SMIMEA, CLASS_IN, -1,
+			&SMIMEA{
+				TLSAUsagePKIX_EE, TLSASelectorSubjectPKInfo, TLSAMatchingTypeSHA256,
+				[]byte{1, 2, 3, 4, 5},
+			},
+		},
 		&RR{"nSOA.example.com.", TYPE_SOA, CLASS_IN, -1,
 			&SOA{"mname.example.com.", "rname.example.com.", 0x12345678, 0x123456, 0x98765, 0x1331, 0x9812}},
 		&RR{"nSPF.example.com.", TYPE_SPF, CLASS_IN, -1,
@@ -336,6 +370,12 @@ func Test0(t *testing.T) {
 			&SSHFP{SSHFPAlgorithmDSA, SSHFPTypeSHA1,
 				[]byte{1, 2, 4, 8, 16, 32, 64, 128}},
 		},
+		&RR{"nSVCB.example.com.", TYPE_SVCB, CLASS_IN, -1,
+			&SVCB{0, "svc.example.com.", nil},
+		},
+		&RR{"nSVCB.example.com.", TYPE_SVCB, CLASS_IN, -1,
+			&SVCB{1, ".", []SvcParam{{SvcALPN, []byte("\x02h2")}, {SvcPort, []byte{0, 53}}}},
+		},
 		&RR{"nTA.example.com.", TYPE_TA, CLASS_IN, -1,
 			&TA{0x1234, 0x56, HashAlgorithmSHA1,
 				[]byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17, 18, 19}}},
@@ -435,12 +475,18 @@ func Test0(t *testing.T) {
 			&WKS{net.ParseIP("8.9.10.11"), TCP_Protocol, map[IP_Port]struct{}{SMTP_Port: struct{}{}}}},
 		&RR{"nX25.example.com.", TYPE_X25, CLASS_IN, -1,
 			&X25{"Linux \"rulez!\""}},
+		&RR{"nZONEMD.example.com.", TYPE_ZONEMD, CLASS_IN, -1,
+			&ZONEMD{0x12345678, ZONEMDSchemeSimple, ZONEMDHashSHA384,
+				bytes.Repeat([]byte{0x5a}, 48)}},
 
 		// keep last, it's a RR which can have rdlength == 0
 		&RR{"nOPT.example.com.", TYPE_OPT, Class(4096), -1,
 			&OPT{}},
 	}
+}
 
+func Test0(t *testing.T) {
+	data := allTypes()
 	for i, r := range data {
 		r.TTL = int32(i)
 	}
@@ -600,6 +646,19 @@ func TestSetAdd(t *testing.T) {
 		t.Log(set)
 		t.Fatal(len(set), "!= 3")
 	}
+
+	set = RRs{}
+	set.SetAdd(RRs{
+		&RR{`A\.B.Example.COM.`, TYPE_A, CLASS_IN, 0, &A{net.ParseIP("1.2.3.4")}},
+		&RR{`a\.b.example.com`, TYPE_A, CLASS_IN, 0, &A{net.ParseIP("1.2.3.4")}},
+		&RR{`a.b.example.com.`, TYPE_A, CLASS_IN, 0, &A{net.ParseIP("1.2.3.4")}},
+		&RR{`\065.example.com.`, TYPE_CNAME, CLASS_IN, 0, &CNAME{"Target.Example."}},
+		&RR{`\065.EXAMPLE.com.`, TYPE_CNAME, CLASS_IN, 0, &CNAME{"target.example."}},
+		&RR{`\097.example.com.`, TYPE_CNAME, CLASS_IN, 0, &CNAME{"target.example."}},
+	})
+	if len(set) != 4 {
+		t.Fatal(len(set), "!= 4\n", set)
+	}
 }
 
 func TestPartition(t *testing.T) {
@@ -1130,3 +1189,4340 @@ func TestTSIG(t *testing.T) {
 		t.Errorf("\n%v\n!=\n%v", g, e)
 	}
 }
+
+func TestTypeByName(t *testing.T) {
+	for typ, s := range Types {
+		if g, ok := TypeByName(strings.ToLower(s)); !ok || g != typ {
+			t.Fatalf("%q: %d %t", s, g, ok)
+		}
+	}
+
+	tab := []struct {
+		s  string
+		t  Type
+		ok bool
+	}{
+		{"TYPE1", TYPE_A, true},
+		{"type65534", Type(65534), true},
+		{"TYPE65536", 0, false},
+		{"TYPE", 0, false},
+		{"TYPE-1", 0, false},
+		{"FOO", 0, false},
+	}
+	for _, test := range tab {
+		if g, ok := TypeByName(test.s); ok != test.ok || g != test.t {
+			t.Fatalf("%q: %d %t", test.s, g, ok)
+		}
+	}
+}
+
+func TestClassByName(t *testing.T) {
+	tab := []struct {
+		s  string
+		c  Class
+		ok bool
+	}{
+		{"IN", CLASS_IN, true},
+		{"ch", CLASS_CH, true},
+		{"CLASS4096", Class(4096), true},
+		{"CLASSX", 0, false},
+		{"", 0, false},
+	}
+	for _, test := range tab {
+		if g, ok := ClassByName(test.s); ok != test.ok || g != test.c {
+			t.Fatalf("%q: %d %t", test.s, g, ok)
+		}
+	}
+}
+
+func TestParseGeneric(t *testing.T) {
+	var rd RDATA
+	if err := rd.ParseGeneric([]string{`\#`, "4", "0a00", "0001"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if g, e := []byte(rd), []byte{10, 0, 0, 1}; !bytes.Equal(g, e) {
+		t.Fatalf("% x != % x", g, e)
+	}
+
+	if err := rd.ParseGeneric([]string{`\#`, "0"}); err != nil || len(rd) != 0 {
+		t.Fatal(err, len(rd))
+	}
+
+	for _, bad := range [][]string{
+		{},
+		{`\#`},
+		{"#", "1", "00"},
+		{`\#`, "2", "00"},
+		{`\#`, "1", "0"},
+		{`\#`, "x", "00"},
+	} {
+		if err := rd.ParseGeneric(bad); err == nil {
+			t.Fatalf("%q: unexpected success", bad)
+		}
+	}
+
+	r := &RR{"example.com.", TYPE_A, CLASS_IN, 3600, &A{net.ParseIP("10.0.0.1")}}
+	if g, e := r.GenericString(), "example.com.\tIN\t3600\tA \\# 4 0a000001"; g != e {
+		t.Fatalf("%q != %q", g, e)
+	}
+
+	if g, e := r.String(), "example.com.\tIN\t3600\tA 10.0.0.1"; g != e {
+		t.Fatalf("%q != %q", g, e)
+	}
+}
+
+func TestWKS(t *testing.T) {
+	rd := &WKS{net.ParseIP("10.0.0.1"), TCP_Protocol, map[IP_Port]struct{}{
+		SMTP_Port: struct{}{},
+		DNS_Port:  struct{}{},
+		0:         struct{}{},
+	}}
+	w := dns.NewWirebuf()
+	rd.Encode(w)
+	// RFC 1035/3.4.2: the first bit of the bit map corresponds to port 0.
+	e := []byte{10, 0, 0, 1, 6, 0x80, 0, 0, 0x40, 0, 0, 0x04}
+	if g := w.Buf; !bytes.Equal(g, e) {
+		t.Fatalf("\n% x\n% x", g, e)
+	}
+
+	if g, e := rd.String(), "10.0.0.1 TCP 0 SMTP DNS"; g != e {
+		t.Fatalf("%q != %q", g, e)
+	}
+
+	rd2 := &WKS{}
+	p := 0
+	if err := rd2.Decode(w.Buf, &p, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if g, e := rd2.String(), rd.String(); g != e {
+		t.Fatalf("%q != %q", g, e)
+	}
+
+	p = 0
+	if err := rd2.Decode(w.Buf[:4], &p, nil); err == nil {
+		t.Fatal("unexpected success")
+	}
+}
+
+func TestNULL(t *testing.T) {
+	data := []byte{0, 1, 0, 0, 0xff, 0}
+	rrs := RRs{&RR{"null.example.com.", TYPE_NULL, CLASS_IN, 0, &NULL{data}}}
+	got := rrs.Pack().Unpack()
+	if len(got) != 1 {
+		t.Fatal(len(got))
+	}
+
+	if g, e := got[0].RData.(*NULL).Data, data; !bytes.Equal(g, e) {
+		t.Fatalf("% x != % x", g, e)
+	}
+
+	if g, e := got[0].RData.(*NULL).String(), `\# 6 00010000ff00`; g != e {
+		t.Fatalf("%q != %q", g, e)
+	}
+
+	huge := &RR{"null.example.com.", TYPE_NULL, CLASS_IN, 0, &NULL{make([]byte, 65536)}}
+	w := dns.NewWirebuf()
+	huge.Encode(w)
+	if err := huge.EncodeErr(w); err == nil {
+		t.Fatal("unexpected success")
+	}
+}
+
+func TestHexDump(t *testing.T) {
+	r := &RR{"a.example.", TYPE_A, CLASS_IN, 3600, &A{net.ParseIP("10.0.0.1")}}
+	g := r.HexDump()
+	t.Logf("\n%s", g)
+	e := `00000000  01 61 07 65 78 61 6d 70  6c 65 00 00 01 00 01 00  |.a.example......|
+00000010  00 0e 10 00 04 0a 00 00  01                       |.........|
+00000019
+name     00000000-0000000b 11
+type     0000000b-0000000d 2
+class    0000000d-0000000f 2
+ttl      0000000f-00000013 4
+rdlength 00000013-00000015 2
+rdata    00000015-00000019 4
+`
+	if g != e {
+		t.Fatalf("\n%s\n!=\n%s", g, e)
+	}
+}
+
+func TestJSON(t *testing.T) {
+	for _, r := range allTypes() {
+		b, err := json.Marshal(r)
+		if err != nil {
+			t.Fatal(r, err)
+		}
+
+		var r2 RR
+		if err = json.Unmarshal(b, &r2); err != nil {
+			t.Fatalf("%s\n%s\n%s", r, b, err)
+		}
+
+		if g, e := fmt.Sprintf("%T", r2.RData), fmt.Sprintf("%T", r.RData); g != e {
+			t.Fatalf("%s != %s", g, e)
+		}
+
+		if !r2.Equal(r) || r2.TTL != r.TTL || r2.String() != r.String() {
+			t.Fatalf("\n%s\n%s\n%s", b, &r2, r)
+		}
+	}
+
+	r := &RR{"example.com.", TYPE_MX, CLASS_IN, 3600, &MX{10, "mail."}}
+	b, err := json.Marshal(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if g, e := string(b), `{"name":"example.com.","type":"MX","class":"IN","ttl":3600,"rdata":{"exchange":"mail.","preference":10}}`; g != e {
+		t.Fatalf("\n%s\n%s", g, e)
+	}
+
+	r = &RR{"example.com.", TYPE_DS, CLASS_IN, 3600, &DS{1, AlgorithmRSA_SHA1, HashAlgorithmSHA1, []byte{0xab, 0xcd}}}
+	if b, err = json.Marshal(r); err != nil {
+		t.Fatal(err)
+	}
+
+	if g, e := string(b), `{"name":"example.com.","type":"DS","class":"IN","ttl":3600,"rdata":{"algorithm":5,"digest":"abcd","digestType":1,"keyTag":1}}`; g != e {
+		t.Fatalf("\n%s\n%s", g, e)
+	}
+}
+
+func TestSOAShort(t *testing.T) {
+	soa := &SOA{"ns.example.", "hostmaster.example.", 1, 2, 3, 4, 5}
+	w := dns.NewWirebuf()
+	w.DisableCompression()
+	soa.Encode(w)
+	names := len(w.Buf) - 5*4
+	for n := names; n < len(w.Buf); n++ {
+		pos := 0
+		if err := (&SOA{}).Decode(w.Buf[:n], &pos, nil); err == nil {
+			t.Fatalf("%d/%d: unexpected success", n, len(w.Buf))
+		}
+	}
+
+	pos := 0
+	got := &SOA{}
+	if err := got.Decode(w.Buf, &pos, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if g, e := got.String(), soa.String(); g != e {
+		t.Fatalf("%q != %q", g, e)
+	}
+}
+
+type testRData []byte
+
+func (rd *testRData) Encode(b *dns.Wirebuf) {
+	b.Buf = append(b.Buf, *rd...)
+}
+
+func (rd *testRData) Decode(b []byte, pos *int, sniffer dns.WireDecodeSniffer) (err error) {
+	*rd = append([]byte(nil), b[*pos:]...)
+	*pos = len(b)
+	return
+}
+
+func TestEqualUnknown(t *testing.T) {
+	a := &RR{"example.com.", Type(65280), CLASS_IN, 0, &testRData{1, 2, 3}}
+	b := &RR{"EXAMPLE.com.", Type(65280), CLASS_IN, 0, &testRData{1, 2, 3}}
+	c := &RR{"example.com.", Type(65280), CLASS_IN, 0, &testRData{1, 2, 4}}
+	d := &RR{"example.com.", Type(65280), CLASS_IN, 0, &RDATA{1, 2, 3}}
+	if !a.Equal(b) {
+		t.Fatal("a != b")
+	}
+
+	if a.Equal(c) {
+		t.Fatal("a == c")
+	}
+
+	if !a.Equal(d) || !d.Equal(a) {
+		t.Fatal("a != d")
+	}
+
+	mx := &RR{"example.com.", TYPE_MX, CLASS_IN, 0, &MX{10, "mx.example.com."}}
+	w := dns.NewWirebuf()
+	w.DisableCompression()
+	mx.RData.Encode(w)
+	raw := &RR{"example.com.", TYPE_MX, CLASS_IN, 0, &RDATA{}}
+	*raw.RData.(*RDATA) = w.Buf
+	if !mx.Equal(raw) || !raw.Equal(mx) {
+		t.Fatal("mx != raw")
+	}
+}
+
+func TestCopy(t *testing.T) {
+	data := allTypes()
+	y := data.Copy()
+	if g, e := len(y), len(data); g != e {
+		t.Fatal(g, e)
+	}
+
+	for i, rec := range data {
+		if y[i] == rec || y[i].RData == rec.RData {
+			t.Fatalf("%d: shared %s", i, rec)
+		}
+
+		if !y[i].Equal(rec) || y[i].String() != rec.String() {
+			t.Fatalf("%d:\n%s\n%s", i, y[i], rec)
+		}
+	}
+
+	a := &RR{"a.example.", TYPE_A, CLASS_IN, 0, &A{net.ParseIP("10.0.0.1")}}
+	b := a.Copy()
+	a.RData.(*A).Address[15] = 2
+	if g, e := b.RData.(*A).Address.String(), "10.0.0.1"; g != e {
+		t.Fatal(g, e)
+	}
+
+	txt := &RR{"a.example.", TYPE_TXT, CLASS_IN, 0, &TXT{[]string{"foo", "bar"}}}
+	txt2 := txt.Copy()
+	txt.RData.(*TXT).S[0] = "baz"
+	if g, e := txt2.RData.(*TXT).S[0], "foo"; g != e {
+		t.Fatal(g, e)
+	}
+}
+
+func TestSetAddCopy(t *testing.T) {
+	key := []byte{1, 2, 3, 4}
+	src := &RR{"example.com.", TYPE_DNSKEY, CLASS_IN, 0, &DNSKEY{256, 3, 5, key}}
+	set := RRs{}
+	set.SetAdd(RRs{src})
+	key[0] = 42
+	src.TTL = 3600
+	if g, e := set[0].RData.(*DNSKEY).Key[0], byte(1); g != e {
+		t.Fatal(g, e)
+	}
+
+	if g, e := set[0].TTL, int32(0); g != e {
+		t.Fatal(g, e)
+	}
+}
+
+func TestSort(t *testing.T) {
+	zone := RRs{
+		&RR{"example.com.", TYPE_SOA, CLASS_IN, 3600, &SOA{"ns.example.com.", "hostmaster.example.com.", 1, 2, 3, 4, 5}},
+		&RR{"example.com.", TYPE_NS, CLASS_IN, 3600, &NS{"ns2.example.com."}},
+		&RR{"example.com.", TYPE_NS, CLASS_IN, 3600, &NS{"ns.example.com."}},
+		&RR{"example.com.", TYPE_MX, CLASS_IN, 3600, &MX{10, "mail.example.com."}},
+		&RR{"Example.com.", TYPE_A, CLASS_IN, 3600, &A{net.ParseIP("192.0.2.1")}},
+		&RR{"a.example.com.", TYPE_A, CLASS_IN, 3600, &A{net.ParseIP("192.0.2.3")}},
+		&RR{"a.example.com.", TYPE_A, CLASS_IN, 3600, &A{net.ParseIP("192.0.2.2")}},
+		&RR{"x.a.example.com.", TYPE_TXT, CLASS_IN, 3600, &TXT{[]string{"x"}}},
+		&RR{"b.example.com.", TYPE_AAAA, CLASS_IN, 3600, &AAAA{net.ParseIP("2001:db8::1")}},
+		&RR{"b.example.com.", TYPE_A, CLASS_IN, 3600, &A{net.ParseIP("192.0.2.4")}},
+		&RR{`a\.b.example.com.`, TYPE_CNAME, CLASS_IN, 3600, &CNAME{"b.example.com."}},
+		&RR{"mail.example.com.", TYPE_A, CLASS_IN, 3600, &A{net.ParseIP("192.0.2.5")}},
+		&RR{"com.", TYPE_NS, CLASS_IN, 3600, &NS{"a.gtld-servers.net."}},
+		&RR{"example.net.", TYPE_A, CLASS_IN, 3600, &A{net.ParseIP("192.0.2.6")}},
+	}
+	rng := rand.New(rand.NewSource(42))
+	for i := range zone {
+		j := rng.Intn(i + 1)
+		zone[i], zone[j] = zone[j], zone[i]
+	}
+	zone.Sort()
+	e := `com.	IN	3600	NS a.gtld-servers.net.
+Example.com.	IN	3600	A 192.0.2.1
+example.com.	IN	3600	NS ns.example.com.
+example.com.	IN	3600	NS ns2.example.com.
+example.com.	IN	3600	SOA ns.example.com. hostmaster.example.com. 1 2 3 4 5
+example.com.	IN	3600	MX 10 mail.example.com.
+a.example.com.	IN	3600	A 192.0.2.2
+a.example.com.	IN	3600	A 192.0.2.3
+x.a.example.com.	IN	3600	TXT "x"
+a\.b.example.com.	IN	3600	CNAME b.example.com.
+b.example.com.	IN	3600	A 192.0.2.4
+b.example.com.	IN	3600	AAAA 2001:db8::1
+mail.example.com.	IN	3600	A 192.0.2.5
+example.net.	IN	3600	A 192.0.2.6`
+	if g := zone.String(); g != e {
+		t.Fatalf("\n%s\n!=\n%s", g, e)
+	}
+
+	sort.Stable(Sorter{zone, func(a, b *RR) int { return int(a.Type) - int(b.Type) }})
+	if g, e := zone[0].String(), "Example.com.\tIN\t3600\tA 192.0.2.1"; g != e {
+		t.Fatalf("%q != %q", g, e)
+	}
+
+	if g, e := zone[len(zone)-1].String(), "b.example.com.\tIN\t3600\tAAAA 2001:db8::1"; g != e {
+		t.Fatalf("%q != %q", g, e)
+	}
+}
+
+func TestGroupByName(t *testing.T) {
+	data := RRs{
+		&RR{"b.example.", TYPE_A, CLASS_IN, 0, &A{net.ParseIP("192.0.2.1")}},
+		&RR{"a.example.", TYPE_A, CLASS_IN, 0, &A{net.ParseIP("192.0.2.2")}},
+		&RR{"B.example", TYPE_TXT, CLASS_IN, 0, &TXT{[]string{"b"}}},
+		&RR{"a.example.", TYPE_MX, CLASS_IN, 0, &MX{10, "b.example."}},
+		&RR{"b.example.", TYPE_AAAA, CLASS_IN, 0, &AAAA{net.ParseIP("2001:db8::1")}},
+	}
+	if g, e := fmt.Sprint(data.Names()), "[b.example. a.example.]"; g != e {
+		t.Fatalf("%q != %q", g, e)
+	}
+
+	groups := data.GroupByName()
+	if g, e := len(groups), 2; g != e {
+		t.Fatal(g, e)
+	}
+
+	b := groups["b.example."]
+	if g, e := len(b), 3; g != e {
+		t.Fatal(g, e)
+	}
+
+	if b[0] != data[0] || b[1] != data[2] || b[2] != data[4] {
+		t.Fatalf("\n%s", b)
+	}
+
+	a := groups["a.example."]
+	if g, e := len(a), 2; g != e {
+		t.Fatal(g, e)
+	}
+
+	if a[0] != data[1] || a[1] != data[3] {
+		t.Fatalf("\n%s", a)
+	}
+}
+
+func TestNormalizeTTL(t *testing.T) {
+	mk := func() RRs {
+		return RRs{
+			&RR{"example.", TYPE_A, CLASS_IN, 300, &A{net.ParseIP("192.0.2.1")}},
+			&RR{"EXAMPLE.", TYPE_A, CLASS_IN, 100, &A{net.ParseIP("192.0.2.2")}},
+			&RR{"example.", TYPE_A, CLASS_CH, 200, &A{net.ParseIP("192.0.2.3")}},
+			&RR{"example.", TYPE_MX, CLASS_IN, 400, &MX{10, "mx.example."}},
+			&RR{"example.", TYPE_RRSIG, CLASS_IN, 300, &RRSIG{Type: TYPE_A}},
+			&RR{"example.", TYPE_RRSIG, CLASS_IN, 400, &RRSIG{Type: TYPE_MX}},
+			&RR{"example.", TYPE_RRSIG, CLASS_IN, 100, &RRSIG{Type: TYPE_A}},
+			&RR{"", TYPE_OPT, Class(4096), 0x8000, &OPT{}},
+			&RR{"", TYPE_OPT, Class(4096), 0, &OPT{}},
+		}
+	}
+
+	data := mk()
+	err := data.CheckTTL()
+	if err == nil {
+		t.Fatal("unexpected success")
+	}
+
+	if g, e := err.Error(), "(RRs).CheckTTL: inconsistent TTLs in RRset(s) example. IN A, example. IN RRSIG A"; g != e {
+		t.Fatalf("%q != %q", g, e)
+	}
+
+	if g, e := data[0].TTL, int32(300); g != e {
+		t.Fatal(g, e)
+	}
+
+	data.NormalizeTTL()
+	if err := data.CheckTTL(); err != nil {
+		t.Fatal(err)
+	}
+
+	for i, e := range []int32{100, 100, 200, 400, 100, 400, 100, 0x8000, 0} {
+		if g := data[i].TTL; g != e {
+			t.Fatalf("%d: %d != %d", i, g, e)
+		}
+	}
+}
+
+func TestSignedData(t *testing.T) {
+	sig := &RRSIG{TYPE_A, AlgorithmRSA_SHA1, 2, 3600, 0x50000000, 0x4f000000, 0x1234, "Example.", []byte{1, 2, 3}}
+	rrset := RRs{
+		&RR{"WWW.example.", TYPE_A, CLASS_IN, 60, &A{net.ParseIP("192.0.2.2")}},
+		&RR{"www.Example.", TYPE_A, CLASS_IN, 60, &A{net.ParseIP("192.0.2.1")}},
+		&RR{"www.example.", TYPE_A, CLASS_IN, 60, &A{net.ParseIP("192.0.2.2")}},
+	}
+	g, err := sig.SignedData(rrset)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e, _ := hex.DecodeString("" +
+		"0001" + "05" + "02" + "00000e10" + "50000000" + "4f000000" + "1234" + "076578616d706c6500" +
+		"03777777076578616d706c6500" + "0001" + "0001" + "00000e10" + "0004" + "c0000201" +
+		"03777777076578616d706c6500" + "0001" + "0001" + "00000e10" + "0004" + "c0000202")
+	if !bytes.Equal(g, e) {
+		t.Fatalf("\n%x\n%x", g, e)
+	}
+
+	// Wildcard expansion
+	sig.Labels = 2
+	g, err = sig.SignedData(RRs{&RR{"a.b.example.", TYPE_A, CLASS_IN, 60, &A{net.ParseIP("192.0.2.1")}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if g, e := hex.EncodeToString(g[27:]), "012a0162076578616d706c6500000100010000"+"0e100004c0000201"; g != e {
+		t.Fatalf("\n%s\n%s", g, e)
+	}
+
+	for i, bad := range []RRs{
+		{},
+		{&RR{"www.example.", TYPE_AAAA, CLASS_IN, 60, &AAAA{net.ParseIP("::1")}}},
+		{rrset[0], &RR{"ftp.example.", TYPE_A, CLASS_IN, 60, &A{net.ParseIP("192.0.2.1")}}},
+		{rrset[0], &RR{"www.example.", TYPE_A, CLASS_CH, 60, &A{net.ParseIP("192.0.2.1")}}},
+		{&RR{"example.", TYPE_A, CLASS_IN, 60, &A{net.ParseIP("192.0.2.1")}}},
+	} {
+		if _, err := sig.SignedData(bad); err == nil {
+			t.Fatal(i, "unexpected success")
+		}
+	}
+}
+
+func TestKeyTag(t *testing.T) {
+	// RFC 4034/5.4
+	key, err := strutil.Base64Decode([]byte("" +
+		"AQOeiiR0GOMYkDshWoSKz9XzfwJr1AYtsmx3TGkJaNXVbfi/2pHm822aJ5iI9BMz" +
+		"NXxeYCmZDRD99WYwYqUSdjMmmAphXdvxegXd/M5+X7OrzKBaMbCVdFLUUh6DhweJ" +
+		"BjEVv5f2wwjM9XzcnOf+EPbtG9DMBmADjFDc2w/rljwvFw=="))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if g, e := (&DNSKEY{256, 3, AlgorithmRSA_SHA1, key}).KeyTag(), uint16(60485); g != e {
+		t.Fatal(g, e)
+	}
+}
+
+// rsaDNSKEY returns the RFC 3110 DNSKEY RDATA of k.
+func rsaDNSKEY(k *rsa.PublicKey, alg AlgorithmType) *DNSKEY {
+	e := big.NewInt(int64(k.E)).Bytes()
+	b := append([]byte{byte(len(e))}, e...)
+	return &DNSKEY{256, 3, alg, append(b, k.N.Bytes()...)}
+}
+
+func TestSignRRSet(t *testing.T) {
+	priv, err := rsa.GenerateKey(crand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rrset := RRs{
+		&RR{"*.Example.", TYPE_A, CLASS_IN, 60, &A{net.ParseIP("192.0.2.2")}},
+		&RR{"*.example.", TYPE_A, CLASS_IN, 60, &A{net.ParseIP("192.0.2.1")}},
+	}
+	for _, alg := range []AlgorithmType{AlgorithmRSA_SHA1, AlgorithmRSA_SHA256} {
+		key := &RR{"Example.", TYPE_DNSKEY, CLASS_IN, 3600, rsaDNSKEY(&priv.PublicKey, alg)}
+		sig, err := SignRRSet(rrset, key, priv, 0x4f000000, 0x50000000)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if g, e := sig.String(), fmt.Sprintf("A %d 1 60 20120713110120 20120101064104 %d example. %s",
+			alg, key.RData.(*DNSKEY).KeyTag(), strutil.Base64Encode(sig.Signature)); g != e {
+			t.Fatalf("%q != %q", g, e)
+		}
+
+		data, err := sig.SignedData(rrset)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		h := crypto.SHA1
+		if alg == AlgorithmRSA_SHA256 {
+			h = crypto.SHA256
+		}
+		hash := h.New()
+		hash.Write(data)
+		if err = rsa.VerifyPKCS1v15(&priv.PublicKey, h, hash.Sum(nil), sig.Signature); err != nil {
+			t.Fatal(err)
+		}
+
+		sig.Signature[0] ^= 1
+		if err = rsa.VerifyPKCS1v15(&priv.PublicKey, h, hash.Sum(nil), sig.Signature); err == nil {
+			t.Fatal("unexpected success")
+		}
+	}
+
+	key := &RR{"example.", TYPE_DNSKEY, CLASS_IN, 3600, rsaDNSKEY(&priv.PublicKey, AlgorithmRSA_SHA1)}
+	for i, bad := range []RRs{
+		{},
+		{rrset[0], &RR{"*.example.", TYPE_A, CLASS_IN, 61, &A{net.ParseIP("192.0.2.3")}}},
+		{rrset[0], &RR{"a.example.", TYPE_A, CLASS_IN, 60, &A{net.ParseIP("192.0.2.3")}}},
+		{rrset[0], &RR{"*.example.", TYPE_TXT, CLASS_IN, 60, &TXT{[]string{"x"}}}},
+	} {
+		if _, err := SignRRSet(bad, key, priv, 0, 1); err == nil {
+			t.Fatal(i, "unexpected success")
+		}
+	}
+
+	key.RData.(*DNSKEY).Algorithm = AlgorithmDSA_SHA1
+	if _, err := SignRRSet(rrset, key, priv, 0, 1); err == nil {
+		t.Fatal("unexpected success")
+	}
+}
+
+func TestRDataLen(t *testing.T) {
+	long := make([]string, 300)
+	for i := range long {
+		long[i] = strings.Repeat("x", 255)
+	}
+	tab := []struct {
+		rd dns.Wirer
+		n  int
+	}{
+		{&A{net.ParseIP("192.0.2.1")}, 4},
+		{&MX{10, "mx.example.com."}, 2 + 16},
+		{&MX{10, "."}, 3},
+		{&TXT{[]string{"foo", ""}}, 5},
+		{&TXT{long}, 300 * 256},
+	}
+	for i, v := range tab {
+		if g, e := RDataLen(v.rd), v.n; g != e {
+			t.Errorf("%d: %d != %d", i, g, e)
+		}
+	}
+}
+
+func TestEncodeErr(t *testing.T) {
+	long := make([]string, 300)
+	for i := range long {
+		long[i] = strings.Repeat("x", 255)
+	}
+	ok := &RR{"a.example.", TYPE_A, CLASS_IN, 0, &A{net.ParseIP("192.0.2.1")}}
+	big := &RR{"txt.b.example.", TYPE_TXT, CLASS_IN, 0, &TXT{long}}
+	ok2 := &RR{"b.example.", TYPE_A, CLASS_IN, 0, &A{net.ParseIP("192.0.2.2")}}
+
+	w := dns.NewWirebuf()
+	if err := ok.EncodeErr(w); err != nil {
+		t.Fatal(err)
+	}
+
+	n := len(w.Buf)
+	if err := big.EncodeErr(w); err == nil {
+		t.Fatal("unexpected success")
+	}
+
+	if g, e := len(w.Buf), n; g != e {
+		t.Fatal(g, e)
+	}
+
+	if err := ok2.EncodeErr(w); err != nil {
+		t.Fatal(err)
+	}
+
+	got := Bytes(w.Buf).Unpack()
+	if g, e := got.String(), (RRs{ok, ok2}).String(); g != e {
+		t.Fatalf("\n%s\n%s", g, e)
+	}
+
+	// Encode doesn't check rr, but it must not panic.
+	n = len(w.Buf)
+	big.Encode(w)
+	if len(w.Buf) <= n {
+		t.Fatal(len(w.Buf), n)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	bitmap := TypesEncode([]Type{TYPE_A, TYPE_RRSIG})
+	good := []dns.Wirer{
+		&A{net.ParseIP("192.0.2.1")},
+		&AAAA{net.ParseIP("2001:db8::1")},
+		&DNSKEY{256, 3, AlgorithmRSA_SHA1, []byte{1}},
+		&DS{1, AlgorithmRSA_SHA1, HashAlgorithmSHA1, []byte{1}},
+		&HINFO{"cpu", "os"},
+		&NSEC{"next.example.", bitmap},
+		&NSEC3{NSEC3PARAM{HashAlgorithmSHA1, 0, 10, make([]byte, 255)}, []byte{1}, bitmap},
+		&NSEC3PARAM{HashAlgorithmSHA1, 0, 10, nil},
+		&TXT{[]string{strings.Repeat("x", 255)}},
+	}
+	for i, rd := range good {
+		if err := rd.(Validator).Validate(); err != nil {
+			t.Errorf("%d: %s", i, err)
+		}
+	}
+
+	bad := []dns.Wirer{
+		&A{net.ParseIP("2001:db8::1")},
+		&A{nil},
+		&AAAA{net.ParseIP("192.0.2.1")},
+		&AAAA{net.IP{1, 2, 3, 4}},
+		&DNSKEY{256, 2, AlgorithmRSA_SHA1, []byte{1}},
+		&DNSKEY{256, 3, AlgorithmRSA_SHA1, nil},
+		&DS{1, AlgorithmRSA_SHA1, HashAlgorithmSHA1, nil},
+		&HINFO{strings.Repeat("x", 256), "os"},
+		&NSEC3{NSEC3PARAM{HashAlgorithmSHA1, 0, 10, make([]byte, 256)}, []byte{1}, bitmap},
+		&NSEC3{NSEC3PARAM{HashAlgorithmSHA1, 0, 10, nil}, nil, bitmap},
+		&NSEC3PARAM{HashAlgorithmSHA1, 0, 10, make([]byte, 256)},
+		&SPF{[]string{"", strings.Repeat("x", 256)}},
+	}
+	for i, rd := range bad {
+		if err := rd.(Validator).Validate(); err == nil {
+			t.Errorf("%d: unexpected success", i)
+		}
+	}
+
+	if err := (&RR{"example.", TYPE_A, CLASS_IN, 0, &A{net.ParseIP("::1")}}).Validate(); err == nil {
+		t.Error("unexpected success")
+	}
+
+	if err := (&RR{"example.", TYPE_MX, CLASS_IN, 0, &MX{10, "mx.example."}}).Validate(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestEncodeErrInvalid(t *testing.T) {
+	for i, rec := range []*RR{
+		{"example.", TYPE_A, CLASS_IN, 0, &A{net.ParseIP("2001:db8::1")}},
+		{"example.", TYPE_A, CLASS_IN, 0, &A{net.IP{1, 2, 3}}},
+		{"example.", TYPE_AAAA, CLASS_IN, 0, &AAAA{net.IP{1, 2, 3}}},
+		{"example.", TYPE_NSEC3PARAM, CLASS_IN, 0, &NSEC3PARAM{HashAlgorithmSHA1, 0, 1, make([]byte, 256)}},
+	} {
+		w := dns.NewWirebuf()
+		if err := rec.EncodeErr(w); err == nil {
+			t.Errorf("%d: unexpected success", i)
+		}
+
+		if len(w.Buf) != 0 {
+			t.Errorf("%d: % x", i, w.Buf)
+		}
+
+		// Encode doesn't validate and must not panic.
+		rec.Encode(w)
+		RRs{rec}.Pack()
+	}
+}
+
+func TestDecodeTruncated(t *testing.T) {
+	for _, rec := range allTypes() {
+		w := dns.NewWirebuf()
+		rec.Encode(w)
+		b := w.Buf
+		for n := 0; n < len(b); n++ {
+			func() {
+				defer func() {
+					if e := recover(); e != nil {
+						t.Errorf("%s: len %d/%d: panic %v", rec.Type, n, len(b), e)
+					}
+				}()
+
+				pos := 0
+				if err := (&RR{}).Decode(b[:n], &pos, nil); err == nil {
+					t.Errorf("%s: len %d/%d: unexpected success", rec.Type, n, len(b))
+				}
+			}()
+		}
+
+		// rdlength too big
+		c := append([]byte(nil), b...)
+		nw := dns.NewWirebuf()
+		dns.DomainName(rec.Name).Encode(nw)
+		rdl := len(nw.Buf) + 8
+		c[rdl+1]++
+		pos := 0
+		if err := (&RR{}).Decode(c, &pos, nil); err == nil {
+			t.Errorf("%s: rdlength+1: unexpected success", rec.Type)
+		}
+	}
+}
+
+func TestSafeDecodeRR(t *testing.T) {
+	hdr := func(typ Type, rdata ...byte) []byte {
+		b := []byte{0, byte(typ >> 8), byte(typ), 0, 1, 0, 0, 0, 0, byte(len(rdata) >> 8), byte(len(rdata))}
+		return append(b, rdata...)
+	}
+	bad := [][]byte{
+		{},
+		{0},
+		{0xc0, 0x00, 0, 1, 0, 1, 0, 0, 0, 0, 0, 0},             // self pointing name
+		{1, 'a', 0xc0, 0x00, 0, 1, 0, 1, 0, 0, 0, 0, 0, 0},     // pointer loop
+		{0xc0, 0x20, 0, 1, 0, 1, 0, 0, 0, 0, 0, 0},             // forward pointer
+		hdr(TYPE_A, 1, 2, 3),                                   // short A
+		hdr(TYPE_A, 1, 2, 3, 4, 5),                             // long A
+		hdr(TYPE_MX, 0),                                        // short MX
+		hdr(TYPE_MX, 0, 10, 3, 'f', 'o'),                       // truncated label
+		hdr(TYPE_SOA, 0, 0, 0, 0, 0, 1),                        // short SOA
+		hdr(TYPE_DS, 0, 1, 5, 1, 1, 2),                         // short digest
+		hdr(TYPE_NSEC3PARAM, 1, 0, 0, 1, 5, 1),                 // short salt
+		hdr(TYPE_TXT, 5, 'a'),                                  // short char-string
+		hdr(TYPE_RRSIG, 0, 1, 5, 2, 0, 0, 0, 0, 0, 0, 0, 0, 0), // short RRSIG
+	}
+	for i, b := range bad {
+		pos := 0
+		if _, err := SafeDecodeRR(b, &pos); err == nil {
+			t.Errorf("%d: unexpected success", i)
+		}
+	}
+
+	// Malformed NSEC3 bit map decodes but doesn't panic when printed.
+	pos := 0
+	rec, err := SafeDecodeRR(hdr(TYPE_NSEC3, 1, 0, 0, 1, 0, 0, 1, 0xaa, 0, 99), &pos)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if g, e := rec.RData.(*NSEC3).String(), `1 0 1 - L8====== \# 2 0063`; g != e {
+		t.Fatalf("%q != %q", g, e)
+	}
+
+	// Random mutations of valid records.
+	rng := rand.New(rand.NewSource(1))
+	for _, rec := range allTypes() {
+		w := dns.NewWirebuf()
+		rec.Encode(w)
+		for i := 0; i < 200; i++ {
+			b := append([]byte(nil), w.Buf...)
+			for j := rng.Intn(4); j >= 0; j-- {
+				b[rng.Intn(len(b))] = byte(rng.Int())
+			}
+			pos := 0
+			if got, err := SafeDecodeRR(b, &pos); err == nil {
+				_ = got.String()
+			} else if strings.HasPrefix(err.Error(), "SafeDecodeRR") {
+				t.Errorf("%s: % x: %s", rec.Type, b, err)
+			}
+		}
+	}
+}
+
+func TestNSECStringBadBitmap(t *testing.T) {
+	for i, v := range []struct {
+		bits []byte
+		s    string
+	}{
+		{nil, ""},
+		{TypesEncode([]Type{TYPE_A, TYPE_MX}), "A MX"},
+		{[]byte{0}, `\# 1 00`},
+		{[]byte{0, 0}, `\# 2 0000`},
+		{[]byte{0, 33}, `\# 2 0021`},
+		{[]byte{0, 2, 0x40}, `\# 3 000240`},
+	} {
+		if g, e := (&NSEC{"next.example.", v.bits}).String(), "next.example. "+v.s; g != e {
+			t.Errorf("%d: %q != %q", i, g, e)
+		}
+
+		nsec3 := &NSEC3{NSEC3PARAM{HashAlgorithmSHA1, 1, 10, []byte{0xab}}, []byte{0xaa}, v.bits}
+		if g, e := nsec3.String(), "1 1 10 ab L8====== "+v.s; g != e {
+			t.Errorf("%d: %q != %q", i, g, e)
+		}
+	}
+}
+
+func TestRDataEqual(t *testing.T) {
+	mk := func(cert ...byte) *RR {
+		return &RR{"_443._tcp.example.", TYPE_TLSA, CLASS_IN, 0, &TLSA{TLSAUsageMatchCert, TLSASelectorSubjectPKInfo, TLSAMatchingTypeSHA256, cert}}
+	}
+	a, b, c := mk(1, 2, 3), mk(1, 2, 3), mk(1, 2, 4)
+	if !a.Equal(b) {
+		t.Fatal("a != b")
+	}
+
+	if a.Equal(c) {
+		t.Fatal("a == c")
+	}
+
+	c.RData.(*TLSA).Certificate[2] = 3
+	c.RData.(*TLSA).Usage = TLSAUsagePKIX_EE
+	if a.Equal(c) {
+		t.Fatal("a == c")
+	}
+
+	// Typed vs. generic RDATA, case insensitive names.
+	mx := &RR{"example.", TYPE_MX, CLASS_IN, 0, &MX{10, "MX.Example."}}
+	raw := &RR{"example.", TYPE_MX, CLASS_IN, 0, &RDATA{}}
+	*raw.RData.(*RDATA) = wireBytes(&MX{10, "mx.example."})
+	if !mx.Equal(raw) || !raw.Equal(mx) {
+		t.Fatal("mx != raw")
+	}
+
+	// Nil RData.
+	n1 := &RR{"example.", TYPE_MX, CLASS_IN, 0, nil}
+	n2 := &RR{"example.", TYPE_MX, CLASS_IN, 0, nil}
+	if !n1.Equal(n2) {
+		t.Fatal("n1 != n2")
+	}
+
+	if n1.Equal(mx) || mx.Equal(n1) {
+		t.Fatal("n1 == mx")
+	}
+}
+
+func TestDiff(t *testing.T) {
+	a1 := &RR{"a.example.", TYPE_A, CLASS_IN, 300, &A{net.ParseIP("192.0.2.1")}}
+	a2 := &RR{"a.example.", TYPE_A, CLASS_IN, 300, &A{net.ParseIP("192.0.2.2")}}
+	a2ttl := &RR{"A.example.", TYPE_A, CLASS_IN, 600, &A{net.ParseIP("192.0.2.2")}}
+	mx := &RR{"example.", TYPE_MX, CLASS_IN, 300, &MX{10, "a.example."}}
+	mx2 := &RR{"example.", TYPE_MX, CLASS_IN, 300, &MX{10, "A.EXAMPLE."}}
+	txt := &RR{"example.", TYPE_TXT, CLASS_IN, 300, &TXT{[]string{"x"}}}
+
+	old := RRs{a1, a2, mx}
+	new := RRs{a2ttl, mx2, txt}
+	added, removed := old.Diff(new)
+	if g, e := added.String(), (RRs{a2ttl, txt}).String(); g != e {
+		t.Fatalf("\n%s\n%s", g, e)
+	}
+
+	if g, e := removed.String(), (RRs{a1, a2}).String(); g != e {
+		t.Fatalf("\n%s\n%s", g, e)
+	}
+
+	added, removed = old.DiffTTL(new, false)
+	if g, e := added.String(), (RRs{txt}).String(); g != e {
+		t.Fatalf("\n%s\n%s", g, e)
+	}
+
+	if g, e := removed.String(), (RRs{a1}).String(); g != e {
+		t.Fatalf("\n%s\n%s", g, e)
+	}
+
+	if added, removed = old.Diff(old); len(added) != 0 || len(removed) != 0 {
+		t.Fatalf("\n%s\n%s", added, removed)
+	}
+}
+
+func TestApply(t *testing.T) {
+	mk := func() RRs {
+		return RRs{
+			&RR{"example.", TYPE_SOA, CLASS_IN, 300, &SOA{"ns.example.", "hostmaster.example.", 1, 2, 3, 4, 5}},
+			&RR{"a.example.", TYPE_A, CLASS_IN, 300, &A{net.ParseIP("192.0.2.1")}},
+			&RR{"a.example.", TYPE_A, CLASS_IN, 300, &A{net.ParseIP("192.0.2.2")}},
+			&RR{"example.", TYPE_MX, CLASS_IN, 300, &MX{10, "a.example."}},
+		}
+	}
+	old := mk()
+	new := mk()
+	new[0].RData.(*SOA).Serial = 2
+	new[2].TTL = 600
+	new[3] = &RR{"b.example.", TYPE_AAAA, CLASS_IN, 300, &AAAA{net.ParseIP("2001:db8::1")}}
+	added, removed := old.Diff(new)
+
+	zone := mk()
+	zone.Apply(added, removed)
+	zone.Sort()
+	new.Sort()
+	if g, e := zone.String(), new.String(); g != e {
+		t.Fatalf("\n%s\n!=\n%s", g, e)
+	}
+
+	zone.Apply(added, removed)
+	zone.Sort()
+	if g, e := zone.String(), new.String(); g != e {
+		t.Fatalf("\n%s\n!=\n%s", g, e)
+	}
+}
+
+func TestCoveredBy(t *testing.T) {
+	zone := RRs{
+		&RR{"example.", TYPE_A, CLASS_IN, 60, &A{net.ParseIP("192.0.2.9")}},
+		&RR{"*.example.", TYPE_A, CLASS_IN, 60, &A{net.ParseIP("192.0.2.2")}},
+		&RR{"*.example.", TYPE_TXT, CLASS_IN, 60, &TXT{[]string{"x"}}},
+		&RR{"*.Example.", TYPE_A, CLASS_IN, 60, &A{net.ParseIP("192.0.2.1")}},
+		&RR{"*.example.", TYPE_A, CLASS_CH, 60, &A{net.ParseIP("192.0.2.3")}},
+		&RR{"bar.example.", TYPE_A, CLASS_IN, 60, &A{net.ParseIP("192.0.2.4")}},
+		&RR{"x.bar.example.", TYPE_A, CLASS_IN, 60, &A{net.ParseIP("192.0.2.5")}},
+	}
+	sig := &RR{"foo.example.", TYPE_RRSIG, CLASS_IN, 60, &RRSIG{Type: TYPE_A, Labels: 1, Name: "example."}}
+	got := zone.CoveredBy(sig)
+	if g, e := got.String(), (RRs{zone[3], zone[1]}).String(); g != e {
+		t.Fatalf("\n%s\n%s", g, e)
+	}
+
+	// The signed data of the wildcard RRset is the same as of the expanded one.
+	expanded := got.Copy()
+	for _, v := range expanded {
+		v.Name = "foo.example."
+	}
+	d1, err := sig.RData.(*RRSIG).SignedData(got)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d2, err := sig.RData.(*RRSIG).SignedData(expanded)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(d1, d2) {
+		t.Fatalf("\n%x\n%x", d1, d2)
+	}
+
+	sig.Name, sig.RData.(*RRSIG).Labels = "bar.example.", 2
+	if g, e := zone.CoveredBy(sig).String(), zone[5].String(); g != e {
+		t.Fatalf("\n%s\n%s", g, e)
+	}
+
+	sig.Name = "y.bar.example."
+	if g := zone.CoveredBy(sig); len(g) != 0 {
+		t.Fatal(g)
+	}
+
+	if g := zone.CoveredBy(zone[0]); g != nil {
+		t.Fatal(g)
+	}
+}
+
+func TestParseTTL(t *testing.T) {
+	for i, v := range []struct {
+		s   string
+		ttl int32
+		ok  bool
+	}{
+		{"0", 0, true},
+		{"3600", 3600, true},
+		{"1h", 3600, true},
+		{"1H", 3600, true},
+		{"1h30m", 5400, true},
+		{"2w3d", 2*604800 + 3*86400, true},
+		{"1d2h3m4s", 86400 + 7200 + 180 + 4, true},
+		{"1m30", 90, true},
+		{"2147483647", math.MaxInt32, true},
+		{"2147483648", 0, false},
+		{"3551w", 0, false},
+		{"3550w", 3550 * 604800, true},
+		{"99999999999999999999", 0, false},
+		{"", 0, false},
+		{"-1", 0, false},
+		{"h", 0, false},
+		{"1hh", 0, false},
+		{"1x", 0, false},
+		{" 1", 0, false},
+	} {
+		ttl, err := ParseTTL(v.s)
+		if g, e := err == nil, v.ok; g != e {
+			t.Errorf("%d: %q: %v", i, v.s, err)
+			continue
+		}
+
+		if g, e := ttl, v.ttl; g != e {
+			t.Errorf("%d: %q: %d != %d", i, v.s, g, e)
+		}
+	}
+}
+
+func TestSerialLess(t *testing.T) {
+	for i, v := range []struct {
+		a, b uint32
+		less bool
+	}{
+		{0, 0, false},
+		{0, 1, true},
+		{1, 0, false},
+		{4294967295, 0, true},
+		{0, 4294967295, false},
+		{4294967295, 1<<31 - 2, true},
+		{1<<31 - 2, 4294967295, false},
+		{0, 1<<31 - 1, true},
+		{1<<31 - 1, 0, false},
+		{0, 1 << 31, false}, // undefined
+		{1 << 31, 0, false}, // undefined
+		{100, 1<<31 + 100, false},
+		{1<<31 + 100, 100, false},
+		{1<<31 + 101, 100, true},
+	} {
+		if g, e := SerialLess(v.a, v.b), v.less; g != e {
+			t.Errorf("%d: %d < %d: %t != %t", i, v.a, v.b, g, e)
+		}
+	}
+
+	soa := &SOA{Serial: 0}
+	if !soa.SerialAfter(4294967295) {
+		t.Error("0 not after 4294967295")
+	}
+
+	if soa.SerialAfter(0) || soa.SerialAfter(1) || soa.SerialAfter(1<<31) {
+		t.Error("unexpected SerialAfter")
+	}
+}
+
+func TestBumpSerial(t *testing.T) {
+	now := time.Date(2012, 3, 4, 23, 59, 0, 0, time.UTC)
+	for i, v := range []struct{ serial, bumped uint32 }{
+		{0, 2012030401},          // new zone
+		{42, 2012030401},         // plain counter
+		{2012030301, 2012030401}, // new day reset
+		{2012030400, 2012030401}, // today, counter 00
+		{2012030401, 2012030402}, // same day bump
+		{2012030498, 2012030499}, // same day bump
+		{2012030499, 2012030500}, // counter overflow
+		{2012030612, 2012030613}, // serial in the future
+		{4294967295, 0},          // wrap
+	} {
+		soa := &SOA{Serial: v.serial}
+		soa.BumpSerial(now)
+		if g, e := soa.Serial, v.bumped; g != e {
+			t.Errorf("%d: %d: %d != %d", i, v.serial, g, e)
+		}
+	}
+}
+
+func TestNewA(t *testing.T) {
+	if _, err := NewA(net.ParseIP("192.0.2.1")); err != nil {
+		t.Error(err)
+	}
+
+	if _, err := NewA(net.IP{192, 0, 2, 1}); err != nil {
+		t.Error(err)
+	}
+
+	for i, ip := range []net.IP{nil, net.ParseIP("2001:db8::1"), net.IP{1, 2, 3}} {
+		if rd, err := NewA(ip); err == nil || rd != nil {
+			t.Errorf("%d: unexpected success", i)
+		}
+	}
+
+	if _, err := NewAAAA(net.ParseIP("2001:db8::1")); err != nil {
+		t.Error(err)
+	}
+
+	for i, ip := range []net.IP{nil, net.ParseIP("192.0.2.1"), net.IP{192, 0, 2, 1}, net.ParseIP("::ffff:192.0.2.1")} {
+		if rd, err := NewAAAA(ip); err == nil || rd != nil {
+			t.Errorf("%d: unexpected success", i)
+		}
+	}
+}
+
+func TestNegativeCache(t *testing.T) {
+	now := time.Unix(1e9, 0)
+	c := NewNegativeCache()
+	c.Now = func() time.Time { return now }
+	soa := &RR{"example.com.", TYPE_SOA, CLASS_IN, 3600, &SOA{"ns.example.com.", "hostmaster.example.com.", 1, 7200, 3600, 1209600, 300}}
+	if ttl, err := NegativeTTL(soa); err != nil || ttl != 300 {
+		t.Fatal(ttl, err)
+	}
+
+	if err := c.Add(&RR{"www.example.com.", TYPE_NODATA, CLASS_IN, 0, &NODATA{TYPE_AAAA}}, soa); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.Add(&RR{"nx.example.com.", TYPE_NXDOMAIN, CLASS_IN, 0, &NXDOMAIN{}}, soa); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.Add(&RR{"www.example.com.", TYPE_A, CLASS_IN, 0, &A{net.IP{192, 0, 2, 1}}}, soa); err == nil {
+		t.Fatal("unexpected success")
+	}
+
+	type res struct{ present, negative, expired bool }
+	get := func(name string, typ Type) res {
+		p, n, e := c.Get(name, typ)
+		return res{p, n, e}
+	}
+
+	for i, v := range []struct {
+		dt   int64
+		name string
+		typ  Type
+		res
+	}{
+		{0, "www.example.com.", TYPE_AAAA, res{true, true, false}},
+		{0, "WWW.example.com.", TYPE_AAAA, res{true, true, false}},
+		{0, "www.example.com.", TYPE_A, res{false, false, false}},
+		{0, "nx.example.com.", TYPE_A, res{false, true, false}},
+		{0, "nx.example.com.", TYPE_MX, res{false, true, false}},
+		{299, "www.example.com.", TYPE_AAAA, res{true, true, false}},
+		{299, "nx.example.com.", TYPE_A, res{false, true, false}},
+		{300, "www.example.com.", TYPE_AAAA, res{true, true, true}},
+		{300, "www.example.com.", TYPE_AAAA, res{false, false, false}},
+		{300, "nx.example.com.", TYPE_A, res{false, true, true}},
+		{300, "nx.example.com.", TYPE_A, res{false, false, false}},
+	} {
+		now = time.Unix(1e9+v.dt, 0)
+		if g, e := get(v.name, v.typ), v.res; g != e {
+			t.Errorf("%d: %s %s: %+v != %+v", i, v.name, v.typ, g, e)
+		}
+	}
+
+	if p, n, _ := c.GetClass("www.example.com.", TYPE_AAAA, CLASS_CH); p || n {
+		t.Error("unexpected class CH hit")
+	}
+}
+
+func TestRRSIGValid(t *testing.T) {
+	defer func(f func() time.Time) { Now = f }(Now)
+
+	// RFC 4034/3.3
+	sig := &RRSIG{TYPE_A, AlgorithmRSA_SHA1, 3, 86400, 1048354263, 1045762263, 2642, "example.", []byte{1, 2, 3}}
+	for i, v := range []struct {
+		secs  int64
+		valid bool
+	}{
+		{1045762262, false},
+		{1045762263, true},
+		{1047000000, true},
+		{1048354263, true},
+		{1048354264, false},
+	} {
+		Now = func() time.Time { return time.Unix(v.secs, 0) }
+		if g, e := sig.Valid(), v.valid; g != e {
+			t.Errorf("%d: %d: %t != %t", i, v.secs, g, e)
+		}
+	}
+
+	// Validity period wrapping 2^32 seconds.
+	sig.Inception, sig.Expiration = 1<<32-100, 100
+	for i, secs := range []int64{1<<32 - 100, 1 << 32, 1<<32 + 100} {
+		if !sig.ValidAt(time.Unix(secs, 0)) {
+			t.Errorf("%d: %d: not valid", i, secs)
+		}
+	}
+}
+
+func TestParseSigTime(t *testing.T) {
+	for i, v := range []struct {
+		s    string
+		secs uint32
+	}{
+		{"20030322173103", 1048354263}, // RFC 4034/3.3
+		{"20030220173103", 1045762263},
+		{"19700101000000", 0},
+		{"21060207062815", 4294967295},
+		{"1048354263", 1048354263},
+		{"0", 0},
+		{"4294967295", 4294967295},
+	} {
+		secs, err := ParseSigTime(v.s)
+		if err != nil {
+			t.Errorf("%d: %s", i, err)
+			continue
+		}
+
+		if g, e := secs, v.secs; g != e {
+			t.Errorf("%d: %q: %d != %d", i, v.s, g, e)
+		}
+
+		if len(v.s) != 14 {
+			continue
+		}
+
+		if g, e := dns.Seconds2String(int64(secs)), v.s; g != e {
+			t.Errorf("%d: %q != %q", i, g, e)
+		}
+	}
+
+	if secs, err := ParseSigTime("21060207062816"); err != nil || secs != 0 {
+		t.Error("modulo 2^32:", secs, err)
+	}
+
+	for i, s := range []string{"", "x", "-1", "4294967296", "2003032217310", "20031322173103", "2003032217310x"} {
+		if _, err := ParseSigTime(s); err == nil {
+			t.Errorf("%d: %q: unexpected success", i, s)
+		}
+	}
+}
+
+func TestRRSIGStringTimes(t *testing.T) {
+	// RFC 4034/3.3
+	sig := &RRSIG{TYPE_A, AlgorithmRSA_SHA1, 3, 86400, 1048354263, 1045762263, 2642, "example.", []byte{1, 2, 3}}
+	if g, e := sig.String(), "A 5 3 86400 20030322173103 20030220173103 2642 example. AQID"; g != e {
+		t.Fatalf("\n%s\n%s", g, e)
+	}
+}
+
+func TestTypeClass(t *testing.T) {
+	for _, v := range []struct {
+		t                      Type
+		pseudo, meta, dataType bool
+	}{
+		{0, false, false, false},
+		{TYPE_A, false, false, true},
+		{TYPE_RRSIG, false, false, true},
+		{TYPE_TLSA, false, false, true},
+		{TYPE_DLV, false, false, true},
+		{TYPE_OPT, false, true, false},
+		{TYPE_TSIG, false, true, false},
+		{TYPE_AXFR, false, true, false},
+		{255, false, true, false},
+		{TYPE_NODATA, true, false, false},
+		{TYPE_NXDOMAIN, true, false, false},
+	} {
+		if g, e := v.t.IsPseudo(), v.pseudo; g != e {
+			t.Errorf("%s IsPseudo: %t != %t", v.t, g, e)
+		}
+
+		if g, e := v.t.IsMeta(), v.meta; g != e {
+			t.Errorf("%s IsMeta: %t != %t", v.t, g, e)
+		}
+
+		if g, e := v.t.IsData(), v.dataType; g != e {
+			t.Errorf("%s IsData: %t != %t", v.t, g, e)
+		}
+	}
+}
+
+func TestEscapeCharString(t *testing.T) {
+	for i, v := range []struct{ s, e string }{
+		{"", ""},
+		{"abc def", "abc def"},
+		{`a"b`, `a\"b`},
+		{`a\b`, `a\\b`},
+		{"\x00\t\n\x7f\xff", `\000\009\010\127\255`},
+		{"~ ", "~ "},
+	} {
+		g := escapeCharString(v.s)
+		if g != v.e {
+			t.Errorf("%d: %q != %q", i, g, v.e)
+			continue
+		}
+
+		u, err := UnescapeCharString(g)
+		if err != nil {
+			t.Errorf("%d: %s", i, err)
+			continue
+		}
+
+		if u != v.s {
+			t.Errorf("%d: %q != %q", i, u, v.s)
+		}
+	}
+
+	b := make([]byte, 256)
+	for i := range b {
+		b[i] = byte(i)
+	}
+	rd := &TXT{[]string{string(b), string(b[128:])}}
+	for i, s := range rd.S {
+		if u, err := UnescapeCharString(escapeCharString(s)); err != nil || u != s {
+			t.Errorf("%d: %q %v", i, u, err)
+		}
+	}
+
+	if g, e := (&TXT{[]string{`C:\`, "a\x01"}}).String(), `"C:\\" "a\001"`; g != e {
+		t.Errorf("%s != %s", g, e)
+	}
+
+	if g, err := UnescapeCharString(`\a\.\065`); err != nil || g != "a.A" {
+		t.Error(g, err)
+	}
+
+	for i, s := range []string{`\`, `a\`, `\1`, `\25`, `\256`, `\1x2`} {
+		if _, err := UnescapeCharString(s); err == nil {
+			t.Errorf("%d: %q: unexpected success", i, s)
+		}
+	}
+}
+
+func TestEscapeName(t *testing.T) {
+	for i, v := range []struct{ s, e string }{
+		{".", "."},
+		{"www.example.com.", "www.example.com."},
+		{`a\.b.example.`, `a\.b.example.`},
+		{`a\\b.example.`, `a\\b.example.`},
+		{`a\065.example.`, `a\065.example.`},
+		{"a b.example.", `a\032b.example.`},
+		{"a\x00\xff.example.", `a\000\255.example.`},
+		{`a"();@$.example.`, `a\"\(\)\;\@\$.example.`},
+	} {
+		if g, e := escapeName(v.s), v.e; g != e {
+			t.Errorf("%d: %q != %q", i, g, e)
+		}
+	}
+
+	r := &RR{`a\.b.example.`, TYPE_MX, CLASS_IN, 3600, &MX{10, `mail\.server.example.`}}
+	if g, e := r.String(), "a\\.b.example.\tIN\t3600\tMX 10 mail\\.server.example."; g != e {
+		t.Errorf("%q != %q", g, e)
+	}
+
+	r = &RR{"a b.example.", TYPE_CNAME, CLASS_IN, 60, &CNAME{"c;d.example."}}
+	if g, e := r.String(), "a\\032b.example.\tIN\t60\tCNAME c\\;d.example."; g != e {
+		t.Errorf("%q != %q", g, e)
+	}
+
+	// Names decoded from the wire round-trip through String.
+	w := dns.NewWirebuf()
+	w.DisableCompression()
+	(&RR{"x.example.", TYPE_CNAME, CLASS_IN, 60, &CNAME{"c.example."}}).Encode(w)
+	b := append([]byte(nil), w.Buf...)
+	b[1] = 200                      // x -> \200
+	copy(b[11+10:], []byte{1, '.'}) // c -> .
+	r = &RR{}
+	p := 0
+	if err := r.Decode(b, &p, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if g, e := r.String(), "\\200.example.\tIN\t60\tCNAME \\..example."; g != e {
+		t.Fatalf("%q != %q", g, e)
+	}
+
+	w = dns.NewWirebuf()
+	w.DisableCompression()
+	(&RR{`\200.example.`, TYPE_CNAME, CLASS_IN, 60, &CNAME{`\..example.`}}).Encode(w)
+	if !bytes.Equal(w.Buf, b) {
+		t.Fatalf("\n% x\n% x", w.Buf, b)
+	}
+}
+
+func TestWireLen(t *testing.T) {
+	if g := RRs(nil).WireLen(); g != 0 {
+		t.Fatal(g)
+	}
+
+	a := &RR{"www.example.com.", TYPE_A, CLASS_IN, 3600, &A{net.IP{192, 0, 2, 1}}}
+	if g, e := (RRs{a}).WireLen(), len((RRs{a}).Pack()); g != e {
+		t.Fatal(g, e)
+	}
+
+	// 17 octets owner name, 10 octets type, class, TTL and RDLENGTH
+	if g, e := (RRs{a}).WireLen(), 17+10+4; g != e {
+		t.Fatal(g, e)
+	}
+
+	rrs := RRs{
+		a,
+		&RR{"www.example.com.", TYPE_A, CLASS_IN, 3600, &A{net.IP{192, 0, 2, 2}}},
+		&RR{"example.com.", TYPE_MX, CLASS_IN, 3600, &MX{10, "mail.example.com."}},
+	}
+	g, packed := rrs.WireLen(), len(rrs.Pack())
+	if e := 31 + 31 + 13 + 10 + 2 + 18; g != e {
+		t.Fatal(g, e)
+	}
+
+	if g <= packed {
+		t.Fatal(g, packed)
+	}
+}
+
+func TestFitInto(t *testing.T) {
+	a1 := &RR{"www.example.com.", TYPE_A, CLASS_IN, 3600, &A{net.IP{192, 0, 2, 1}}}
+	mx := &RR{"example.com.", TYPE_MX, CLASS_IN, 3600, &MX{10, "mail.example.com."}}
+	a2 := &RR{"www.example.com.", TYPE_A, CLASS_IN, 3600, &A{net.IP{192, 0, 2, 2}}}
+	aaaa := &RR{"www.example.com.", TYPE_AAAA, CLASS_IN, 3600, &AAAA{net.ParseIP("2001:db8::1")}}
+	rrs := RRs{a1, mx, a2, aaaa}
+	const la, lmx, laaaa = 31, 43, 43
+
+	for i, v := range []struct {
+		max          int
+		fit, dropped RRs
+	}{
+		{0, nil, RRs{a1, a2, mx, aaaa}},
+		{la - 1, nil, RRs{a1, a2, mx, aaaa}},
+		{la, RRs{a1}, RRs{a2, mx, aaaa}},
+		{2*la - 1, RRs{a1}, RRs{a2, mx, aaaa}},
+		{2 * la, RRs{a1, a2}, RRs{mx, aaaa}},
+		{2*la + lmx - 1, RRs{a1, a2}, RRs{mx, aaaa}},
+		{2*la + lmx, RRs{a1, a2, mx}, RRs{aaaa}},
+		{2*la + lmx + laaaa - 1, RRs{a1, a2, mx}, RRs{aaaa}},
+		{2*la + lmx + laaaa, RRs{a1, a2, mx, aaaa}, nil},
+		{1 << 16, RRs{a1, a2, mx, aaaa}, nil},
+	} {
+		fit, dropped := rrs.FitInto(v.max)
+		if g, e := fit.String(), v.fit.String(); g != e {
+			t.Errorf("%d: fit\n%s\n%s", i, g, e)
+		}
+
+		if g, e := dropped.String(), v.dropped.String(); g != e {
+			t.Errorf("%d: dropped\n%s\n%s", i, g, e)
+		}
+	}
+}
+
+func TestToDS(t *testing.T) {
+	// RFC 4034/5.4
+	key, err := strutil.Base64Decode([]byte("" +
+		"AQOeiiR0GOMYkDshWoSKz9XzfwJr1AYtsmx3TGkJaNXVbfi/2pHm822aJ5iI9BMz" +
+		"NXxeYCmZDRD99WYwYqUSdjMmmAphXdvxegXd/M5+X7OrzKBaMbCVdFLUUh6DhweJ" +
+		"BjEVv5f2wwjM9XzcnOf+EPbtG9DMBmADjFDc2w/rljwvFw=="))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ds := (&DNSKEY{256, 3, AlgorithmRSA_SHA1, key}).ToDS("DSKEY.example.com")
+	if g, e := ds.String(), "60485 5 1 2bb183af5f22588179a53b0a98631fad1a292118"; g != e {
+		t.Fatalf("\n%s\n%s", g, e)
+	}
+}
+
+func TestParseDSAKey(t *testing.T) {
+	// There's no published DSA DNSSEC test vector, so this is a DSA-1024
+	// key generated by
+	//
+	//	openssl genpkey -genparam -algorithm DSA -pkeyopt dsa_paramgen_bits:1024 -pkeyopt dsa_paramgen_q_bits:160
+	//	openssl genpkey -paramfile ...
+	//
+	// and converted to the RFC 2536/2 T|Q|P|G|Y form by a short script.
+	// The same script computed the key tag using the RFC 4034/B algorithm
+	// and the DS digest as SHA-1 of the owner name and the DNSKEY RDATA
+	// in wire format (RFC 4034/5.1.4). The RRSIG signed data (RFC
+	// 4034/3.1.8.1) were signed by "openssl dgst -sha1 -sign" and the DER
+	// encoded r, s converted to the RFC 2536/3 T|R|S form.
+	key, err := base64.StdEncoding.DecodeString("CICbtJTMqGvprnQtCjtJTbZ10jVh2StH6hob3BsknrnTL2rMDHb+xTEnpDf6W12L" +
+		"S3HzIyiD+tiG9Pi7KKv58Y28bAgFV7cozoFTJmIMDqZGpeN5rADeDC6+WUQM0IYq" +
+		"q0bANTBz7VVbqSDLqmOM0ld1dmjB/rECCEK7Y/4HqCaC9qFMm8Kx7ROfzVlSEiZb" +
+		"R0HHmJFVJPkJd3mEE2E24m0vL9B1ZlxfyHz7JS6uvK51jQZSS4uRBf6/F8oJK+Zh" +
+		"jmepD5cXEWlaOMvVsBTG7TrW+yFoS6ztEweEDl+ONTkwze6W0gfAxtIaxoNQ4N6b" +
+		"XCOj309jouDl6zZsH9vHi2EBnu/tY29PXjkqjuoY/0WFa9SldnR5wt6n65Mx3tDK" +
+		"D9r+VGXUQNIC/5+dxsHnm/CrtDygSmkajmhCpV4G7D3j8bq17dyevNZhRPsz14q4" +
+		"rn4vrOkH9t+fAtCM70/iazBI3oaf8Z/FPalUrkUGm6krSOaL8pR1Yxky8m+I6nTf" +
+		"RVDppLLV8vtxPHhEqivfoWQZsAuK")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signature, err := base64.StdEncoding.DecodeString("CBTUl93izqVl78/cpqroz8WMSy7FOUdPi9AJUt1o3K7gHE3lk3HA6ps=")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	k, err := parseDSAKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if g, e := k.P.BitLen(), 1024; g != e {
+		t.Fatal(g, e)
+	}
+
+	if g, e := k.Q.BitLen(), 160; g != e {
+		t.Fatal(g, e)
+	}
+
+	rd := &DNSKEY{256, 3, AlgorithmDSA_SHA1, key}
+	if g, e := rd.KeyTag(), uint16(2573); g != e {
+		t.Fatal(g, e)
+	}
+
+	if g, e := rd.ToDS("DSA.example.").String(), "2573 3 1 ee2ee79c5d3423f183d207b9fe181620062f13f9"; g != e {
+		t.Fatalf("\n%s\n%s", g, e)
+	}
+
+	defer func(f func() time.Time) { Now = f }(Now)
+	Now = func() time.Time { return time.Unix(0x4f800000, 0) }
+	keyRR := &RR{"dsa.example.", TYPE_DNSKEY, CLASS_IN, 3600, rd}
+	rrset := RRs{{"a.dsa.example.", TYPE_A, CLASS_IN, 3600, &A{net.ParseIP("192.0.2.1")}}}
+	sig := &RRSIG{TYPE_A, AlgorithmDSA_SHA1, 3, 3600, 0x50000000, 0x4f800000, 2573, "dsa.example.", signature}
+	if err := sig.Verify(keyRR, rrset); err != nil {
+		t.Fatal(err)
+	}
+
+	rrset[0].RData = &A{net.ParseIP("192.0.2.2")}
+	if err := sig.Verify(keyRR, rrset); err == nil {
+		t.Fatal("unexpected success")
+	}
+
+	for _, bad := range [][]byte{nil, key[:len(key)-1], append(key[:len(key):len(key)], 0)} {
+		if _, err := parseDSAKey(bad); err == nil {
+			t.Fatal("unexpected success")
+		}
+	}
+
+	if _, err := parseDSAKey(append([]byte{9}, make([]byte, 20+3*136)...)); err == nil {
+		t.Fatal("unexpected success")
+	}
+}
+
+func TestRSAPublicKey(t *testing.T) {
+	priv, err := rsa.GenerateKey(crand.Reader, 512)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pub := &priv.PublicKey
+	k, err := rsaDNSKEY(pub, AlgorithmRSA_SHA256).RSAPublicKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if k.E != pub.E || k.N.Cmp(pub.N) != 0 {
+		t.Fatal(k, pub)
+	}
+
+	// Three octets exponent length.
+	e := big.NewInt(int64(pub.E)).Bytes()
+	key := append([]byte{0, 0, byte(len(e))}, e...)
+	key = append(key, pub.N.Bytes()...)
+	if k, err = (&DNSKEY{256, 3, AlgorithmRSA_SHA1, key}).RSAPublicKey(); err != nil {
+		t.Fatal(err)
+	}
+
+	if k.E != pub.E || k.N.Cmp(pub.N) != 0 {
+		t.Fatal(k, pub)
+	}
+
+	for i, v := range []struct {
+		alg AlgorithmType
+		key []byte
+	}{
+		{AlgorithmDSA_SHA1, key},
+		{AlgorithmRSA_SHA1, nil},
+		{AlgorithmRSA_SHA1, []byte{0}},
+		{AlgorithmRSA_SHA1, []byte{0, 0}},
+		{AlgorithmRSA_SHA1, []byte{0, 0, 0, 1, 2}},
+		{AlgorithmRSA_SHA1, []byte{3, 1, 0, 1}},
+		{AlgorithmRSA_SHA1, []byte{4, 1, 0, 1, 2}},
+		{AlgorithmRSA_SHA1, []byte{0, 1, 0, 1, 0, 1}},
+	} {
+		if _, err := (&DNSKEY{256, 3, v.alg, v.key}).RSAPublicKey(); err == nil {
+			t.Errorf("%d: unexpected success", i)
+		}
+	}
+}
+
+func TestAlgorithmType(t *testing.T) {
+	for _, v := range []struct {
+		a AlgorithmType
+		s string
+	}{
+		{0, "0"},
+		{AlgorithmRSA_MD5, "RSAMD5"},
+		{AlgorithmDSA_SHA1, "DSA"},
+		{AlgorithmRSA_SHA1, "RSASHA1"},
+		{AlgorithmRSA_SHA1_NSEC3_SHA1, "RSASHA1-NSEC3-SHA1"},
+		{AlgorithmRSA_SHA256, "RSASHA256"},
+		{9, "9"},
+		{AlgorithmRSA_SHA512, "RSASHA512"},
+		{AlgorithmECDSA_P256_SHA256, "ECDSAP256SHA256"},
+		{AlgorithmECDSA_P384_SHA384, "ECDSAP384SHA384"},
+		{AlgorithmED448, "ED448"},
+		{AlgorithmIndirect, "INDIRECT"},
+		{AlgorithmPrivateOID, "PRIVATEOID"},
+		{255, "255"},
+	} {
+		if g, e := v.a.String(), v.s; g != e {
+			t.Errorf("%d: %q != %q", v.a, g, e)
+		}
+	}
+
+	if AlgorithmRSA_SHA256 != 8 || AlgorithmRSA_SHA512 != 10 || AlgorithmECDSA_P256_SHA256 != 13 ||
+		AlgorithmED448 != 16 || AlgorithmIndirect != 252 || AlgorithmReserved1255 != 255 {
+		t.Fatal("invalid algorithm values")
+	}
+}
+
+func TestVerifyECDSA(t *testing.T) {
+	defer func(f func() time.Time) { Now = f }(Now)
+
+	// RFC 6605/6.1
+	key, err := strutil.Base64Decode([]byte("" +
+		"GojIhhXUN/u4v54ZQqGSnyhWJwaubCvTmeexv7bR6edb" +
+		"krSqQpF64cYbcB7wNcP+e+MAnLr+Wi9xMWyQLc8NAA=="))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signature, err := strutil.Base64Decode([]byte("" +
+		"qx6wLYqmh+l9oCKTN6qIc+bw6ya+KJ8oMz0YP107epXA" +
+		"yGmt+3SNruPFKG7tZoLBLlUzGGus7ZwmwWep666VCw=="))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dnskey := &RR{"example.net.", TYPE_DNSKEY, CLASS_IN, 3600, &DNSKEY{257, 3, AlgorithmECDSA_P256_SHA256, key}}
+	if g, e := dnskey.RData.(*DNSKEY).KeyTag(), uint16(55648); g != e {
+		t.Fatal(g, e)
+	}
+
+	rrset := RRs{&RR{"www.example.net.", TYPE_A, CLASS_IN, 3600, &A{net.IP{192, 0, 2, 1}}}}
+	expiration, _ := ParseSigTime("20100909100439")
+	inception, _ := ParseSigTime("20100812100439")
+	sig := &RRSIG{TYPE_A, AlgorithmECDSA_P256_SHA256, 3, 3600, expiration, inception, 55648, "example.net.", signature}
+	Now = func() time.Time { return time.Unix(int64(inception)+3600, 0) }
+	if err := sig.Verify(dnskey, rrset); err != nil {
+		t.Fatal(err)
+	}
+
+	Now = func() time.Time { return time.Unix(int64(expiration)+1, 0) }
+	if err := sig.Verify(dnskey, rrset); err == nil {
+		t.Fatal("unexpected success")
+	}
+
+	Now = func() time.Time { return time.Unix(int64(inception), 0) }
+	rrset[0].RData = &A{net.IP{192, 0, 2, 2}}
+	if err := sig.Verify(dnskey, rrset); err == nil {
+		t.Fatal("unexpected success")
+	}
+
+	if _, err := (&DNSKEY{257, 3, AlgorithmECDSA_P384_SHA384, key}).ECDSAPublicKey(); err == nil {
+		t.Fatal("unexpected success")
+	}
+
+	key[0] ^= 1
+	if _, err := dnskey.RData.(*DNSKEY).ECDSAPublicKey(); err == nil {
+		t.Fatal("unexpected success")
+	}
+}
+
+func TestVerifyRSA(t *testing.T) {
+	defer func(f func() time.Time) { Now = f }(Now)
+
+	priv, err := rsa.GenerateKey(crand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	Now = func() time.Time { return time.Unix(0x4f800000, 0) }
+	rrset := RRs{&RR{"www.example.", TYPE_A, CLASS_IN, 60, &A{net.IP{192, 0, 2, 1}}}}
+	for _, alg := range []AlgorithmType{AlgorithmRSA_SHA1, AlgorithmRSA_SHA256, AlgorithmRSA_SHA512} {
+		key := &RR{"example.", TYPE_DNSKEY, CLASS_IN, 3600, rsaDNSKEY(&priv.PublicKey, alg)}
+		sig, err := SignRRSet(rrset, key, priv, 0x4f000000, 0x50000000)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err = sig.Verify(key, rrset); err != nil {
+			t.Fatal(alg, err)
+		}
+
+		sig.Signature[0] ^= 1
+		if err = sig.Verify(key, rrset); err == nil {
+			t.Fatal(alg, "unexpected success")
+		}
+
+		sig.Signature[0] ^= 1
+		sig.Name = "other.example."
+		if err = sig.Verify(key, rrset); err == nil {
+			t.Fatal(alg, "unexpected success")
+		}
+	}
+}
+
+func TestCanonicalRData(t *testing.T) {
+	lower := CanonicalRData(&MX{10, "mail.example.com."}, TYPE_MX)
+	if g, e := CanonicalRData(&MX{10, "MAIL.Example.COM."}, TYPE_MX), lower; !bytes.Equal(g, e) {
+		t.Fatalf("\n%x\n%x", g, e)
+	}
+
+	if g, e := lower, wireBytes(&MX{10, "mail.example.com."}); !bytes.Equal(g, e) {
+		t.Fatalf("\n%x\n%x", g, e)
+	}
+
+	generic := RDATA(wireBytes(&MX{10, "MAIL.Example.COM."}))
+	if g, e := CanonicalRData(&generic, TYPE_MX), lower; !bytes.Equal(g, e) {
+		t.Fatalf("\n%x\n%x", g, e)
+	}
+
+	// Names in RData of types not listed in RFC 4034/6.2 keep their case.
+	nsec := &NSEC{"Next.Example.", TypesEncode([]Type{TYPE_A})}
+	if g, e := CanonicalRData(nsec, TYPE_NSEC), wireBytes(nsec); !bytes.Equal(g, e) {
+		t.Fatalf("\n%x\n%x", g, e)
+	}
+
+	// Unsupported types are left intact.
+	generic = RDATA("ABC")
+	if g, e := CanonicalRData(&generic, 0xFF00-1), []byte("ABC"); !bytes.Equal(g, e) {
+		t.Fatalf("\n%x\n%x", g, e)
+	}
+}
+
+func TestHeader(t *testing.T) {
+	a := &RR{"a.example.", TYPE_A, CLASS_IN, 60, &A{net.IP{192, 0, 2, 1}}}
+	b := &RR{"b.example.", TYPE_A, CLASS_CH, 120, &A{net.IP{192, 0, 2, 2}}}
+	ha, hb := a.Header(), b.Header()
+	if g, e := ha, (Header{"a.example.", TYPE_A, CLASS_IN, 60}); g != e {
+		t.Fatalf("%+v != %+v", g, e)
+	}
+
+	if ha == hb {
+		t.Fatal("unexpected equal headers")
+	}
+
+	a.RData, b.RData = b.RData, a.RData
+	if a.Header() != ha || b.Header() != hb {
+		t.Fatal("header changed")
+	}
+
+	if g, e := a.String(), "a.example.\tIN\t60\tA 192.0.2.2"; g != e {
+		t.Fatalf("%q != %q", g, e)
+	}
+
+	a.SetHeader(hb)
+	if g, e := a.String(), "b.example.\tCH\t120\tA 192.0.2.2"; g != e {
+		t.Fatalf("%q != %q", g, e)
+	}
+}
+
+func TestByName(t *testing.T) {
+	rrs := RRs{
+		&RR{"www.Example.", TYPE_A, CLASS_IN, 60, &A{net.IP{192, 0, 2, 1}}},
+		&RR{"example.", TYPE_MX, CLASS_IN, 60, &MX{10, "mail.example."}},
+		&RR{"WWW.example.", TYPE_AAAA, CLASS_IN, 60, &AAAA{net.ParseIP("2001:db8::1")}},
+		&RR{"www.example.", TYPE_TXT, CLASS_CH, 60, &TXT{[]string{"x"}}},
+	}
+
+	for i, v := range []struct {
+		g, e RRs
+	}{
+		{rrs.ByName("www.example."), RRs{rrs[0], rrs[2], rrs[3]}},
+		{rrs.ByName("WWW.EXAMPLE"), RRs{rrs[0], rrs[2], rrs[3]}},
+		{rrs.ByName("example."), RRs{rrs[1]}},
+		{rrs.ByName("nx.example."), nil},
+		{rrs.ByType(TYPE_AAAA), RRs{rrs[2]}},
+		{rrs.ByType(TYPE_NS), nil},
+		{rrs.ByClass(CLASS_IN), rrs[:3]},
+		{rrs.ByClass(CLASS_CH), RRs{rrs[3]}},
+		{rrs.ByName("www.example.").ByType(TYPE_A).ByClass(CLASS_IN), RRs{rrs[0]}},
+	} {
+		if g, e := len(v.g), len(v.e); g != e {
+			t.Errorf("%d: len %d != %d", i, g, e)
+			continue
+		}
+
+		for j, r := range v.g {
+			if r != v.e[j] {
+				t.Errorf("%d.%d: %s != %s", i, j, r, v.e[j])
+			}
+		}
+	}
+}
+
+func TestRRsets(t *testing.T) {
+	rrs := RRs{
+		&RR{"a.example.", TYPE_A, CLASS_IN, 60, &A{net.IP{192, 0, 2, 1}}},
+		&RR{"b.example.", TYPE_A, CLASS_IN, 60, &A{net.IP{192, 0, 2, 2}}},
+		&RR{"A.example.", TYPE_A, CLASS_IN, 120, &A{net.IP{192, 0, 2, 3}}},
+		&RR{"a.example.", TYPE_A, CLASS_CH, 60, &A{net.IP{192, 0, 2, 4}}},
+		&RR{"a.example.", TYPE_RRSIG, CLASS_IN, 60, &RRSIG{Type: TYPE_A}},
+		&RR{"a.example.", TYPE_RRSIG, CLASS_IN, 60, &RRSIG{Type: TYPE_MX}},
+		&RR{"a.example.", TYPE_RRSIG, CLASS_IN, 60, &RRSIG{Type: TYPE_A}},
+	}
+	sets := rrs.RRsets()
+	for i, e := range [][]int{{0, 2}, {1}, {3}, {4, 6}, {5}} {
+		if i >= len(sets) {
+			t.Fatal(len(sets))
+		}
+
+		set := sets[i]
+		if len(set) != len(e) {
+			t.Fatalf("%d: len %d != %d", i, len(set), len(e))
+		}
+
+		for j, k := range e {
+			if set[j] != rrs[k] {
+				t.Fatalf("%d.%d: %s != %s", i, j, set[j], rrs[k])
+			}
+		}
+	}
+
+	if len(sets) != 5 {
+		t.Fatal(len(sets))
+	}
+
+	if RRs(nil).RRsets() != nil {
+		t.Fatal("unexpected RRsets")
+	}
+}
+
+func TestSign(t *testing.T) {
+	defer func(f func() time.Time) { Now = f }(Now)
+
+	priv, err := rsa.GenerateKey(crand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key := &RR{"example.", TYPE_DNSKEY, CLASS_IN, 3600, rsaDNSKEY(&priv.PublicKey, AlgorithmRSA_SHA256)}
+	zone := RRs{
+		&RR{"example.", TYPE_SOA, CLASS_IN, 3600, &SOA{"ns.example.", "hostmaster.example.", 1, 7200, 3600, 1209600, 300}},
+		&RR{"example.", TYPE_NS, CLASS_IN, 3600, &NS{"ns.example."}},
+		key,
+		&RR{"ns.example.", TYPE_A, CLASS_IN, 3600, &A{net.IP{192, 0, 2, 1}}},
+		&RR{"www.example.", TYPE_A, CLASS_IN, 60, &A{net.IP{192, 0, 2, 2}}},
+		&RR{"www.example.", TYPE_A, CLASS_IN, 60, &A{net.IP{192, 0, 2, 3}}},
+		&RR{"nx.example.", TYPE_NXDOMAIN, CLASS_IN, 60, &NXDOMAIN{}},
+		&RR{"old.example.", TYPE_RRSIG, CLASS_IN, 60, &RRSIG{Type: TYPE_A}},
+	}
+	signed, err := zone.Sign(key, priv, 0x4f000000, 0x50000000)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if g, e := len(signed), len(zone)+5; g != e {
+		t.Fatal(g, e)
+	}
+
+	for i, v := range zone {
+		if signed[i] != v {
+			t.Fatal(i)
+		}
+	}
+
+	Now = func() time.Time { return time.Unix(0x4f800000, 0) }
+	covered := map[Type]bool{}
+	for _, v := range signed[len(zone):] {
+		sig := v.RData.(*RRSIG)
+		covered[sig.Type] = true
+		if err := sig.Verify(key, zone.CoveredBy(v)); err != nil {
+			t.Fatal(v, err)
+		}
+	}
+
+	for _, typ := range []Type{TYPE_SOA, TYPE_NS, TYPE_DNSKEY, TYPE_A} {
+		if !covered[typ] {
+			t.Fatal(typ)
+		}
+	}
+
+	if covered[TYPE_NXDOMAIN] || covered[TYPE_RRSIG] {
+		t.Fatal(covered)
+	}
+
+	zone[4].TTL = 120
+	if _, err = zone.Sign(key, priv, 0x4f000000, 0x50000000); err == nil {
+		t.Fatal("unexpected success")
+	}
+}
+
+func testZone() RRs {
+	return RRs{
+		&RR{"example.", TYPE_SOA, CLASS_IN, 3600, &SOA{"ns.example.", "hostmaster.example.", 1, 7200, 3600, 1209600, 300}},
+		&RR{"example.", TYPE_NS, CLASS_IN, 3600, &NS{"ns.example."}},
+		&RR{"www.example.", TYPE_A, CLASS_IN, 60, &A{net.IP{192, 0, 2, 2}}},
+		&RR{"ns.example.", TYPE_A, CLASS_IN, 3600, &A{net.IP{192, 0, 2, 1}}},
+		&RR{"www.example.", TYPE_AAAA, CLASS_IN, 60, &AAAA{net.ParseIP("2001:db8::2")}},
+		&RR{"Sub.example.", TYPE_NS, CLASS_IN, 3600, &NS{"ns.sub.example."}},
+		&RR{"ns.sub.example.", TYPE_A, CLASS_IN, 3600, &A{net.IP{192, 0, 2, 3}}},
+		&RR{"a.example.", TYPE_MX, CLASS_IN, 3600, &MX{10, "www.example."}},
+		&RR{"nx.example.", TYPE_NXDOMAIN, CLASS_IN, 60, &NXDOMAIN{}},
+	}
+}
+
+func TestGenerateNSEC(t *testing.T) {
+	nsec := GenerateNSEC(testZone())
+	e := []struct {
+		name, next string
+		types      []Type
+	}{
+		{"example.", "a.example.", []Type{TYPE_NS, TYPE_SOA, TYPE_RRSIG, TYPE_NSEC}},
+		{"a.example.", "ns.example.", []Type{TYPE_MX, TYPE_RRSIG, TYPE_NSEC}},
+		{"ns.example.", "Sub.example.", []Type{TYPE_A, TYPE_RRSIG, TYPE_NSEC}},
+		{"Sub.example.", "www.example.", []Type{TYPE_NS, TYPE_RRSIG, TYPE_NSEC}},
+		{"www.example.", "example.", []Type{TYPE_A, TYPE_AAAA, TYPE_RRSIG, TYPE_NSEC}},
+	}
+	if g, e := len(nsec), len(e); g != e {
+		t.Fatalf("%d != %d\n%s", g, e, nsec)
+	}
+
+	for i, v := range nsec {
+		rd := v.RData.(*NSEC)
+		if g, e := v.Name, e[i].name; g != e {
+			t.Errorf("%d: %s != %s", i, g, e)
+		}
+
+		if g, e := rd.NextDomainName, e[i].next; g != e {
+			t.Errorf("%d: %s != %s", i, g, e)
+		}
+
+		if g, e := bitmapString(rd.TypeBitMaps), TypesString(e[i].types); g != e {
+			t.Errorf("%d: %s != %s", i, g, e)
+		}
+
+		if v.Type != TYPE_NSEC || v.Class != CLASS_IN || v.TTL != 300 {
+			t.Errorf("%d: %s", i, v)
+		}
+	}
+
+	// The next names form a closed loop.
+	seen := map[string]bool{}
+	name := nsec[0].Name
+	for range nsec {
+		seen[name] = true
+		name = nsec.ByName(name)[0].RData.(*NSEC).NextDomainName
+	}
+	if name != nsec[0].Name || len(seen) != len(nsec) {
+		t.Fatal(name, seen)
+	}
+}
+
+func TestCompareCanonicalNames(t *testing.T) {
+	// RFC 4034/6.1
+	names := []string{
+		"example.",
+		"a.example.",
+		"yljkjljk.a.example.",
+		"Z.a.example.",
+		"zABC.a.EXAMPLE.",
+		"z.example.",
+		`\001.z.example.`,
+		"*.z.example.",
+		`\200.z.example.`,
+	}
+	for i, a := range names {
+		for j, b := range names {
+			g := compareCanonicalNames(a, b)
+			switch {
+			case i < j && g >= 0, i == j && g != 0, i > j && g <= 0:
+				t.Errorf("%s %s: %d", a, b, g)
+			}
+		}
+	}
+
+	if g := compareCanonicalNames(`\065.example.`, "a.Example"); g != 0 {
+		t.Error(g)
+	}
+
+	zone := append(testZone(), &RR{`\200.example.`, TYPE_A, CLASS_IN, 300, &A{net.ParseIP("192.0.2.9")}})
+	nsec := GenerateNSEC(zone)
+	if g, e := nsec[len(nsec)-1].Name, `\200.example.`; g != e {
+		t.Fatalf("%s != %s\n%s", g, e, nsec)
+	}
+}
+
+func TestHashName(t *testing.T) {
+	// RFC 5155/Appendix A
+	params := &NSEC3PARAM{HashAlgorithmSHA1, 1, 12, []byte{0xaa, 0xbb, 0xcc, 0xdd}}
+	for _, v := range []struct{ name, hash string }{
+		{"example", "0p9mhaveqvm6t7vbl5lop2u3t2rp3tom"},
+		{"a.example", "35mthgpgcu1qg68fab165klnsnk3dpvl"},
+		{"A.EXAMPLE.", "35mthgpgcu1qg68fab165klnsnk3dpvl"},
+	} {
+		h, err := params.HashName(v.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if g, e := strings.ToLower(string(strutil.Base32ExtEncode(h))), v.hash; g != e {
+			t.Errorf("%s: %s != %s", v.name, g, e)
+		}
+	}
+
+	if _, err := (&NSEC3PARAM{}).HashName("example."); err == nil {
+		t.Fatal("unexpected success")
+	}
+}
+
+func TestGenerateNSEC3(t *testing.T) {
+	params := &NSEC3PARAM{HashAlgorithmSHA1, 0, 2, []byte{0xaa, 0xbb}}
+	zone := append(testZone(),
+		&RR{"x.y.example.", TYPE_TXT, CLASS_IN, 60, &TXT{[]string{"ent"}}},
+		&RR{"secure.example.", TYPE_NS, CLASS_IN, 3600, &NS{"ns.example."}},
+		&RR{"secure.example.", TYPE_DS, CLASS_IN, 3600, &DS{1, AlgorithmRSA_SHA1, HashAlgorithmSHA1, make([]byte, 20)}},
+	)
+	hash := func(name string) string {
+		h, err := params.HashName(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return string(h)
+	}
+
+	for _, optOut := range []bool{false, true} {
+		nsec3, err := GenerateNSEC3(zone, params, optOut)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		e := map[string][]Type{
+			"example.":        {TYPE_NS, TYPE_SOA, TYPE_RRSIG},
+			"a.example.":      {TYPE_MX, TYPE_RRSIG},
+			"ns.example.":     {TYPE_A, TYPE_RRSIG},
+			"www.example.":    {TYPE_A, TYPE_AAAA, TYPE_RRSIG},
+			"y.example.":      nil,
+			"x.y.example.":    {TYPE_TXT, TYPE_RRSIG},
+			"secure.example.": {TYPE_NS, TYPE_DS, TYPE_RRSIG},
+		}
+		if !optOut {
+			e["sub.example."] = []Type{TYPE_NS}
+		}
+		byHash := map[string]string{}
+		for name := range e {
+			byHash[hash(name)] = name
+		}
+
+		if g, e := len(nsec3), len(e); g != e {
+			t.Fatalf("%t: %d != %d\n%s", optOut, g, e, nsec3)
+		}
+
+		next := map[string]string{}
+		for _, v := range nsec3 {
+			rd := v.RData.(*NSEC3)
+			labels := nameLabels(v.Name)
+			h, err := strutil.Base32ExtDecode([]byte(strings.ToUpper(labels[0])))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			name, ok := byHash[string(h)]
+			if !ok || dns.CanonicalName(strings.Join(labels[1:], ".")) != "example." {
+				t.Fatalf("%t: unexpected %s", optOut, v)
+			}
+
+			if g, e := bitmapString(rd.TypeBitMaps), TypesString(e[name]); g != e {
+				t.Errorf("%t: %s: %q != %q", optOut, name, g, e)
+			}
+
+			if g, e := rd.Flags&1 != 0, optOut; g != e {
+				t.Errorf("%t: %s: opt-out %t", optOut, name, g)
+			}
+
+			if v.TTL != 300 || rd.Iterations != 2 {
+				t.Errorf("%t: %s", optOut, v)
+			}
+
+			next[string(h)] = string(rd.NextHashedOwnerName)
+		}
+
+		// The next hashes form a closed loop in increasing order, wrapping once.
+		h0 := hash("example.")
+		h, wraps := h0, 0
+		for range nsec3 {
+			n, ok := next[h]
+			if !ok {
+				t.Fatalf("%t: broken chain", optOut)
+			}
+
+			if n <= h {
+				wraps++
+			}
+			h = n
+		}
+		if h != h0 || wraps != 1 {
+			t.Fatalf("%t: %d wraps", optOut, wraps)
+		}
+	}
+
+	if _, err := GenerateNSEC3(zone[1:], params, false); err == nil {
+		t.Fatal("unexpected success")
+	}
+}
+
+func TestDecodeCompressedSigner(t *testing.T) {
+	sig := &RRSIG{TYPE_A, AlgorithmRSA_SHA1, 1, 3600, 0x50000000, 0x4f000000, 0x1234, "example.", []byte{1, 2, 3}}
+	b := dns.NewWirebuf()
+	dns.DomainName("example.").Encode(b)
+	p0 := len(b.Buf)
+	sig.Encode(b)
+	rdata := b.Buf[p0:]
+	if !bytes.Equal(rdata[18:27], []byte("\x07example\x00")) {
+		t.Fatalf("signer name compressed\n%s", hex.Dump(rdata))
+	}
+
+	var rd RRSIG
+	pos := p0
+	if err := rd.Decode(b.Buf, &pos, nil); err != nil || rd.Name != "example." {
+		t.Fatal(err, rd.Name)
+	}
+
+	// Replace the signer's name by a compression pointer to offset 0.
+	c := append(append(append([]byte{}, b.Buf[:p0+18]...), 0xC0, 0), sig.Signature...)
+	pos = p0
+	if err := rd.Decode(c, &pos, nil); err == nil {
+		t.Fatal("unexpected success")
+	}
+
+	// The same for NSEC and DNAME.
+	c = append(append([]byte{}, b.Buf[:p0]...), 0xC0, 0, 0, 1, 0x40)
+	pos = p0
+	if err := (&NSEC{}).Decode(c, &pos, nil); err == nil {
+		t.Fatal("unexpected success")
+	}
+
+	c = append(append([]byte{}, b.Buf[:p0]...), 0xC0, 0)
+	pos = p0
+	if err := (&DNAME{}).Decode(c, &pos, nil); err == nil {
+		t.Fatal("unexpected success")
+	}
+
+	// Other names may be compressed.
+	pos = p0
+	if err := (&CNAME{}).Decode(c, &pos, nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestClassify(t *testing.T) {
+	rrs := RRs{
+		&RR{"www.example.", TYPE_CNAME, CLASS_IN, 60, &CNAME{"web.Example."}},
+		&RR{"web.example.", TYPE_CNAME, CLASS_IN, 60, &CNAME{"host.example."}},
+		&RR{"host.example.", TYPE_A, CLASS_IN, 60, &A{net.IP{192, 0, 2, 1}}},
+		&RR{"host.example.", TYPE_AAAA, CLASS_IN, 60, &AAAA{net.ParseIP("2001:db8::1")}},
+		&RR{"example.", TYPE_NS, CLASS_IN, 3600, &NS{"ns.example."}},
+		&RR{"example.", TYPE_SOA, CLASS_IN, 3600, &SOA{"ns.example.", "hostmaster.example.", 1, 7200, 3600, 1209600, 300}},
+		&RR{"ns.example.", TYPE_A, CLASS_IN, 3600, &A{net.IP{192, 0, 2, 53}}},
+		&RR{"other.example.", TYPE_A, CLASS_IN, 3600, &A{net.IP{192, 0, 2, 99}}},
+	}
+	check := func(s string, g RRs, e ...int) {
+		if len(g) != len(e) {
+			t.Fatalf("%s: len %d != %d\n%s", s, len(g), len(e), g)
+		}
+
+		for i, j := range e {
+			if g[i] != rrs[j] {
+				t.Fatalf("%s: %d: %s != %s", s, i, g[i], rrs[j])
+			}
+		}
+	}
+
+	answer, authority, additional := rrs.Classify("WWW.example.", TYPE_A)
+	check("answer", answer, 0, 1, 2)
+	check("authority", authority, 4, 5)
+	check("additional", additional, 6)
+
+	answer, authority, additional = rrs.Classify("www.example.", TYPE_CNAME)
+	check("answer", answer, 0)
+	check("authority", authority, 4, 5)
+	check("additional", additional, 6)
+
+	answer, authority, additional = rrs.Classify("example.", TYPE_NS)
+	check("answer", answer, 4)
+	check("authority", authority, 5)
+	check("additional", additional, 6)
+
+	answer, _, _ = rrs.Classify("host.example.", 255)
+	check("answer", answer, 2, 3)
+
+	answer, _, _ = rrs.Classify("nx.example.", TYPE_A)
+	check("answer", answer)
+
+	// CNAME loop
+	rrs[1].RData = &CNAME{"www.example."}
+	answer, _, _ = rrs.Classify("www.example.", TYPE_A)
+	check("answer", answer, 0, 1)
+}
+
+func TestResolveCNAME(t *testing.T) {
+	rrs := RRs{
+		&RR{"a.example.", TYPE_CNAME, CLASS_IN, 60, &CNAME{"b.example."}},
+		&RR{"B.example.", TYPE_CNAME, CLASS_IN, 60, &CNAME{"c.example."}},
+		&RR{"c.example.", TYPE_CNAME, CLASS_IN, 60, &CNAME{"D.example."}},
+		&RR{"d.example.", TYPE_A, CLASS_IN, 60, &A{net.IP{192, 0, 2, 1}}},
+		&RR{"x.example.", TYPE_CNAME, CLASS_IN, 60, &CNAME{"y.example."}},
+		&RR{"y.example.", TYPE_CNAME, CLASS_IN, 60, &CNAME{"X.example."}},
+	}
+
+	chain, target, loop := rrs.ResolveCNAME("A.example.", TYPE_A)
+	if len(chain) != 3 || chain[0] != rrs[0] || chain[1] != rrs[1] || chain[2] != rrs[2] || target != "D.example." || loop {
+		t.Fatal(chain, target, loop)
+	}
+
+	if chain, target, loop = rrs.ResolveCNAME("c.example.", TYPE_CNAME); len(chain) != 0 || target != "c.example." || loop {
+		t.Fatal(chain, target, loop)
+	}
+
+	if chain, target, loop = rrs.ResolveCNAME("d.example.", TYPE_MX); len(chain) != 0 || target != "d.example." || loop {
+		t.Fatal(chain, target, loop)
+	}
+
+	if chain, target, loop = rrs.ResolveCNAME("x.example.", TYPE_A); len(chain) != 2 || target != "X.example." || !loop {
+		t.Fatal(chain, target, loop)
+	}
+}
+
+func TestTSIGComputeMAC(t *testing.T) {
+	msg := []byte{
+		0x12, 0x34, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		7, 'e', 'x', 'a', 'm', 'p', 'l', 'e', 3, 'c', 'o', 'm', 0,
+		0x00, 0x01, 0x00, 0x01,
+	}
+	secret := []byte("0123456789abcdef")
+	ts := time.Date(1997, 1, 21, 0, 0, 0, 0, time.UTC)
+	for _, test := range []struct {
+		alg, mac string
+	}{
+		{HMAC_MD5, "23e022408429b3236dc0732c089ff421"},
+		{"HMAC-SHA256.", "3291530aa8310c6913249b6e9e8c882d74c986d8b0e854fb9d583f06d4de953d"},
+	} {
+		rr := &RR{"Key.Example.", TYPE_TSIG, 255, 0, &TSIG{test.alg, ts, 300 * time.Second, nil, 0x1234, 0, nil}}
+		mac, err := rr.ComputeTSIGMAC(msg, secret)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if g, e := hex.EncodeToString(mac), test.mac; g != e {
+			t.Errorf("%s\ngot: %s\nexp: %s", test.alg, g, e)
+		}
+	}
+
+	if _, err := (&TSIG{AlgorithmName: "gss-tsig."}).ComputeMAC(msg, secret); err == nil {
+		t.Error("unexpected success")
+	}
+}
+
+func TestTKEY(t *testing.T) {
+	tkey := &TKEY{
+		"gss-tsig.",
+		time.Unix(1000, 0),
+		time.Unix(2000, 0),
+		TKEYModeGSSAPINegotation,
+		0,
+		[]byte("key"),
+		[]byte("other"),
+	}
+	w := dns.NewWirebuf()
+	tkey.Encode(w)
+
+	tkey2 := &TKEY{}
+	p := 0
+	if err := tkey2.Decode(w.Buf, &p, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if g, e := p, len(w.Buf); g != e {
+		t.Fatalf("%d != %d", g, e)
+	}
+
+	if g, e := tkey2.String(), tkey.String(); g != e {
+		t.Errorf("\n%v\n!=\n%v", g, e)
+	}
+
+	// Key and other data lengths must be bound by the buffer
+	for _, n := range []int{len(w.Buf) - 1, len(w.Buf) - len("other") - 3} {
+		p = 0
+		if err := (&TKEY{}).Decode(w.Buf[:n], &p, nil); err == nil {
+			t.Errorf("%d: unexpected success", n)
+		}
+	}
+}
+
+func TestKEYFlags(t *testing.T) {
+	b := []byte{0x42, 0x01, 3, byte(AlgorithmRSA_SHA1), 1, 2, 3}
+	rd := &KEY{}
+	p := 0
+	if err := rd.Decode(b, &p, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if g, e := p, len(b); g != e {
+		t.Fatalf("%d != %d", g, e)
+	}
+
+	if rd.Flags&KEY_NOAUTH != 0 || rd.Flags&KEY_NOCONF == 0 || rd.NoKey() {
+		t.Errorf("%#04x: A/C bits", rd.Flags)
+	}
+
+	if g, e := rd.NameType(), uint16(KEY_NAMTYP_ENTITY); g != e {
+		t.Errorf("%#04x != %#04x", g, e)
+	}
+
+	if g, e := rd.Signatory(), uint16(1); g != e {
+		t.Errorf("%d != %d", g, e)
+	}
+
+	if !(&KEY{Flags: 0xc100}).NoKey() {
+		t.Error("NoKey")
+	}
+
+	w := dns.NewWirebuf()
+	rd.Encode(w)
+	if g, e := w.Buf, b; !bytes.Equal(g, e) {
+		t.Errorf("\n%s\n!=\n%s", hex.Dump(g), hex.Dump(e))
+	}
+
+	// RFC 2535/3.1.2: A/C = 01 means the key is usable for authentication
+	// only, but there must be key data.
+	p = 0
+	if err := rd.Decode(b[:4], &p, nil); err == nil {
+		t.Error("unexpected success")
+	}
+
+	// A/C = 10, usable for confidentiality only.
+	b[0] = 0x82
+	p = 0
+	if err := rd.Decode(b, &p, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if rd.Flags&KEY_NOAUTH == 0 || rd.Flags&KEY_NOCONF != 0 || rd.NoKey() {
+		t.Errorf("%#04x: A/C bits", rd.Flags)
+	}
+
+	// A/C = 11, no key, RFC 2535/3.1.5: the key field is null.
+	b = []byte{0xc1, 0x00, 3, byte(AlgorithmRSA_SHA1)}
+	p = 0
+	if err := rd.Decode(b, &p, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if g, e := p, len(b); g != e {
+		t.Fatalf("%d != %d", g, e)
+	}
+
+	if !rd.NoKey() || len(rd.Key) != 0 || rd.NameType() != KEY_NAMTYP_ZONE {
+		t.Errorf("%#04x %x", rd.Flags, rd.Key)
+	}
+
+	w = dns.NewWirebuf()
+	rd.Encode(w)
+	if g, e := w.Buf, b; !bytes.Equal(g, e) {
+		t.Errorf("\n%s\n!=\n%s", hex.Dump(g), hex.Dump(e))
+	}
+
+	// A DNSKEY has no such flags, the key is always required.
+	p = 0
+	if err := (&DNSKEY{}).Decode(b, &p, nil); err == nil {
+		t.Error("unexpected success")
+	}
+}
+
+func TestSIG0(t *testing.T) {
+	defer func(f func() time.Time) { Now = f }(Now)
+
+	priv, err := rsa.GenerateKey(crand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	Now = func() time.Time { return time.Unix(0x4f800000, 0) }
+	key := (*KEY)(rsaDNSKEY(&priv.PublicKey, AlgorithmRSA_SHA256))
+	key.Flags = KEY_NAMTYP_ENTITY
+	msg := []byte{0x12, 0x34, 0x28, 0x00, 0, 1, 0, 0, 0, 0, 0, 0, 7, 'e', 'x', 'a', 'm', 'p', 'l', 'e', 0, 0, 6, 0, 1}
+	sig := &SIG{
+		Algorithm:  key.Algorithm,
+		Expiration: 0x50000000,
+		Inception:  0x4f000000,
+		KeyTag:     (*DNSKEY)(key).KeyTag(),
+		Name:       "Host.Example.",
+	}
+	h := crypto.SHA256.New()
+	h.Write(sig.SignedData(msg))
+	if sig.Signature, err = rsa.SignPKCS1v15(crand.Reader, priv, crypto.SHA256, h.Sum(nil)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = sig.Verify(key, msg); err != nil {
+		t.Fatal(err)
+	}
+
+	msg[1] ^= 1
+	if err = sig.Verify(key, msg); err == nil {
+		t.Fatal("unexpected success")
+	}
+
+	// RFC 2535/3.1.2: Keys not usable for authentication.
+	msg[1] ^= 1
+	for _, flags := range []uint16{KEY_NOAUTH, KEY_NOKEY} {
+		k := *key
+		k.Flags |= flags
+		if err = sig.Verify(&k, msg); err == nil {
+			t.Fatalf("%#04x: unexpected success", k.Flags)
+		}
+	}
+
+	k := *key
+	k.Flags |= KEY_NOCONF
+	nc := *sig
+	nc.KeyTag = (*DNSKEY)(&k).KeyTag()
+	h = crypto.SHA256.New()
+	h.Write(nc.SignedData(msg))
+	if nc.Signature, err = rsa.SignPKCS1v15(crand.Reader, priv, crypto.SHA256, h.Sum(nil)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = nc.Verify(&k, msg); err != nil {
+		t.Fatal(err)
+	}
+
+	Now = func() time.Time { return time.Unix(0x50000001, 0) }
+	if err = sig.Verify(key, msg); err == nil {
+		t.Fatal("unexpected success")
+	}
+
+	w := dns.NewWirebuf()
+	sig.Encode(w)
+	sig2 := &SIG{}
+	p := 0
+	if err = sig2.Decode(w.Buf, &p, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if g, e := sig2.String(), sig.String(); g != e {
+		t.Errorf("\n%s\n!=\n%s", g, e)
+	}
+}
+
+func TestSVCB(t *testing.T) {
+	tab := []struct {
+		typ  Type
+		rd   dns.Wirer
+		s    string
+		wire []byte
+	}{
+		// RFC 9460, D.1 AliasMode
+		{
+			TYPE_SVCB,
+			&SVCB{0, "foo.example.com.", nil},
+			"0 foo.example.com.",
+			[]byte{
+				0x00, 0x00,
+				0x03, 'f', 'o', 'o', 0x07, 'e', 'x', 'a', 'm', 'p', 'l', 'e', 0x03, 'c', 'o', 'm', 0x00,
+			},
+		},
+		// ServiceMode, params out of order are encoded in ascending key order.
+		{
+			TYPE_HTTPS,
+			&HTTPS{SVCB{1, ".", []SvcParam{
+				{SvcIPv4Hint, []byte{1, 2, 3, 4}},
+				{SvcALPN, []byte("\x02h2\x02h3")},
+			}}},
+			`1 . ipv4hint=1.2.3.4 alpn="h2,h3"`,
+			[]byte{
+				0x00, 0x01,
+				0x00,
+				0x00, 0x01, 0x00, 0x06, 0x02, 'h', '2', 0x02, 'h', '3',
+				0x00, 0x04, 0x00, 0x04, 0x01, 0x02, 0x03, 0x04,
+			},
+		},
+		// RFC 9460, D.2 ServiceMode with an unknown key
+		{
+			TYPE_SVCB,
+			&SVCB{1, "foo.example.com.", []SvcParam{{667, []byte("hello")}, {SvcPort, []byte{0, 53}}}},
+			`1 foo.example.com. key667="hello" port=53`,
+			[]byte{
+				0x00, 0x01,
+				0x03, 'f', 'o', 'o', 0x07, 'e', 'x', 'a', 'm', 'p', 'l', 'e', 0x03, 'c', 'o', 'm', 0x00,
+				0x00, 0x03, 0x00, 0x02, 0x00, 0x35,
+				0x02, 0x9b, 0x00, 0x05, 'h', 'e', 'l', 'l', 'o',
+			},
+		},
+	}
+
+	for i, test := range tab {
+		if g, e := test.rd.(fmt.Stringer).String(), test.s; g != e {
+			t.Errorf("%d: %q != %q", i, g, e)
+		}
+
+		w := dns.NewWirebuf()
+		test.rd.Encode(w)
+		if g, e := w.Buf, test.wire; !bytes.Equal(g, e) {
+			t.Errorf("%d:\n%s\n!=\n%s", i, hex.Dump(g), hex.Dump(e))
+			continue
+		}
+
+		rd := newRData(test.typ)
+		p := 0
+		if err := rd.Decode(w.Buf, &p, nil); err != nil {
+			t.Errorf("%d: %s", i, err)
+			continue
+		}
+
+		if a, b := (&RR{"x.", test.typ, CLASS_IN, 0, test.rd}), (&RR{"x.", test.typ, CLASS_IN, 0, rd}); !a.Equal(b) {
+			t.Errorf("%d: %s != %s", i, a, b)
+		}
+	}
+
+	// Keys out of order and values overflowing the RDATA are rejected.
+	for i, b := range [][]byte{
+		{0, 1, 0, 0, 3, 0, 2, 0, 53, 0, 1, 0, 1, 'x'},
+		{0, 1, 0, 0, 3, 0, 3, 0, 53},
+	} {
+		p := 0
+		if err := (&SVCB{}).Decode(b, &p, nil); err == nil {
+			t.Errorf("%d: unexpected success", i)
+		}
+	}
+}
+
+func TestSVCBParams(t *testing.T) {
+	rd := &HTTPS{SVCB{Priority: 1, TargetName: "."}}
+	rd.SetALPN([]string{"h2", "foo,bar", `b\z`})
+	rd.SetParam(SvcPort, []byte{0x01, 0xbb})
+	rd.SetParam(SvcIPv4Hint, []byte{192, 0, 2, 1, 192, 0, 2, 2})
+	rd.SetParam(SvcIPv6Hint, net.ParseIP("2001:db8::1").To16())
+	if g, e := rd.String(), `1 . alpn="h2,foo\\,bar,b\\\\z" port=443 ipv4hint=192.0.2.1,192.0.2.2 ipv6hint=2001:db8::1`; g != e {
+		t.Errorf("\ngot: %s\nexp: %s", g, e)
+	}
+
+	w := dns.NewWirebuf()
+	rd.Encode(w)
+	rd2 := &HTTPS{}
+	p := 0
+	if err := rd2.Decode(w.Buf, &p, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if g, e := strings.Join(rd2.ALPN(), "|"), `h2|foo,bar|b\z`; g != e {
+		t.Errorf("%q != %q", g, e)
+	}
+
+	if port, ok := rd2.Port(); !ok || port != 443 {
+		t.Errorf("%d %t", port, ok)
+	}
+
+	if g, e := fmt.Sprint(rd2.IPv4Hint()), "[192.0.2.1 192.0.2.2]"; g != e {
+		t.Errorf("%s != %s", g, e)
+	}
+
+	if g, e := fmt.Sprint(rd2.IPv6Hint()), "[2001:db8::1]"; g != e {
+		t.Errorf("%s != %s", g, e)
+	}
+
+	rd2.SetALPN([]string{"h3"})
+	if g, e := len(rd2.Params), 4; g != e {
+		t.Errorf("%d != %d", g, e)
+	}
+
+	if g, e := strings.Join(rd2.ALPN(), "|"), "h3"; g != e {
+		t.Errorf("%q != %q", g, e)
+	}
+
+	rd2.SetParam(SvcPort, []byte{1})
+	if _, ok := rd2.Port(); ok {
+		t.Error("malformed port accepted")
+	}
+}
+
+func TestAPL(t *testing.T) {
+	// RFC 3123/5 (the last item is an extra IPv6 item)
+	rd := &APL{[]APLItem{
+		{1, 21, false, []byte{192, 168, 32, 0}},
+		{1, 28, true, []byte{192, 168, 38, 0}},
+		{2, 48, false, net.ParseIP("2001:db8:1::").To16()},
+	}}
+	if g, e := rd.String(), "1:192.168.32.0/21 !1:192.168.38.0/28 2:2001:db8:1::/48"; g != e {
+		t.Errorf("%q != %q", g, e)
+	}
+
+	w := dns.NewWirebuf()
+	rd.Encode(w)
+	if g, e := w.Buf, []byte{
+		0x00, 0x01, 21, 0x03, 192, 168, 32,
+		0x00, 0x01, 28, 0x83, 192, 168, 38,
+		0x00, 0x02, 48, 0x06, 0x20, 0x01, 0x0d, 0xb8, 0x00, 0x01,
+	}; !bytes.Equal(g, e) {
+		t.Fatalf("\n%s\n!=\n%s", hex.Dump(g), hex.Dump(e))
+	}
+
+	rd2 := &APL{}
+	p := 0
+	if err := rd2.Decode(w.Buf, &p, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if g, e := rd2.String(), rd.String(); g != e {
+		t.Errorf("%q != %q", g, e)
+	}
+
+	if a, b := (&RR{"x.", TYPE_APL, CLASS_IN, 0, rd}), (&RR{"x.", TYPE_APL, CLASS_IN, 0, rd2}); !a.Equal(b) {
+		t.Errorf("%s != %s", a, b)
+	}
+
+	rd2.Items[1].Negation = false
+	if a, b := (&RR{"x.", TYPE_APL, CLASS_IN, 0, rd}), (&RR{"x.", TYPE_APL, CLASS_IN, 0, rd2}); a.Equal(b) {
+		t.Errorf("%s == %s", a, b)
+	}
+
+	p = 0
+	if err := rd2.Decode(w.Buf[:len(w.Buf)-1], &p, nil); err == nil {
+		t.Error("unexpected success")
+	}
+
+	// RFC 3123/4: AFD length and prefix limits per family.
+	for i, b := range [][]byte{
+		{0x00, 0x01, 24, 0x05, 192, 0, 2, 1, 1},
+		{0x00, 0x01, 33, 0x01, 192},
+		{0x00, 0x02, 48, 0x11, 0x20, 0x01, 0x0d, 0xb8, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1},
+		{0x00, 0x02, 129, 0x01, 0x20},
+	} {
+		p = 0
+		if err := rd2.Decode(b, &p, nil); err == nil {
+			t.Errorf("%d: unexpected success", i)
+		}
+	}
+
+	// IPv4 addresses in the 16 octet form are encoded as 4 octets.
+	rd = &APL{[]APLItem{{1, 24, false, net.ParseIP("192.0.2.0")}}}
+	if g, e := rd.String(), "1:192.0.2.0/24"; g != e {
+		t.Errorf("%q != %q", g, e)
+	}
+
+	w = dns.NewWirebuf()
+	rd.Encode(w)
+	if g, e := w.Buf, []byte{0x00, 0x01, 24, 0x03, 192, 0, 2}; !bytes.Equal(g, e) {
+		t.Fatalf("\n%s\n!=\n%s", hex.Dump(g), hex.Dump(e))
+	}
+}
+
+func TestOPENPGPKEY(t *testing.T) {
+	key := make([]byte, 4000)
+	for i := range key {
+		key[i] = byte(i*7 + i>>8)
+	}
+	r := &RR{"c93f1e400f26708f98cb19d936620da35eec8f72e57f9eec01c1afd6._openpgpkey.example.com.", TYPE_OPENPGPKEY, CLASS_IN, 3600, &OPENPGPKEY{key}}
+	w := dns.NewWirebuf()
+	r.Encode(w)
+	r2 := &RR{}
+	p := 0
+	if err := r2.Decode(w.Buf, &p, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if g, e := p, len(w.Buf); g != e {
+		t.Fatalf("%d != %d", g, e)
+	}
+
+	if g, e := r2.RData.(*OPENPGPKEY).PublicKey, key; !bytes.Equal(g, e) {
+		t.Fatalf("len %d != len %d", len(g), len(e))
+	}
+
+	if !r.Equal(r2) {
+		t.Error("not equal")
+	}
+
+	if g, e := r2.RData.(*OPENPGPKEY).String(), string(strutil.Base64Encode(key)); g != e {
+		t.Errorf("%q != %q", g, e)
+	}
+}
+
+func TestSMIMEA(t *testing.T) {
+	cert := []byte{0xd2, 0xab, 0xde, 0x24, 0x0d, 0x7c, 0xd3, 0xee}
+	smimea := &SMIMEA{TLSAUsageMatchCert, TLSASelectorSubjectPKInfo, TLSAMatchingTypeSHA256, cert}
+	tlsa := &TLSA{TLSAUsageMatchCert, TLSASelectorSubjectPKInfo, TLSAMatchingTypeSHA256, cert}
+	w, w2 := dns.NewWirebuf(), dns.NewWirebuf()
+	smimea.Encode(w)
+	tlsa.Encode(w2)
+	if g, e := w.Buf, w2.Buf; !bytes.Equal(g, e) {
+		t.Fatalf("\n%s\n!=\n%s", hex.Dump(g), hex.Dump(e))
+	}
+
+	if g, e := smimea.String(), "3 1 1 d2abde240d7cd3ee"; g != e {
+		t.Errorf("%q != %q", g, e)
+	}
+
+	for _, n := range []int{len(w.Buf), 4} {
+		rd := &SMIMEA{}
+		p := 0
+		if err := rd.Decode(w.Buf[:n], &p, nil); err != nil {
+			t.Fatal(err)
+		}
+
+		if g, e := p, n; g != e {
+			t.Fatalf("%d != %d", g, e)
+		}
+
+		if g, e := rd.Certificate, cert[:n-3]; !bytes.Equal(g, e) {
+			t.Errorf("%x != %x", g, e)
+		}
+	}
+}
+
+func TestCSYNC(t *testing.T) {
+	// RFC 7477/2.2
+	r := &RR{"example.com.", TYPE_CSYNC, CLASS_IN, 0,
+		&CSYNC{66, CSYNC_IMMEDIATE | CSYNC_SOAMINIMUM, TypesEncode([]Type{TYPE_NS, TYPE_A})}}
+	if g, e := r.RData.(*CSYNC).String(), "66 3 A NS"; g != e {
+		t.Errorf("%q != %q", g, e)
+	}
+
+	w := dns.NewWirebuf()
+	r.Encode(w)
+	r2 := &RR{}
+	p := 0
+	if err := r2.Decode(w.Buf, &p, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if !r.Equal(r2) {
+		t.Fatalf("%s != %s", r, r2)
+	}
+
+	types, err := TypesDecode(r2.RData.(*CSYNC).TypeBitMaps)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if g, e := TypesString(types), "A NS"; g != e {
+		t.Errorf("%q != %q", g, e)
+	}
+}
+
+func TestZONEMD(t *testing.T) {
+	// RFC 8976, A.1
+	digest, _ := hex.DecodeString("c68090d90a7aed716bc459f9340e3d7c1370d4d24b7e2fc3a1ddc0b9a87153b9a9713b3c9ae5cc27777f98b8e730044c")
+	for _, rd := range []*ZONEMD{
+		{2018031900, ZONEMDSchemeSimple, ZONEMDHashSHA384, digest},
+		{2018031900, ZONEMDSchemeSimple, 240, digest[:20]},
+	} {
+		w := dns.NewWirebuf()
+		rd.Encode(w)
+		rd2 := &ZONEMD{}
+		p := 0
+		if err := rd2.Decode(w.Buf, &p, nil); err != nil {
+			t.Fatal(err)
+		}
+
+		if g, e := p, len(w.Buf); g != e {
+			t.Fatalf("%d != %d", g, e)
+		}
+
+		if g, e := rd2.String(), fmt.Sprintf("2018031900 1 %d %x", rd.HashAlgorithm, rd.Digest); g != e {
+			t.Errorf("%q != %q", g, e)
+		}
+
+		if a, b := (&RR{"example.", TYPE_ZONEMD, CLASS_IN, 86400, rd}), (&RR{"example.", TYPE_ZONEMD, CLASS_IN, 86400, rd2}); !a.Equal(b) {
+			t.Errorf("%s != %s", a, b)
+		}
+	}
+
+	// A SHA-384 digest must be 48 bytes long.
+	w := dns.NewWirebuf()
+	(&ZONEMD{1, ZONEMDSchemeSimple, ZONEMDHashSHA384, digest[:47]}).Encode(w)
+	p := 0
+	if err := (&ZONEMD{}).Decode(w.Buf, &p, nil); err == nil {
+		t.Error("unexpected success")
+	}
+}
+
+func TestZoneDigest(t *testing.T) {
+	// RFC 8976, A.1
+	digest := "c68090d90a7aed716bc459f9340e3d7c1370d4d24b7e2fc3a1ddc0b9a87153b9a9713b3c9ae5cc27777f98b8e730044c"
+	b, _ := hex.DecodeString(digest)
+	zone := RRs{
+		&RR{"ns2.example.", TYPE_AAAA, CLASS_IN, 3600, &AAAA{net.ParseIP("2001:db8::63")}},
+		&RR{"example.", TYPE_ZONEMD, CLASS_IN, 86400, &ZONEMD{2018031900, ZONEMDSchemeSimple, ZONEMDHashSHA384, b}},
+		&RR{"Example.", TYPE_NS, CLASS_IN, 86400, &NS{"ns2.example."}},
+		&RR{"example.", TYPE_SOA, CLASS_IN, 86400, &SOA{"ns1.example.", "admin.example.", 2018031900, 1800, 900, 604800, 86400}},
+		&RR{"example.", TYPE_NS, CLASS_IN, 86400, &NS{"NS1.example."}},
+		&RR{"ns1.example.", TYPE_A, CLASS_IN, 3600, &A{net.IP{203, 0, 113, 63}}},
+		&RR{"ns1.example.", TYPE_A, CLASS_IN, 3600, &A{net.IP{203, 0, 113, 63}}},
+		&RR{"other.", TYPE_A, CLASS_IN, 3600, &A{net.IP{203, 0, 113, 64}}},
+	}
+	g, err := zone.ZoneDigest(ZONEMDSchemeSimple, ZONEMDHashSHA384)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if e := digest; hex.EncodeToString(g) != e {
+		t.Errorf("\ngot: %x\nexp: %s", g, e)
+	}
+
+	if _, err := zone.ZoneDigest(ZONEMDSchemeSimple, 3); err == nil {
+		t.Error("unexpected success")
+	}
+
+	if _, err := zone[:3].ZoneDigest(ZONEMDSchemeSimple, ZONEMDHashSHA512); err == nil {
+		t.Error("unexpected success")
+	}
+
+	// Owner names are in the canonical order (RFC 4034/6.1).
+	a := &RR{"a.example.", TYPE_A, CLASS_IN, 300, &A{net.ParseIP("192.0.2.1")}}
+	c := &RR{`\200.example.`, TYPE_A, CLASS_IN, 300, &A{net.ParseIP("192.0.2.1")}}
+	if compareCanonical(a, c) >= 0 || compareCanonical(c, a) <= 0 {
+		t.Errorf("%s >= %s", a.Name, c.Name)
+	}
+}
+
+func TestHIP(t *testing.T) {
+	rd := &HIP{IPSECKEYAlgorithmRSA,
+		[]byte{0x20, 0x01, 0x00, 0x10},
+		[]byte{1, 2, 3, 4, 5},
+		[]string{"rvs1.example.com.", "rvs2.example.com."},
+	}
+	if g, e := rd.String(), "2 20010010 AQIDBAU= rvs1.example.com. rvs2.example.com."; g != e {
+		t.Errorf("%q != %q", g, e)
+	}
+
+	w := dns.NewWirebuf()
+	rd.Encode(w)
+	rd2 := &HIP{}
+	p := 0
+	if err := rd2.Decode(w.Buf, &p, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	a, b := &RR{"x.", TYPE_HIP, CLASS_IN, 0, rd}, &RR{"x.", TYPE_HIP, CLASS_IN, 0, rd2}
+	if !a.Equal(b) {
+		t.Fatalf("%s != %s", a, b)
+	}
+
+	rd2.PKAlgorithm = IPSECKEYAlgorithmDSA
+	if a.Equal(b) {
+		t.Errorf("%s == %s", a, b)
+	}
+
+	// HIT and public key lengths overflowing the RDATA
+	for _, n := range []int{7, 12} {
+		p = 0
+		if err := rd2.Decode(w.Buf[:n], &p, nil); err == nil {
+			t.Errorf("%d: unexpected success", n)
+		}
+	}
+
+	// Compressed rendezvous server names
+	buf := append(append([]byte{}, w.Buf[:13]...), 4, 'r', 'v', 's', '3', 0xc0, 18)
+	p = 0
+	if err := rd2.Decode(buf, &p, nil); err == nil {
+		t.Error("unexpected success")
+	}
+}
+
+func TestMailNames(t *testing.T) {
+	tab := []struct {
+		typ  Type
+		name func(dns.Wirer) string
+	}{
+		{TYPE_MB, func(rd dns.Wirer) string { return rd.(*MB).MADNAME }},
+		{TYPE_MD, func(rd dns.Wirer) string { return rd.(*MD).MADNAME }},
+		{TYPE_MF, func(rd dns.Wirer) string { return rd.(*MF).MADNAME }},
+		{TYPE_MG, func(rd dns.Wirer) string { return rd.(*MG).MGNAME }},
+		{TYPE_MR, func(rd dns.Wirer) string { return rd.(*MR).NEWNAME }},
+	}
+	for _, test := range tab {
+		// example.com. followed by a RR with a compressed owner name and
+		// a compressed RDATA domain name.
+		b := []byte{
+			7, 'e', 'x', 'a', 'm', 'p', 'l', 'e', 3, 'c', 'o', 'm', 0,
+			0xc0, 0,
+			byte(test.typ >> 8), byte(test.typ), 0, 1, 0, 0, 0, 60, 0, 7,
+			4, 'm', 'a', 'i', 'l', 0xc0, 0,
+		}
+		r := &RR{}
+		p := 13
+		if err := r.Decode(b, &p, nil); err != nil {
+			t.Fatal(test.typ, err)
+		}
+
+		if g, e := p, len(b); g != e {
+			t.Fatal(test.typ, g, e)
+		}
+
+		if g, e := test.name(r.RData), "mail.example.com."; g != e {
+			t.Errorf("%s: %q != %q", test.typ, g, e)
+		}
+
+		if g, e := r.String(), fmt.Sprintf("example.com.\tIN\t60\t%s mail.example.com.", test.typ); g != e {
+			t.Errorf("%q != %q", g, e)
+		}
+	}
+}
+
+func TestRT(t *testing.T) {
+	r := &RR{"sh.prime.com.", TYPE_RT, CLASS_IN, 3600, &RT{2, "Relay.Prime.COM."}}
+	w := dns.NewWirebuf()
+	r.Encode(w)
+	r2 := &RR{}
+	p := 0
+	if err := r2.Decode(w.Buf, &p, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if g, e := r2.String(), "sh.prime.com.\tIN\t3600\tRT 2 Relay.Prime.COM."; g != e {
+		t.Errorf("%q != %q", g, e)
+	}
+
+	r2.RData.(*RT).Hostname = "relay.prime.com."
+	if !r.Equal(r2) {
+		t.Errorf("%s != %s", r, r2)
+	}
+
+	r2.RData.(*RT).Preference = 10
+	if r.Equal(r2) {
+		t.Errorf("%s == %s", r, r2)
+	}
+}
+
+func TestX25ISDNPX(t *testing.T) {
+	// RFC 1183/3.1, 3.2 and RFC 2163/4
+	for _, test := range []struct {
+		r *RR
+		s string
+	}{
+		{&RR{"relay.prime.com.", TYPE_X25, CLASS_IN, 0, &X25{"311061700956"}}, `"311061700956"`},
+		{&RR{"isi.edu.", TYPE_ISDN, CLASS_IN, 0, &ISDN{"150862028003217", "004"}}, `"150862028003217" "004"`},
+		{&RR{"sh.prime.com.", TYPE_ISDN, CLASS_IN, 0, &ISDN{"150862028003217", ""}}, `"150862028003217"`},
+		{&RR{"*.ab.", TYPE_PX, CLASS_IN, 0, &PX{50, "ab.", "O-ab.PRMD-net2.ADMDb.C-ab."}}, "50 ab. O-ab.PRMD-net2.ADMDb.C-ab."},
+	} {
+		if g, e := test.r.RData.(fmt.Stringer).String(), test.s; g != e {
+			t.Errorf("%q != %q", g, e)
+		}
+
+		w := dns.NewWirebuf()
+		test.r.Encode(w)
+		r := &RR{}
+		p := 0
+		if err := r.Decode(w.Buf, &p, nil); err != nil {
+			t.Fatal(test.r.Type, err)
+		}
+
+		if g, e := p, len(w.Buf); g != e {
+			t.Fatal(test.r.Type, g, e)
+		}
+
+		if !r.Equal(test.r) {
+			t.Errorf("%s != %s", r, test.r)
+		}
+	}
+
+	// The ISDN subaddress is not encoded if absent.
+	w := dns.NewWirebuf()
+	(&ISDN{"150862028003217", ""}).Encode(w)
+	if g, e := len(w.Buf), 16; g != e {
+		t.Errorf("%d != %d", g, e)
+	}
+}
+
+func TestCharStrings(t *testing.T) {
+	v := strings.Repeat("a", 255) + strings.Repeat("b", 255) + `c"d`
+	c := NewCharStrings(v)
+	if g, e := len(c), 3; g != e {
+		t.Fatal(g, e)
+	}
+
+	r := &RR{"example.", TYPE_TXT, CLASS_IN, 0, &TXT{c}}
+	w := dns.NewWirebuf()
+	r.Encode(w)
+	r2 := &RR{}
+	p := 0
+	if err := r2.Decode(w.Buf, &p, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	s := CharStrings(r2.RData.(*TXT).S)
+	if g, e := len(s), len(c); g != e {
+		t.Fatal(g, e)
+	}
+
+	for i, e := range c {
+		if g := s[i]; g != e {
+			t.Errorf("%d: %q != %q", i, g, e)
+		}
+	}
+
+	if g, e := s.Join(), v; g != e {
+		t.Errorf("%q != %q", g, e)
+	}
+
+	if g, e := s[1:].String(), `"`+strings.Repeat("b", 255)+`" "c\"d"`; g != e {
+		t.Errorf("%q != %q", g, e)
+	}
+
+	if g, e := len(NewCharStrings("")), 1; g != e {
+		t.Errorf("%d != %d", g, e)
+	}
+}
+
+func TestDecodeRRs(t *testing.T) {
+	rrs := RRs{
+		&RR{"example.", TYPE_A, CLASS_IN, 60, &A{net.ParseIP("192.0.2.1")}},
+		&RR{"example.", TYPE_A, CLASS_IN, 60, &A{net.ParseIP("192.0.2.2")}},
+		&RR{"example.", TYPE_MX, CLASS_IN, 60, &MX{10, "mx.example."}},
+	}
+	w := dns.NewWirebuf()
+	last := 0
+	for _, r := range rrs {
+		last = len(w.Buf)
+		r.Encode(w)
+	}
+
+	// The trailing octet belongs to the next section.
+	b := append(w.Buf, 0)
+	pos := 0
+	y, err := DecodeRRs(b, &pos, len(rrs))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if g, e := pos, len(w.Buf); g != e {
+		t.Fatal(g, e)
+	}
+
+	if g, e := len(y), len(rrs); g != e {
+		t.Fatal(g, e)
+	}
+
+	for i, r := range rrs {
+		if !y[i].Equal(r) {
+			t.Errorf("%d: %s != %s", i, y[i], r)
+		}
+	}
+
+	// Truncated final record.
+	pos = 0
+	if y, err = DecodeRRs(w.Buf[:len(w.Buf)-3], &pos, len(rrs)); err == nil {
+		t.Fatal("unexpected success")
+	}
+
+	t.Log(err)
+	if g, e := len(y), 2; g != e {
+		t.Fatal(g, e)
+	}
+
+	if g, e := pos, last; g != e {
+		t.Fatal(g, e)
+	}
+
+	// Too few records.
+	pos = 0
+	if y, err = DecodeRRs(w.Buf, &pos, len(rrs)+1); err == nil || len(y) != len(rrs) {
+		t.Fatal(len(y), err)
+	}
+}
+
+func TestRRsEncode(t *testing.T) {
+	rrs := RRs{
+		&RR{"example.com.", TYPE_MX, CLASS_IN, 60, &MX{10, "mail.example.com."}},
+		&RR{"www.example.com.", TYPE_MX, CLASS_IN, 60, &MX{20, "mail.example.com."}},
+	}
+	sum := 0
+	for _, r := range rrs {
+		w := dns.NewWirebuf()
+		r.Encode(w)
+		sum += len(w.Buf)
+	}
+
+	w := dns.NewWirebuf()
+	rrs.Encode(w)
+	if n := len(w.Buf); n >= sum {
+		t.Fatalf("%d >= %d", n, sum)
+	}
+
+	// The second exchange is a single pointer to the first one.
+	if g, e := w.Buf[len(w.Buf)-2]&0xC0, byte(0xC0); g != e {
+		t.Fatalf("%#x != %#x", g, e)
+	}
+
+	if g, e := len(rrs.Pack()), len(w.Buf); g != e {
+		t.Fatal(g, e)
+	}
+
+	pos := 0
+	y, err := DecodeRRs(w.Buf, &pos, len(rrs))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, r := range rrs {
+		if !y[i].Equal(r) {
+			t.Errorf("%d: %s != %s", i, y[i], r)
+		}
+	}
+}
+
+func TestPackUncompressed(t *testing.T) {
+	rrs := RRs{
+		&RR{"example.com.", TYPE_NS, CLASS_IN, 60, &NS{"ns.example.com."}},
+		&RR{"example.com.", TYPE_MX, CLASS_IN, 60, &MX{10, "mail.example.com."}},
+		&RR{"www.example.com.", TYPE_CNAME, CLASS_IN, 60, &CNAME{"example.com."}},
+	}
+	if bytes.IndexByte(rrs.Pack(), 0xC0) < 0 {
+		t.Fatal("expected compression pointers")
+	}
+
+	b := rrs.PackUncompressed()
+	if i := bytes.IndexByte(b, 0xC0); i >= 0 {
+		t.Fatalf("compression pointer at %#x\n%s", i, hex.Dump(b))
+	}
+
+	if g, e := len(b), rrs.WireLen(); g != e {
+		t.Fatal(g, e)
+	}
+
+	y := b.Unpack()
+	if g, e := len(y), len(rrs); g != e {
+		t.Fatal(g, e)
+	}
+
+	for i, r := range rrs {
+		if !y[i].Equal(r) {
+			t.Errorf("%d: %s != %s", i, y[i], r)
+		}
+	}
+}
+
+func TestNSECIsMinimallyCovering(t *testing.T) {
+	bitmap := TypesEncode([]Type{TYPE_RRSIG, TYPE_NSEC})
+	for i, test := range []struct {
+		next, qname string
+		e           bool
+	}{
+		// Black lies, owner == qname
+		{"\x00.nx.example.com.", "nx.example.com.", true},
+		{"\x00.NX.Example.COM.", "nx.example.com", true},
+		{`\000.nx.example.com.`, "nx.example.com.", true},
+		{"\x00.", ".", true},
+		// White lies, RFC 4470/3
+		{"\x00.b.example.com.", "b.example.com.", true},
+		// Not a successor of qname
+		{"\x00.nx.example.com.", "example.com.", false},
+		{"\x00.nx.example.com.", "a.nx.example.com.", false},
+		{"\x00\x00.nx.example.com.", "nx.example.com.", false},
+		{"c.example.com.", "b.example.com.", false},
+		{"nx.example.com.", "nx.example.com.", false},
+	} {
+		if g, e := (&NSEC{test.next, bitmap}).IsMinimallyCovering(test.qname), test.e; g != e {
+			t.Errorf("%d: %q %q: %t != %t", i, test.next, test.qname, g, e)
+		}
+	}
+
+	// Round trip of a black lie as served, e.g., by Cloudflare.
+	r := &RR{"nx.example.com.", TYPE_NSEC, CLASS_IN, 300, &NSEC{"\x00.nx.example.com.", bitmap}}
+	w := dns.NewWirebuf()
+	r.Encode(w)
+	r2 := &RR{}
+	p := 0
+	if err := r2.Decode(w.Buf, &p, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if !r2.RData.(*NSEC).IsMinimallyCovering(r2.Name) || !r2.IsMinimallyCoveringNSEC(r2.Name) {
+		t.Errorf("%s", r2)
+	}
+
+	for i, test := range []struct {
+		owner, next, qname string
+		e                  bool
+	}{
+		// Black lies
+		{"nx.example.com.", "\x00.nx.example.com.", "nx.example.com.", true},
+		{"NX.example.com.", `\000.nx.example.com.`, "nx.example.com.", true},
+		// White lies, RFC 4470/3
+		{"a.example.com.", "\x00.b.example.com.", "b.example.com.", true},
+		{"~.a.example.com.", "\x00.b.example.com.", "b.example.com.", true},
+		// Bogus owner, sorts after qname
+		{"c.example.com.", "\x00.b.example.com.", "b.example.com.", false},
+		{"a.b.example.com.", "\x00.b.example.com.", "b.example.com.", false},
+		{"example.net.", "\x00.nx.example.com.", "nx.example.com.", false},
+		// Bogus next name
+		{"nx.example.com.", "z.example.com.", "nx.example.com.", false},
+	} {
+		r := &RR{test.owner, TYPE_NSEC, CLASS_IN, 300, &NSEC{test.next, bitmap}}
+		if g, e := r.IsMinimallyCoveringNSEC(test.qname), test.e; g != e {
+			t.Errorf("%d: %s %q: %t != %t", i, r, test.qname, g, e)
+		}
+	}
+
+	if (&RR{"nx.example.com.", TYPE_A, CLASS_IN, 300, &A{net.ParseIP("192.0.2.1")}}).IsMinimallyCoveringNSEC("nx.example.com.") {
+		t.Error("A RR accepted")
+	}
+}
+
+func TestVerifyRRSet(t *testing.T) {
+	defer func(f func() time.Time) { Now = f }(Now)
+
+	priv, err := rsa.GenerateKey(crand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	Now = func() time.Time { return time.Unix(0x4f800000, 0) }
+	rrset := RRs{&RR{"www.example.", TYPE_A, CLASS_IN, 60, &A{net.IP{192, 0, 2, 1}}}}
+	key := &RR{"example.", TYPE_DNSKEY, CLASS_IN, 3600, rsaDNSKEY(&priv.PublicKey, AlgorithmRSA_SHA256)}
+	rd, err := SignRRSet(rrset, key, priv, 0x4f000000, 0x50000000)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Swapping two key octets 2 positions apart keeps the key tag.
+	bogus := key.Copy()
+	k := bogus.RData.(*DNSKEY).Key
+	for i := 10; ; i++ {
+		if k[i] != k[i+2] {
+			k[i], k[i+2] = k[i+2], k[i]
+			break
+		}
+	}
+	if g, e := bogus.RData.(*DNSKEY).KeyTag(), rd.KeyTag; g != e {
+		t.Fatal(g, e)
+	}
+
+	expired := *rd
+	expired.Expiration = 0x4f700000
+	sigs := RRs{
+		&RR{"www.example.", TYPE_RRSIG, CLASS_IN, 60, &expired},
+		&RR{"www.example.", TYPE_RRSIG, CLASS_IN, 60, rd},
+	}
+	keys := RRs{bogus, key}
+	sig, err := VerifyRRSet(rrset, sigs, keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if sig != rd {
+		t.Fatal(sig)
+	}
+
+	if _, err = VerifyRRSet(rrset, sigs, keys[:1]); err == nil {
+		t.Fatal("unexpected success")
+	}
+
+	t.Log(err)
+	if _, err = VerifyRRSet(rrset, sigs[:1], keys); err == nil {
+		t.Fatal("unexpected success")
+	}
+
+	// Not a zone key.
+	key = key.Copy()
+	key.RData.(*DNSKEY).Flags &^= DNSKEY_ZONE
+	if rd, err = SignRRSet(rrset, key, priv, 0x4f000000, 0x50000000); err != nil {
+		t.Fatal(err)
+	}
+
+	sigs = RRs{&RR{"www.example.", TYPE_RRSIG, CLASS_IN, 60, rd}}
+	if err = rd.Verify(key, rrset); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = VerifyRRSet(rrset, sigs, RRs{key}); err == nil {
+		t.Fatal("unexpected success")
+	}
+}
+
+func TestNINFO(t *testing.T) {
+	r := &RR{"example.com.", TYPE_NINFO, CLASS_IN, 3600, &NINFO{[]string{"ready", "", `say "hi"`}}}
+	if g, e := r.RData.(*NINFO).String(), `"ready" "" "say \"hi\""`; g != e {
+		t.Errorf("%q != %q", g, e)
+	}
+
+	w := dns.NewWirebuf()
+	r.Encode(w)
+	r2 := &RR{}
+	p := 0
+	if err := r2.Decode(w.Buf, &p, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if g, e := p, len(w.Buf); g != e {
+		t.Fatalf("%d != %d", g, e)
+	}
+
+	if g, e := len(r2.RData.(*NINFO).ZSData), 3; g != e {
+		t.Fatalf("%d != %d", g, e)
+	}
+
+	if !r.Equal(r2) {
+		t.Errorf("%s != %s", r, r2)
+	}
+
+	r2.RData.(*NINFO).ZSData[1] = "x"
+	if r.Equal(r2) {
+		t.Errorf("%s == %s", r, r2)
+	}
+}
+
+func TestGPOS(t *testing.T) {
+	// RFC 1712/3
+	r := &RR{"sydney.example.", TYPE_GPOS, CLASS_IN, 0, &GPOS{"-32.6882", "116.8652", "10.0"}}
+	if g, e := r.RData.(*GPOS).String(), "-32.6882 116.8652 10.0"; g != e {
+		t.Errorf("%q != %q", g, e)
+	}
+
+	// The coordinates must survive decoding and encoding verbatim.
+	r.RData = &GPOS{"+1.5", "116.86521234567", "10.00"}
+	w := dns.NewWirebuf()
+	r.Encode(w)
+	r2 := &RR{}
+	p := 0
+	if err := r2.Decode(w.Buf, &p, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if !r.Equal(r2) {
+		t.Errorf("%s != %s", r, r2)
+	}
+
+	if g, e := r2.RData.(*GPOS).String(), "+1.5 116.86521234567 10.00"; g != e {
+		t.Errorf("%q != %q", g, e)
+	}
+
+	w2 := dns.NewWirebuf()
+	r2.Encode(w2)
+	if !bytes.Equal(w.Buf, w2.Buf) {
+		t.Errorf("\n%x\n%x", w.Buf, w2.Buf)
+	}
+
+	for _, s := range []string{"0", "-0", "+90", "-180.000001", "10.0"} {
+		if err := (&GPOS{s, s, s}).Validate(); err != nil {
+			t.Errorf("%q: %s", s, err)
+		}
+	}
+
+	rd := &GPOS{}
+	for _, s := range []string{"", "+", "-", "1.", ".5", "1.5x", "1e3", "0x1p3", "NaN", "Inf", "-Inf", "1,5", " 1"} {
+		if err := (&GPOS{"1", "2", s}).Validate(); err == nil {
+			t.Errorf("%q: unexpected success", s)
+		}
+
+		b := []byte{1, '1', 1, '2', byte(len(s))}
+		b = append(b, s...)
+		p = 0
+		if err := rd.Decode(b, &p, nil); err == nil {
+			t.Errorf("%q: unexpected success", b)
+		}
+	}
+
+	for _, b := range [][]byte{
+		{1, '1', 1, '2'},
+		{1, '1', 1, '2', 1, '3', 1, '4'},
+	} {
+		p = 0
+		if err := rd.Decode(b, &p, nil); err == nil {
+			t.Errorf("%q: unexpected success", b)
+		}
+	}
+}
+
+func TestCoalesce(t *testing.T) {
+	a := &RR{"example.", TYPE_A, CLASS_IN, 3600, &A{net.ParseIP("192.0.2.1")}}
+	rrs := RRs{
+		a,
+		&RR{"example.", TYPE_A, CLASS_IN, 60, &A{net.ParseIP("192.0.2.2")}},
+		&RR{"EXAMPLE.", TYPE_A, CLASS_IN, 300, &A{net.ParseIP("192.0.2.1")}},
+	}
+	rrs.Coalesce()
+	if g, e := len(rrs), 2; g != e {
+		t.Fatalf("%d != %d\n%s", g, e, rrs)
+	}
+
+	if g, e := rrs[0].TTL, int32(300); g != e {
+		t.Errorf("%d != %d", g, e)
+	}
+
+	if g, e := rrs[1].TTL, int32(60); g != e {
+		t.Errorf("%d != %d", g, e)
+	}
+
+	if g, e := a.TTL, int32(3600); g != e {
+		t.Errorf("%d != %d", g, e)
+	}
+
+	u := RRs{a, &RR{"example.", TYPE_A, CLASS_IN, 300, &A{net.ParseIP("192.0.2.1")}}}
+	u.Unique()
+	if g, e := u[0].TTL, int32(3600); len(u) != 1 || g != e {
+		t.Errorf("%d != %d\n%s", g, e, u)
+	}
+}
+
+func TestAge(t *testing.T) {
+	rrs := RRs{
+		&RR{"example.", TYPE_A, CLASS_IN, 3600, &A{net.ParseIP("192.0.2.1")}},
+		&RR{"example.", TYPE_MX, CLASS_IN, 60, &MX{10, "mx.example."}},
+		&RR{"example.", TYPE_NS, CLASS_IN, 100, &NS{"ns.example."}},
+	}
+	y := rrs.Age(99)
+	if g, e := len(y), 2; g != e {
+		t.Fatalf("%d != %d\n%s", g, e, y)
+	}
+
+	for i, e := range []int32{3501, 1} {
+		if g := y[i].TTL; g != e {
+			t.Errorf("%d: %d != %d", i, g, e)
+		}
+	}
+
+	// A record expires when its TTL elapses, the same as in CachedRR.
+	c := CachedRR{rrs[2], time.Unix(1e9, 0)}
+	if !c.Expired(c.Inserted.Add(100 * time.Second)) {
+		t.Fatal("not expired")
+	}
+
+	y = rrs.Age(100)
+	if g, e := len(y), 1; g != e {
+		t.Fatalf("%d != %d\n%s", g, e, y)
+	}
+
+	if g, e := y[0].TTL, int32(3500); g != e {
+		t.Errorf("%d != %d", g, e)
+	}
+
+	for i, e := range []int32{3600, 60, 100} {
+		if g := rrs[i].TTL; g != e {
+			t.Errorf("%d: %d != %d", i, g, e)
+		}
+	}
+
+	y[0].RData.(*A).Address[15] = 2
+	if g, e := rrs[0].RData.(*A).Address.String(), "192.0.2.1"; g != e {
+		t.Errorf("%q != %q", g, e)
+	}
+
+	r := rrs[1].WithTTL(30)
+	if g, e := r.TTL, int32(30); g != e || rrs[1].TTL != 60 || !r.Equal(rrs[1]) {
+		t.Errorf("%d != %d, %s", g, e, rrs[1])
+	}
+}
+
+func TestCachedRR(t *testing.T) {
+	t0 := time.Unix(1e9, 0)
+	c := CachedRR{&RR{"example.", TYPE_A, CLASS_IN, 60, &A{net.ParseIP("192.0.2.1")}}, t0}
+	for _, test := range []struct {
+		d       time.Duration
+		expired bool
+		ttl     int32
+	}{
+		{-time.Second, false, 60},
+		{0, false, 60},
+		{999 * time.Millisecond, false, 60},
+		{time.Second, false, 59},
+		{59*time.Second + 999*time.Millisecond, false, 1},
+		{60 * time.Second, true, 0},
+		{60*time.Second + time.Nanosecond, true, 0},
+		{time.Hour, true, 0},
+	} {
+		now := t0.Add(test.d)
+		if g, e := c.Expired(now), test.expired; g != e {
+			t.Errorf("%s: %t != %t", test.d, g, e)
+		}
+
+		if g, e := c.RemainingTTL(now), test.ttl; g != e {
+			t.Errorf("%s: %d != %d", test.d, g, e)
+		}
+	}
+}
+
+func TestNewRR(t *testing.T) {
+	r := NewRR("example.", 3600, &MX{10, "mx.example."})
+	if r == nil {
+		t.Fatal("nil")
+	}
+
+	if g, e := r.Type, TYPE_MX; g != e {
+		t.Errorf("%s != %s", g, e)
+	}
+
+	if g, e := r.Class, CLASS_IN; g != e {
+		t.Errorf("%s != %s", g, e)
+	}
+
+	if g, e := r.String(), "example.\tIN\t3600\tMX 10 mx.example."; g != e {
+		t.Errorf("%q != %q", g, e)
+	}
+
+	if r = NewRR("example.", 0, &CNAME{"x.example."}); r == nil || r.Type != TYPE_CNAME {
+		t.Errorf("%v", r)
+	}
+
+	if r = NewRR("example.", 0, &HTTPS{}); r == nil || r.Type != TYPE_HTTPS {
+		t.Errorf("%v", r)
+	}
+
+	if r = NewRR("example.", 0, &RDATA{}); r != nil {
+		t.Errorf("%v", r)
+	}
+
+	for typ := range Types {
+		rd := newRData(typ)
+		if _, ok := rd.(*RDATA); ok {
+			continue
+		}
+
+		if g, ok := rdataType(rd); !ok || g != typ {
+			t.Errorf("%s: %s %t", typ, g, ok)
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	r := &RR{"example.", TYPE_A, CLASS_IN, 60, &MX{10, "mx.example."}}
+	if err := r.Check(); err == nil {
+		t.Fatal("unexpected success")
+	}
+
+	w := dns.NewWirebuf()
+	err := r.EncodeErr(w)
+	if err == nil {
+		t.Fatal("unexpected success")
+	}
+
+	t.Log(err)
+	if g, e := len(w.Buf), 0; g != e {
+		t.Fatalf("%d != %d", g, e)
+	}
+
+	r.Type = TYPE_MX
+	if err = r.Check(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = r.EncodeErr(w); err != nil {
+		t.Fatal(err)
+	}
+
+	rd := RDATA{1, 2, 3, 4}
+	if err = (&RR{"example.", TYPE_A, CLASS_IN, 60, &rd}).Check(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = (&RR{"example.", TYPE_A, CLASS_IN, 60, nil}).Check(); err == nil {
+		t.Fatal("unexpected success")
+	}
+}
+
+func TestTALINK(t *testing.T) {
+	r := &RR{"h0.example.", TYPE_TALINK, CLASS_IN, 3600, &TALINK{".", "H1.Example."}}
+	if g, e := r.RData.(*TALINK).String(), ". H1.Example."; g != e {
+		t.Errorf("%q != %q", g, e)
+	}
+
+	w := dns.NewWirebuf()
+	r.Encode(w)
+	r2 := &RR{}
+	p := 0
+	if err := r2.Decode(w.Buf, &p, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if g, e := r2.RData.(*TALINK).NextName, "H1.Example."; g != e {
+		t.Errorf("%q != %q", g, e)
+	}
+
+	r2.RData.(*TALINK).NextName = "h1.example."
+	if !r.Equal(r2) {
+		t.Errorf("%s != %s", r, r2)
+	}
+
+	// The next name compressed as a pointer to the owner name.
+	b := append([]byte{2, 'h', '0', 7, 'e', 'x', 'a', 'm', 'p', 'l', 'e', 0}, 0, 58, 0, 1, 0, 0, 0, 0, 0, 3, 0, 0xC0, 0)
+	p = 0
+	if err := r2.Decode(b, &p, nil); err == nil {
+		t.Fatal("unexpected success")
+	}
+}
+
+func TestNSAP(t *testing.T) {
+	// RFC 1706/6
+	rd, err := ParseNSAP("0x47.0005.80.005a00.0000.0001.e133.ffffff000162.00")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if g, e := rd.String(), "0x47000580005a0000000001e133ffffff00016200"; g != e {
+		t.Errorf("%q != %q", g, e)
+	}
+
+	r := &RR{"host.school.de.", TYPE_NSAP, CLASS_IN, 0, rd}
+	w := dns.NewWirebuf()
+	r.Encode(w)
+	r2 := &RR{}
+	p := 0
+	if err = r2.Decode(w.Buf, &p, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if g, e := len(r2.RData.(*NSAP).NSAP), 20; g != e {
+		t.Errorf("%d != %d", g, e)
+	}
+
+	if !r.Equal(r2) {
+		t.Errorf("%s != %s", r, r2)
+	}
+
+	if rd, err = ParseNSAP(r2.RData.(*NSAP).String()); err != nil || !bytes.Equal(rd.NSAP, r2.RData.(*NSAP).NSAP) {
+		t.Fatal(rd, err)
+	}
+
+	for _, s := range []string{"", "0x", "47.0005", "0x470", "0x47.00g5"} {
+		if _, err = ParseNSAP(s); err == nil {
+			t.Errorf("%q: unexpected success", s)
+		}
+	}
+}
+
+func TestTypesDecodeInto(t *testing.T) {
+	types := []Type{TYPE_A, TYPE_NS, TYPE_RRSIG, TYPE_NSEC, TYPE_CAA, Type(1234), TYPE_TA, TYPE_DLV}
+	bits := TypesEncode([]Type{TYPE_DLV, TYPE_NSEC, TYPE_A, TYPE_TA, TYPE_NS, TYPE_CAA, TYPE_A, Type(1234), TYPE_RRSIG})
+	if g, e := bits[0], byte(0); g != e {
+		t.Fatal(g, e)
+	}
+
+	out := make([]Type, 3, 16)
+	got, err := TypesDecodeInto(bits, out)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if g, e := len(got), len(types); g != e {
+		t.Fatal(g, e)
+	}
+
+	if &got[0] != &out[:1][0] {
+		t.Fatal("out not reused")
+	}
+
+	for i, e := range types {
+		if g := got[i]; g != e {
+			t.Errorf("%d: %s != %s", i, g, e)
+		}
+	}
+
+	if g, e := bitmapString(bits), "A NS RRSIG NSEC CAA TYPE1234 TA DLV"; g != e {
+		t.Errorf("%q != %q", g, e)
+	}
+
+	if g, e := TypesString(types), bitmapString(bits); g != e {
+		t.Errorf("%q != %q", g, e)
+	}
+}
+
+var benchTypes = []Type{TYPE_A, TYPE_NS, TYPE_SOA, TYPE_MX, TYPE_TXT, TYPE_AAAA, TYPE_RRSIG, TYPE_NSEC, TYPE_DNSKEY, TYPE_CAA}
+
+func BenchmarkTypesEncode(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		TypesEncode(benchTypes)
+	}
+}
+
+func BenchmarkTypesDecode(b *testing.B) {
+	bits := TypesEncode(benchTypes)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		TypesDecode(bits)
+	}
+}
+
+func BenchmarkTypesDecodeInto(b *testing.B) {
+	bits := TypesEncode(benchTypes)
+	out := make([]Type, 0, 32)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		out, _ = TypesDecodeInto(bits, out)
+	}
+}
+
+func benchResponse() (r RRs) {
+	for i := 0; i < 50; i++ {
+		name := fmt.Sprintf("host%d.example.com.", i%10)
+		switch i % 5 {
+		case 0:
+			r = append(r, &RR{name, TYPE_A, CLASS_IN, 3600, &A{net.IPv4(192, 0, 2, byte(i))}})
+		case 1:
+			r = append(r, &RR{name, TYPE_AAAA, CLASS_IN, 3600, &AAAA{net.ParseIP(fmt.Sprintf("2001:db8::%x", i))}})
+		case 2:
+			r = append(r, &RR{name, TYPE_MX, CLASS_IN, 3600, &MX{uint16(i), "mail.example.com."}})
+		case 3:
+			r = append(r, &RR{name, TYPE_NS, CLASS_IN, 3600, &NS{"ns1.example.com."}})
+		default:
+			r = append(r, &RR{name, TYPE_TXT, CLASS_IN, 3600, &TXT{[]string{"v=spf1 -all"}}})
+		}
+	}
+	return
+}
+
+func TestEncodeInto(t *testing.T) {
+	r := benchResponse()
+	w := dns.NewWirebuf()
+	r.Encode(w)
+	e := append([]byte(nil), w.Buf...)
+	w.Reset()
+	r.Encode(w)
+	if !bytes.Equal(w.Buf, e) {
+		t.Fatalf("\n%x\n%x", w.Buf, e)
+	}
+
+	// EncodeInto appends, the records share the compression table.
+	w.Reset()
+	for _, rec := range r {
+		rec.EncodeInto(w)
+	}
+	if !bytes.Equal(w.Buf, e) {
+		t.Fatalf("\n%x\n%x", w.Buf, e)
+	}
+
+	encode := func() {
+		w.Reset()
+		for _, rec := range r {
+			rec.EncodeInto(w)
+		}
+	}
+	if n := testing.AllocsPerRun(10, encode); n != 0 {
+		t.Errorf("%v allocations", n)
+	}
+}
+
+func BenchmarkEncodeReuse(b *testing.B) {
+	r := benchResponse()
+	w := dns.NewWirebuf()
+	r.Encode(w)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		w.Reset()
+		for _, rec := range r {
+			rec.EncodeInto(w)
+		}
+	}
+}
+
+func TestInternNames(t *testing.T) {
+	r := RRs{
+		&RR{"www." + "Example.com.", TYPE_A, CLASS_IN, 60, &A{net.ParseIP("192.0.2.1")}},
+		&RR{"www.example." + "com", TYPE_AAAA, CLASS_IN, 60, &AAAA{net.ParseIP("2001:db8::1")}},
+		&RR{"example.com.", TYPE_MX, CLASS_IN, 60, &MX{10, "WWW.example.com."}},
+		&RR{"example.com.", TYPE_NSEC, CLASS_IN, 60, &NSEC{"WWW.example.com.", TypesEncode([]Type{TYPE_MX})}},
+	}
+	i := NewNameInterner()
+	r.InternNames(i)
+	if g, e := i.Len(), 2; g != e {
+		t.Fatalf("%d != %d", g, e)
+	}
+
+	p := func(s string) *byte { return unsafe.StringData(s) }
+	if g, e := r[0].Name, "www.example.com."; g != e {
+		t.Fatalf("%q != %q", g, e)
+	}
+
+	if p(r[0].Name) != p(r[1].Name) || p(r[0].Name) != p(r[2].RData.(*MX).Exchange) {
+		t.Error("names not shared")
+	}
+
+	if p(r[2].Name) != p(r[3].Name) {
+		t.Error("names not shared")
+	}
+
+	// The NSEC next name keeps its case (RFC 6840/5.1).
+	if g, e := r[3].RData.(*NSEC).NextDomainName, "WWW.example.com."; g != e {
+		t.Errorf("%q != %q", g, e)
+	}
+}
+
+func TestNSEC3Limits(t *testing.T) {
+	params := &NSEC3PARAM{HashAlgorithmSHA1, 0, 1000, nil}
+	if err := params.CheckLimits(100); err == nil {
+		t.Fatal("unexpected success")
+	}
+
+	if err := params.CheckLimits(1000); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := params.HashName("example."); err == nil {
+		t.Fatal("unexpected success")
+	}
+
+	defer func(n uint16) { MaxNSEC3Iterations = n }(MaxNSEC3Iterations)
+
+	MaxNSEC3Iterations = 1000
+	if _, err := params.HashName("example."); err != nil {
+		t.Fatal(err)
+	}
+
+	// The signer side is not limited.
+	MaxNSEC3Iterations = 0
+	zone := RRs{
+		&RR{"example.", TYPE_SOA, CLASS_IN, 3600, &SOA{"ns.example.", "hostmaster.example.", 1, 3600, 600, 86400, 300}},
+		&RR{"www.example.", TYPE_A, CLASS_IN, 3600, &A{net.ParseIP("192.0.2.1")}},
+	}
+	if _, err := GenerateNSEC3(zone, params, false); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestParseDNSSEC(t *testing.T) {
+	key := make([]byte, 132)
+	for i := range key {
+		key[i] = byte(i * 13)
+	}
+	dnskey := &DNSKEY{257, 3, AlgorithmRSA_SHA256, key}
+	k, err := ParseDNSKEY(strings.Fields(dnskey.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if g, e := k.String(), dnskey.String(); g != e {
+		t.Errorf("\n%s\n%s", g, e)
+	}
+
+	ds := dnskey.ToDS("example.")
+	d, err := ParseDS(strings.Fields(ds.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if g, e := d.String(), ds.String(); g != e {
+		t.Errorf("\n%s\n%s", g, e)
+	}
+
+	rrsig := &RRSIG{TYPE_A, AlgorithmRSA_SHA256, 2, 3600, 0x50000000, 0x4f000000, 2642, "example.", key}
+	s, err := ParseRRSIG(strings.Fields(rrsig.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if g, e := s.String(), rrsig.String(); g != e {
+		t.Errorf("\n%s\n%s", g, e)
+	}
+
+	// RFC 4034/3.3, the signature split into chunks inside parentheses.
+	s, err = ParseRRSIG(strings.Fields(`A 5 3 86400 20030322173103 (
+		20030220173103 2642 example.com.
+		oJB1W6WNGv+ldvQ3WDG0MQkg5IEhjRip8WTr
+		PYGv07h108dUKGMeDPKijVCHX3DDKdfb+v6o
+		B9wfuh3DTJXUAfI/M0zmO/zz8bW0Rznl8O3t
+		GNazPwQKkRN20XPXV6nwwfoXmJQbsLNrLfkG
+		J5D6fwFm8nN+6pBzeDQfsS3Ap3o= )`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if g, e := s.Type, TYPE_A; g != e {
+		t.Errorf("%s != %s", g, e)
+	}
+
+	if g, e := s.Inception, uint32(1045762263); g != e {
+		t.Errorf("%d != %d", g, e)
+	}
+
+	if g, e := s.Name, "example.com."; g != e {
+		t.Errorf("%q != %q", g, e)
+	}
+
+	if g, e := len(s.Signature), 128; g != e {
+		t.Errorf("%d != %d", g, e)
+	}
+
+	for _, tokens := range []string{
+		"A 5 3 86400 20030322173103 20030220173103 2642 example.com.",
+		"A 256 3 86400 20030322173103 20030220173103 2642 example.com. AAAA",
+		"XYZ 5 3 86400 20030322173103 20030220173103 2642 example.com. AAAA",
+		"A 5 3 86400 2003032217310 20030220173103 2642 example.com. AAAA",
+		"A 5 3 86400 20030322173103 20030220173103 2642 example.com. !!!!",
+	} {
+		if _, err = ParseRRSIG(strings.Fields(tokens)); err == nil {
+			t.Errorf("%q: unexpected success", tokens)
+		}
+	}
+
+	// Algorithm mnemonics.
+	if k, err = ParseDNSKEY(strings.Fields("257 3 RSASHA256 " + string(strutil.Base64Encode(key)))); err != nil {
+		t.Fatal(err)
+	}
+
+	if g, e := k.String(), dnskey.String(); g != e {
+		t.Errorf("\n%s\n%s", g, e)
+	}
+
+	if d, err = ParseDS(strings.Fields("60485 rsasha1 1 2BB183AF5F22588179A53B0A98631FAD1A292118")); err != nil {
+		t.Fatal(err)
+	}
+
+	if g, e := d.Algorithm, AlgorithmRSA_SHA1; g != e {
+		t.Errorf("%s != %s", g, e)
+	}
+
+	if s, err = ParseRRSIG(strings.Fields("A ECDSAP256SHA256 2 3600 20120412000000 20120405000000 2642 example. AAAA")); err != nil {
+		t.Fatal(err)
+	}
+
+	if g, e := s.Algorithm, AlgorithmECDSA_P256_SHA256; g != e {
+		t.Errorf("%s != %s", g, e)
+	}
+
+	for _, v := range []string{"DSA-NSEC3-SHA1", "ed25519", "PRIVATEOID"} {
+		a, ok := AlgorithmByName(v)
+		if !ok || !strings.EqualFold(a.String(), v) {
+			t.Errorf("%q: %s %t", v, a, ok)
+		}
+	}
+
+	for _, tokens := range []string{"257 3 8", "257 3 x AAAA", "257 3 RSASHA3 AAAA", "257 3 256 AAAA", "65536 3 8 AAAA", "257 3 8 A"} {
+		if _, err = ParseDNSKEY(strings.Fields(tokens)); err == nil {
+			t.Errorf("%q: unexpected success", tokens)
+		}
+	}
+
+	for _, tokens := range []string{"2642 8 2", "2642 8 2 abc", "2642 8 2 xy", "2642 RSA 2 ab"} {
+		if _, err = ParseDS(strings.Fields(tokens)); err == nil {
+			t.Errorf("%q: unexpected success", tokens)
+		}
+	}
+}
+
+func TestEIDNIMLOC(t *testing.T) {
+	for _, r := range []*RR{
+		{"eid.example.", TYPE_EID, CLASS_IN, 3600, &EID{[]byte{0x8c, 0x2a, 0x3f, 0x01}}},
+		{"nimloc.example.", TYPE_NIMLOC, CLASS_IN, 3600, &NIMLOC{[]byte{0x32, 0x25, 0x1a, 0x03}}},
+		{"empty.example.", TYPE_EID, CLASS_IN, 3600, &EID{[]byte{}}},
+	} {
+		w := dns.NewWirebuf()
+		r.Encode(w)
+		r2 := &RR{}
+		p := 0
+		if err := r2.Decode(w.Buf, &p, nil); err != nil {
+			t.Fatal(err)
+		}
+
+		if g, e := p, len(w.Buf); g != e {
+			t.Fatalf("%d != %d", g, e)
+		}
+
+		if g, e := fmt.Sprintf("%T", r2.RData), fmt.Sprintf("%T", r.RData); g != e {
+			t.Fatalf("%s != %s", g, e)
+		}
+
+		if !r.Equal(r2) {
+			t.Errorf("%s != %s", r, r2)
+		}
+	}
+
+	rd := &EID{[]byte{0x8c, 0x2a, 0x3f, 0x01}}
+	if g, e := rd.String(), "8c2a3f01"; g != e {
+		t.Errorf("%q != %q", g, e)
+	}
+
+	r := &RR{"x.example.", TYPE_NIMLOC, CLASS_IN, 0, &NIMLOC{[]byte{1, 2}}}
+	if g, e := r.String(), "x.example.\tIN\t0\tNIMLOC 0102"; g != e {
+		t.Errorf("%q != %q", g, e)
+	}
+
+	r2 := &RR{"x.example.", TYPE_NIMLOC, CLASS_IN, 0, &NIMLOC{[]byte{1, 3}}}
+	if r.Equal(r2) {
+		t.Errorf("%s == %s", r, r2)
+	}
+}
+
+func TestATMA(t *testing.T) {
+	aesa, err := hex.DecodeString("39246f000e7c9c03120001000100001234567890")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	table := []struct {
+		rd *ATMA
+		s  string
+	}{
+		{&ATMA{ATMAFormatAESA, aesa}, "39.246f.000e7c9c031200010001.000012345678.90"},
+		{&ATMA{ATMAFormatE164, []byte("14085551212")}, "14085551212"},
+	}
+	for _, test := range table {
+		if g, e := test.rd.String(), test.s; g != e {
+			t.Errorf("%q != %q", g, e)
+		}
+
+		if err := test.rd.Validate(); err != nil {
+			t.Error(err)
+		}
+
+		r := &RR{"atm.example.", TYPE_ATMA, CLASS_IN, 3600, test.rd}
+		w := dns.NewWirebuf()
+		r.Encode(w)
+		r2 := &RR{}
+		p := 0
+		if err := r2.Decode(w.Buf, &p, nil); err != nil {
+			t.Fatal(err)
+		}
+
+		if g, e := p, len(w.Buf); g != e {
+			t.Fatalf("%d != %d", g, e)
+		}
+
+		if !r.Equal(r2) {
+			t.Errorf("%s != %s", r, r2)
+		}
+
+		if g, e := r2.RData.(*ATMA).String(), test.s; g != e {
+			t.Errorf("%q != %q", g, e)
+		}
+	}
+
+	for _, rd := range []*ATMA{
+		{ATMAFormatAESA, aesa[:19]},
+		{ATMAFormatE164, nil},
+		{ATMAFormatE164, []byte("+14085551212")},
+		{2, []byte{1}},
+	} {
+		if err := rd.Validate(); err == nil {
+			t.Errorf("%v: unexpected success", rd)
+		}
+	}
+
+	r := &RR{}
+	p := 0
+	if err := r.Decode([]byte{0, 0, 34, 0, 1, 0, 0, 0, 0, 0, 1, 1}, &p, nil); err == nil {
+		t.Error("unexpected success")
+	}
+}
+
+func TestStats(t *testing.T) {
+	zone := RRs{
+		{"example.com.", TYPE_SOA, CLASS_IN, 3600, &SOA{"ns1.example.com.", "hostmaster.example.com.", 1, 2, 3, 4, 5}},
+		{"example.com.", TYPE_NS, CLASS_IN, 3600, &NS{"ns1.example.com."}},
+		{"example.com.", TYPE_NS, CLASS_IN, 3600, &NS{"ns2.example.com."}},
+		{"example.com.", TYPE_MX, CLASS_IN, 3600, &MX{10, "mail.example.com."}},
+		{"ns1.example.com.", TYPE_A, CLASS_IN, 3600, &A{net.ParseIP("192.0.2.1")}},
+		{"NS1.example.com.", TYPE_AAAA, CLASS_IN, 3600, &AAAA{net.ParseIP("2001:db8::1")}},
+		{"ns2.example.com.", TYPE_A, CLASS_IN, 3600, &A{net.ParseIP("192.0.2.2")}},
+		{"mail.example.com.", TYPE_A, CLASS_IN, 3600, &A{net.ParseIP("192.0.2.3")}},
+		{"www.example.com.", TYPE_CNAME, CLASS_IN, 3600, &CNAME{"example.com."}},
+	}
+	stats := zone.Stats()
+	for typ, n := range map[Type]int{TYPE_SOA: 1, TYPE_NS: 2, TYPE_MX: 1, TYPE_A: 3, TYPE_AAAA: 1, TYPE_CNAME: 1} {
+		if g, e := stats[typ], n; g != e {
+			t.Errorf("%s: %d != %d", typ, g, e)
+		}
+	}
+
+	if g, e := len(stats), 6; g != e {
+		t.Errorf("%d != %d", g, e)
+	}
+
+	if g, e := zone.Summary(), "9 records, 5 names\nA\t3\nNS\t2\nCNAME\t1\nSOA\t1\nMX\t1\nAAAA\t1"; g != e {
+		t.Errorf("\n%s\n!=\n%s", g, e)
+	}
+
+	if g, e := RRs(nil).Summary(), "0 records, 0 names"; g != e {
+		t.Errorf("%q != %q", g, e)
+	}
+}
+
+func TestStringAligned(t *testing.T) {
+	zone := RRs{
+		{"example.com.", TYPE_SOA, CLASS_IN, 3600, &SOA{"ns.example.com.", "hostmaster.example.com.", 1, 2, 3, 4, 5}},
+		{"example.com.", TYPE_NS, CLASS_IN, 3600, &NS{"ns.example.com."}},
+		{"ns.example.com.", TYPE_A, CLASS_IN, 86400, &A{net.ParseIP("192.0.2.1")}},
+		{"www.example.com.", TYPE_CNAME, CLASS_IN, 300, &CNAME{"example.com."}},
+		{"example.com.", TYPE_NSEC, CLASS_IN, 3600, &NSEC{"ns.example.com.", TypesEncode([]Type{TYPE_NS, TYPE_SOA})}},
+	}
+	const golden = `example.com.     3600  IN SOA   ns.example.com. hostmaster.example.com. 1 2 3 4 5
+example.com.     3600  IN NS    ns.example.com.
+ns.example.com.  86400 IN A     192.0.2.1
+www.example.com. 300   IN CNAME example.com.
+example.com.     3600  IN NSEC  ns.example.com. NS SOA`
+	if g, e := zone.StringAligned(), golden; g != e {
+		t.Errorf("\n%s\n!=\n%s", g, e)
+	}
+
+	if g, e := RRs(nil).StringAligned(), ""; g != e {
+		t.Errorf("%q != %q", g, e)
+	}
+}
+
+func TestStringZone(t *testing.T) {
+	zone := RRs{
+		{"example.com.", TYPE_SOA, CLASS_IN, 3600, &SOA{"ns.example.com.", "hostmaster.example.com.", 1, 2, 3, 4, 5}},
+		{"Example.COM.", TYPE_NS, CLASS_IN, 3600, &NS{"ns.example.com."}},
+		{"ns.example.com.", TYPE_A, CLASS_IN, 86400, &A{net.ParseIP("192.0.2.1")}},
+		{"www.sub.example.com", TYPE_TXT, CLASS_IN, 300, &TXT{[]string{"hi"}}},
+		{"www.sub.example.com.", TYPE_TXT, CLASS_CH, 300, &TXT{[]string{"chaos"}}},
+		{"example.net.", TYPE_CNAME, CLASS_CH, 60, &CNAME{"example.com."}},
+		{"notexample.com.", TYPE_A, CLASS_IN, 60, &A{net.ParseIP("192.0.2.2")}},
+		{`foo\.example.com.`, TYPE_A, CLASS_IN, 60, &A{net.ParseIP("192.0.2.3")}},
+		{`bar\\.example.com.`, TYPE_A, CLASS_IN, 60, &A{net.ParseIP("192.0.2.4")}},
+	}
+	const golden = `$ORIGIN example.com.
+@	3600	IN	SOA ns.example.com. hostmaster.example.com. 1 2 3 4 5
+	3600	NS ns.example.com.
+ns	86400	A 192.0.2.1
+www.sub	300	TXT "hi"
+	300	CH	TXT "chaos"
+example.net.	60	CNAME example.com.
+notexample.com.	60	IN	A 192.0.2.2
+foo\.example.com.	60	A 192.0.2.3
+bar\\	60	A 192.0.2.4
+`
+	if g, e := zone.StringZone("EXAMPLE.com"), golden; g != e {
+		t.Errorf("\n%s\n!=\n%s", g, e)
+	}
+
+	const absolute = `example.com.	3600	IN	SOA ns.example.com. hostmaster.example.com. 1 2 3 4 5
+	3600	NS ns.example.com.
+`
+	if g, e := zone[:2].StringZone(""), absolute; g != e {
+		t.Errorf("\n%s\n!=\n%s", g, e)
+	}
+
+	if g, e := zone[2:3].StringZone("."), "$ORIGIN .\nns.example.com\t86400\tIN\tA 192.0.2.1\n"; g != e {
+		t.Errorf("%q != %q", g, e)
+	}
+}
+
+func TestAddresses(t *testing.T) {
+	rrs := RRs{
+		{"example.com.", TYPE_NS, CLASS_IN, 3600, &NS{"ns.example.com."}},
+		{"ns.example.com.", TYPE_A, CLASS_IN, 3600, &A{net.ParseIP("192.0.2.1")}},
+		{"example.com.", TYPE_MX, CLASS_IN, 3600, &MX{10, "mail.example.com."}},
+		{"ns.example.com.", TYPE_AAAA, CLASS_IN, 3600, &AAAA{net.ParseIP("2001:db8::1")}},
+		{"www.example.com.", TYPE_CNAME, CLASS_IN, 3600, &CNAME{"ns.example.com."}},
+		{"mail.example.com.", TYPE_A, CLASS_IN, 3600, &A{net.ParseIP("192.0.2.2")}},
+	}
+	for i, v := range rrs {
+		ip, ok := v.IP()
+		if g, e := ok, v.Type == TYPE_A || v.Type == TYPE_AAAA; g != e {
+			t.Errorf("%d: %t != %t", i, g, e)
+		}
+
+		if g, e := v.IsAddress(), ok; g != e {
+			t.Errorf("%d: %t != %t", i, g, e)
+		}
+
+		if !ok && ip != nil {
+			t.Errorf("%d: %s", i, ip)
+		}
+	}
+
+	ips := rrs.Addresses()
+	if g, e := len(ips), 3; g != e {
+		t.Fatalf("%d != %d", g, e)
+	}
+
+	for i, s := range []string{"192.0.2.1", "2001:db8::1", "192.0.2.2"} {
+		if !ips[i].Equal(net.ParseIP(s)) {
+			t.Errorf("%d: %s != %s", i, ips[i], s)
+		}
+	}
+
+	if ips := rrs[:1].Addresses(); ips != nil {
+		t.Errorf("%v", ips)
+	}
+}
+
+func TestSortMX(t *testing.T) {
+	rrs := RRs{
+		{"example.com.", TYPE_MX, CLASS_IN, 3600, &MX{20, "b.example.com."}},
+		{"example.com.", TYPE_A, CLASS_IN, 3600, &A{net.ParseIP("192.0.2.1")}},
+		{"example.com.", TYPE_MX, CLASS_IN, 3600, &MX{10, "a.example.com."}},
+		{"example.com.", TYPE_MX, CLASS_IN, 3600, &MX{20, "c.example.com."}},
+		{"example.com.", TYPE_MX, CLASS_IN, 3600, &MX{0, "d.example.com."}},
+	}
+	y := rrs.SortMX()
+	var a []string
+	for _, v := range y {
+		a = append(a, v.RData.(*MX).Exchange)
+	}
+	if g, e := strings.Join(a, " "), "d.example.com. a.example.com. b.example.com. c.example.com."; g != e {
+		t.Errorf("%q != %q", g, e)
+	}
+
+	if g, e := rrs[0].RData.(*MX).Exchange, "b.example.com."; g != e {
+		t.Errorf("%q != %q", g, e)
+	}
+}
+
+func TestSelectSRV(t *testing.T) {
+	srv := func(prio, weight uint16, target string) *RR {
+		return &RR{"_sip._tcp.example.com.", TYPE_SRV, CLASS_IN, 3600, &SRV{prio, weight, 5060, target}}
+	}
+	rrs := RRs{
+		srv(20, 0, "backup.example.com."),
+		srv(10, 60, "big.example.com."),
+		srv(10, 20, "small1.example.com."),
+		srv(10, 10, "small2.example.com."),
+		srv(10, 10, "small3.example.com."),
+		srv(30, 5, "."),
+		{"example.com.", TYPE_A, CLASS_IN, 3600, &A{net.ParseIP("192.0.2.1")}},
+	}
+
+	const n = 20000
+	rnd := rand.New(rand.NewSource(42))
+	first := map[string]int{}
+	for i := 0; i < n; i++ {
+		y := rrs.SelectSRV(rnd)
+		if g, e := len(y), 5; g != e {
+			t.Fatalf("%d != %d", g, e)
+		}
+
+		seen := map[string]bool{}
+		for j, v := range y {
+			x := v.RData.(*SRV)
+			if j != 0 && x.Priority < y[j-1].RData.(*SRV).Priority {
+				t.Fatalf("%d: priorities not ordered", i)
+			}
+
+			seen[x.Target] = true
+		}
+		if g, e := len(seen), 5; g != e {
+			t.Fatalf("%d != %d", g, e)
+		}
+
+		if g, e := y[4].RData.(*SRV).Target, "backup.example.com."; g != e {
+			t.Fatalf("%q != %q", g, e)
+		}
+
+		first[y[0].RData.(*SRV).Target]++
+	}
+
+	for target, weight := range map[string]int{
+		"big.example.com.":    60,
+		"small1.example.com.": 20,
+		"small2.example.com.": 10,
+		"small3.example.com.": 10,
+	} {
+		g, e := float64(first[target])/n, float64(weight)/100
+		if math.Abs(g-e) > 0.02 {
+			t.Errorf("%s: selected first with probability %.3f, expected %.3f", target, g, e)
+		}
+	}
+
+	// Weight 0 records are selected first only with probability 1/(sum+1).
+	zero := 0
+	rrs = RRs{srv(10, 0, "zero.example.com."), srv(10, 9, "nine.example.com.")}
+	for i := 0; i < n; i++ {
+		if rrs.SelectSRV(rnd)[0].RData.(*SRV).Target == "zero.example.com." {
+			zero++
+		}
+	}
+	if g, e := float64(zero)/n, 0.1; math.Abs(g-e) > 0.02 {
+		t.Errorf("%.3f != %.3f", g, e)
+	}
+
+	if y := (RRs{srv(0, 0, ".")}).SelectSRV(nil); len(y) != 0 {
+		t.Errorf("%v", y)
+	}
+}
+
+func TestGenerateSalt(t *testing.T) {
+	rd, err := NewNSEC3PARAM(0, 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if g, e := rd.HashAlgorithm, HashAlgorithmSHA1; g != e {
+		t.Errorf("%d != %d", g, e)
+	}
+
+	if g, e := len(rd.Salt), 8; g != e {
+		t.Fatalf("%d != %d", g, e)
+	}
+
+	salt := rd.Salt
+	if err = rd.GenerateSalt(8); err != nil {
+		t.Fatal(err)
+	}
+
+	if bytes.Equal(salt, rd.Salt) {
+		t.Errorf("%x: salt not regenerated", salt)
+	}
+
+	for _, n := range []int{0, 255} {
+		if err = rd.GenerateSalt(n); err != nil {
+			t.Fatal(err)
+		}
+
+		if g, e := len(rd.Salt), n; g != e {
+			t.Errorf("%d != %d", g, e)
+		}
+	}
+
+	for _, n := range []int{-1, 256} {
+		if err = rd.GenerateSalt(n); err == nil {
+			t.Errorf("%d: unexpected success", n)
+		}
+
+		if _, err = NewNSEC3PARAM(1, n); err == nil {
+			t.Errorf("%d: unexpected success", n)
+		}
+	}
+
+	if g, e := len(rd.Salt), 255; g != e {
+		t.Errorf("%d != %d", g, e)
+	}
+
+	r := &RR{"example.com.", TYPE_NSEC3PARAM, CLASS_IN, 0, rd}
+	w := dns.NewWirebuf()
+	r.Encode(w)
+	r2 := &RR{}
+	p := 0
+	if err = r2.Decode(w.Buf, &p, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if !r.Equal(r2) {
+		t.Errorf("%s != %s", r, r2)
+	}
+}
+
+func TestExpiringSignatures(t *testing.T) {
+	sig := func(name string, expiration uint32) *RR {
+		return &RR{name, TYPE_RRSIG, CLASS_IN, 3600, &RRSIG{TYPE_A, AlgorithmRSA_SHA1, 2, 3600, expiration, 0, 1, "example.com.", []byte{1}}}
+	}
+	now := time.Unix(1700000000, 0)
+	const day = 24 * time.Hour
+	secs := uint32(now.Unix())
+	rrs := RRs{
+		sig("inside.example.com.", secs+uint32(7*day/time.Second)-1),
+		sig("edge.example.com.", secs+uint32(7*day/time.Second)),
+		sig("outside.example.com.", secs+uint32(7*day/time.Second)+1),
+		sig("now.example.com.", secs),
+		sig("expired.example.com.", secs-1),
+		{"inside.example.com.", TYPE_A, CLASS_IN, 3600, &A{net.ParseIP("192.0.2.1")}},
+	}
+	var a []string
+	for _, v := range rrs.ExpiringSignatures(7*day, now) {
+		a = append(a, v.Name)
+	}
+	if g, e := strings.Join(a, " "), "inside.example.com. edge.example.com. now.example.com."; g != e {
+		t.Errorf("%q != %q", g, e)
+	}
+
+	// The window wraps around 2^32.
+	now = time.Unix(1<<32-60, 0)
+	rrs = RRs{sig("wrapped.example.com.", 30), sig("outside.example.com.", 100), sig("expired.example.com.", 1<<32-61)}
+	if y := rrs.ExpiringSignatures(2*time.Minute, now); len(y) != 1 || y[0].Name != "wrapped.example.com." {
+		t.Errorf("%v", y)
+	}
+}
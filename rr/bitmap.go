@@ -7,49 +7,33 @@
 package rr
 
 import (
+	"bytes"
 	"fmt"
-	"sort"
-	"strings"
+	"strconv"
 )
 
-// TypesEnccode encodes types into bitmap bits (RFC 4034/4.1.2).
+// TypesEnccode encodes types into bitmap bits (RFC 4034/4.1.2). types need
+// not be sorted and may contain duplicates.
 func TypesEncode(types []Type) (bits []byte) {
 	if len(types) == 0 {
 		return
 	}
 
-	m := map[int]bool{}
-	for _, typ := range types {
-		m[int(typ)] = true
-	}
-
-	v := make([]int, len(m))
-	i := 0
-	for typ := range m {
-		v[i] = typ
-		i++
+	var buf [32]Type
+	v := append(buf[:0], types...)
+	for i := 1; i < len(v); i++ { // insertion sort, type lists are short
+		for j := i; j > 0 && v[j] < v[j-1]; j-- {
+			v[j], v[j-1] = v[j-1], v[j]
+		}
 	}
 
-	sort.Ints(v)
-	first, next := 0, 0
 	var blockbits [32]byte
-
-	for first < len(v) {
-
+	for first := 0; first < len(v); {
 		window := v[first] >> 8
-		for next = first + 1; next < len(v); next++ {
-			if v[next]>>8 != window {
-				break
-			}
-		}
-
-		block := v[first:next]
-		for i := range blockbits[:] {
-			blockbits[i] = 0
-		}
-
-		for _, typ := range block {
-			blockbits[(typ&0xFF)>>3] |= (0x80 >> uint(typ&7))
+		next := first
+		for ; next < len(v) && v[next]>>8 == window; next++ {
+			typ := v[next]
+			blockbits[(typ&0xFF)>>3] |= 0x80 >> uint(typ&7)
 		}
 
 		last := 31
@@ -58,20 +42,44 @@ func TypesEncode(types []Type) (bits []byte) {
 
 		bits = append(bits, byte(window), byte(last+1))
 		bits = append(bits, blockbits[:last+1]...)
+		blockbits = [32]byte{}
 		first = next
 	}
-
 	return
 }
 
 // TypesDecode decodes RR Type bitmap bits (RFC 4034/4.1.2).
 func TypesDecode(bits []byte) (types []Type, err error) {
+	var a [32]Type
+	t, err := TypesDecodeInto(bits, a[:])
+	if err != nil {
+		return nil, err
+	}
+
+	if len(t) != 0 {
+		types = append(types, t...)
+	}
+	return
+}
+
+// TypesDecodeInto is like TypesDecode, but the types are appended to
+// out[:0], so no allocations are needed if out has enough capacity.
+func TypesDecodeInto(bits []byte, out []Type) (types []Type, err error) {
+	types = out[:0]
 	p := 0
 	for p < len(bits) {
+		if p+2 > len(bits) {
+			return nil, fmt.Errorf("bitmap decode - buffer underflow")
+		}
+
 		window := int(bits[p]) << 8
 		p++
 		length := int(bits[p])
 		p++
+		if length == 0 || length > 32 {
+			return nil, fmt.Errorf("bitmap decode - invalid bitmap length %d", length)
+		}
+
 		next := p + length
 		if next > len(bits) {
 			return nil, fmt.Errorf("bitmap decode - buffer underflow")
@@ -80,14 +88,11 @@ func TypesDecode(bits []byte) (types []Type, err error) {
 		bitmap := bits[p:next]
 		p = next
 		for ibyte, octet := range bitmap {
-			if octet != 0 {
-				for ibit := 0; ibit < 8; ibit++ {
-					if octet&0x80 != 0 {
-						x := window | (ibyte << 3) | ibit
-						types = append(types, Type(x))
-					}
-					octet <<= 1
+			for ibit := 0; octet != 0; ibit++ {
+				if octet&0x80 != 0 {
+					types = append(types, Type(window|ibyte<<3|ibit))
 				}
+				octet <<= 1
 			}
 		}
 	}
@@ -95,9 +100,37 @@ func TypesDecode(bits []byte) (types []Type, err error) {
 }
 
 func TypesString(types []Type) string {
-	a := []string{}
-	for _, typ := range types {
-		a = append(a, typ.String())
+	var buf bytes.Buffer
+	writeTypes(&buf, types)
+	return buf.String()
+}
+
+func writeTypes(buf *bytes.Buffer, types []Type) {
+	for i, typ := range types {
+		if i != 0 {
+			buf.WriteByte(' ')
+		}
+		if s, ok := Types[typ]; ok {
+			buf.WriteString(s)
+			continue
+		}
+
+		buf.WriteString("TYPE")
+		buf.WriteString(strconv.Itoa(int(typ)))
 	}
-	return strings.Join(a, " ")
+}
+
+// bitmapString returns the presentation form of the type bit maps bits. Bit
+// maps which can't be decoded are rendered in the RFC 3597 generic form, so
+// printing malformed records received from the network is safe.
+func bitmapString(bits []byte) string {
+	var a [32]Type
+	types, err := TypesDecodeInto(bits, a[:])
+	if err != nil {
+		return fmt.Sprintf("\\# %d %x", len(bits), bits)
+	}
+
+	var buf bytes.Buffer
+	writeTypes(&buf, types)
+	return buf.String()
 }
@@ -0,0 +1,44 @@
+// Copyright (c) 2011 CZ.NIC z.s.p.o. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// blame: jnml, labs.nic.cz
+
+package rr
+
+import (
+	"time"
+)
+
+// CachedRR is a resource record held in a cache since Inserted.
+type CachedRR struct {
+	RR       *RR
+	Inserted time.Time
+}
+
+// Expires returns the time when c expires, i.e. Inserted plus the TTL of the
+// RR.
+func (c CachedRR) Expires() time.Time {
+	return c.Inserted.Add(time.Duration(c.RR.TTL) * time.Second)
+}
+
+// Expired reports whether c has expired at now. A record is expired from the
+// moment its TTL elapses, the same as in NegativeCache.
+func (c CachedRR) Expired(now time.Time) bool {
+	return !now.Before(c.Expires())
+}
+
+// RemainingTTL returns the TTL of c at now, i.e. the TTL of the RR reduced by
+// the whole seconds elapsed since Inserted. The result is zero if c has
+// expired.
+func (c CachedRR) RemainingTTL(now time.Time) int32 {
+	if c.Expired(now) {
+		return 0
+	}
+
+	elapsed := now.Sub(c.Inserted)
+	if elapsed < 0 {
+		elapsed = 0
+	}
+	return c.RR.TTL - int32(elapsed/time.Second)
+}
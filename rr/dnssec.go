@@ -0,0 +1,927 @@
+// Copyright (c) 2011 CZ.NIC z.s.p.o. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// blame: jnml, labs.nic.cz
+
+package rr
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/dsa"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	_ "crypto/sha512"
+	"fmt"
+	"github.com/cznic/dns"
+	"github.com/cznic/strutil"
+	"math/big"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Now returns the current time used by the signature validity period checks.
+// Tests may replace it to get deterministic results.
+var Now = time.Now
+
+// canonicalRData returns the canonical form of rd (RFC 4034/6.2): the
+// uncompressed wire format with domain names of the RR types listed in RFC
+// 4034/6.2, as amended by RFC 6840/5.1, converted to lower case.
+func canonicalRData(rd dns.Wirer) []byte {
+	lower := dns.CanonicalName
+	switch x := rd.(type) {
+	case *AFSDB:
+		y := *x
+		y.Hostname = lower(y.Hostname)
+		rd = &y
+	case *CNAME:
+		rd = &CNAME{lower(x.Name)}
+	case *DNAME:
+		rd = &DNAME{lower(x.Name)}
+	case *KX:
+		y := *x
+		y.Exchanger = lower(y.Exchanger)
+		rd = &y
+	case *MB:
+		rd = &MB{lower(x.MADNAME)}
+	case *MD:
+		rd = &MD{lower(x.MADNAME)}
+	case *MF:
+		rd = &MF{lower(x.MADNAME)}
+	case *MG:
+		rd = &MG{lower(x.MGNAME)}
+	case *MINFO:
+		rd = &MINFO{lower(x.RMAILBX), lower(x.EMAILBX)}
+	case *MR:
+		rd = &MR{lower(x.NEWNAME)}
+	case *MX:
+		rd = &MX{x.Preference, lower(x.Exchange)}
+	case *NAPTR:
+		y := *x
+		y.Replacement = lower(y.Replacement)
+		rd = &y
+	case *NS:
+		rd = &NS{lower(x.NSDName)}
+	case *PTR:
+		rd = &PTR{lower(x.PTRDName)}
+	case *PX:
+		y := *x
+		y.MAP822, y.MAPX400 = lower(y.MAP822), lower(y.MAPX400)
+		rd = &y
+	case *RP:
+		rd = &RP{lower(x.Mbox), lower(x.Txt)}
+	case *RRSIG:
+		y := *x
+		y.Name = lower(y.Name)
+		rd = &y
+	case *RT:
+		y := *x
+		y.Hostname = lower(y.Hostname)
+		rd = &y
+	case *SIG:
+		y := *x
+		y.Name = lower(y.Name)
+		rd = &y
+	case *SOA:
+		y := *x
+		y.MName, y.RName = lower(y.MName), lower(y.RName)
+		rd = &y
+	case *SRV:
+		y := *x
+		y.Target = lower(y.Target)
+		rd = &y
+	}
+	return wireBytes(rd)
+}
+
+// CanonicalRData returns the canonical form (RFC 4034/6.2) of d, the RData of
+// a resource record of type t. If d is a *RDATA, i.e. in the generic form of
+// RFC 3597, it's first decoded as RData of type t, if supported, so the names
+// embedded in RData of the RR types listed in RFC 4034/6.2 are converted to
+// lower case as well.
+func CanonicalRData(d dns.Wirer, t Type) []byte {
+	if x, ok := d.(*RDATA); ok && len(*x) != 0 {
+		rd := newRData(t)
+		if _, generic := rd.(*RDATA); !generic {
+			pos := 0
+			if err := rd.Decode(*x, &pos, nil); err == nil && pos == len(*x) {
+				d = rd
+			}
+		}
+	}
+	return canonicalRData(d)
+}
+
+// SignedData returns the data covered by sig for rrset as defined in RFC
+// 4034/3.1.8.1, i.e. the RRSIG RDATA excluding the Signature field followed
+// by the RRs of rrset in canonical form (RFC 4034/6.2) and canonical order
+// (RFC 4034/6.3). The owner name is expanded to its wildcard form if
+// sig.Labels is less than the number of labels of the owner name. Duplicate
+// RRs are included only once.
+//
+// All records in rrset must have the same owner name, class and type and the
+// type must match sig.Type.
+func (sig *RRSIG) SignedData(rrset RRs) (data []byte, err error) {
+	if len(rrset) == 0 {
+		return nil, fmt.Errorf("(*RRSIG).SignedData: empty RRset")
+	}
+
+	r0 := rrset[0]
+	owner := dns.CanonicalName(r0.Name)
+	for _, v := range rrset {
+		if v.Type != sig.Type {
+			return nil, fmt.Errorf("(*RRSIG).SignedData: RRSIG covers %s, got %s", sig.Type, v.Type)
+		}
+
+		if v.Class != r0.Class || dns.CanonicalName(v.Name) != owner {
+			return nil, fmt.Errorf("(*RRSIG).SignedData: not an RRset, %s %s != %s %s", r0.Name, r0.Class, v.Name, v.Class)
+		}
+	}
+
+	labels := nameLabels(owner)
+	n := dns.CountLabels(owner)
+	switch m := int(sig.Labels); {
+	case m > n:
+		return nil, fmt.Errorf("(*RRSIG).SignedData: RRSIG labels %d > %d labels of %s", m, n, r0.Name)
+	case m < n:
+		owner = "*." + strings.Join(append(labels[len(labels)-m:], ""), ".")
+	}
+
+	b := dns.NewWirebuf()
+	b.DisableCompression()
+	s := *sig
+	s.Name, s.Signature = dns.CanonicalName(s.Name), nil
+	s.Encode(b)
+
+	rdata := make([][]byte, len(rrset))
+	for i, v := range rrset {
+		rdata[i] = CanonicalRData(v.RData, v.Type)
+	}
+	sort.Sort(byteSlices(rdata))
+
+	for i, rd := range rdata {
+		if i != 0 && bytes.Equal(rd, rdata[i-1]) {
+			continue
+		}
+
+		dns.DomainName(owner).Encode(b)
+		dns.Octets2(r0.Type).Encode(b)
+		dns.Octets2(r0.Class).Encode(b)
+		dns.Octets4(sig.TTL).Encode(b)
+		dns.Octets2(len(rd)).Encode(b)
+		b.Buf = append(b.Buf, rd...)
+	}
+	return b.Buf, nil
+}
+
+// CoveredBy returns the records of r covered by the RRSIG resource record
+// sig, i.e. those having the owner name and class of sig and the type sig
+// covers, in canonical order (RFC 4034/6.3). If sig is the result of a
+// wildcard expansion (RFC 4035/5.3.4), i.e. its Labels field is less than the
+// number of labels of its owner name, and r has no covered records at the
+// owner name, the records at the wildcard owner name reconstructed from the
+// Labels field are returned. CoveredBy returns nil if sig is not an RRSIG.
+func (r RRs) CoveredBy(sig *RR) (y RRs) {
+	rd, ok := sig.RData.(*RRSIG)
+	if !ok {
+		return
+	}
+
+	owner := dns.CanonicalName(sig.Name)
+	wildcard := ""
+	if m, labels := int(rd.Labels), nameLabels(owner); m < dns.CountLabels(owner) {
+		wildcard = "*." + strings.Join(append(labels[len(labels)-m:], ""), ".")
+	}
+
+	var w RRs
+	for _, v := range r {
+		if v.Type != rd.Type || v.Class != sig.Class {
+			continue
+		}
+
+		switch dns.CanonicalName(v.Name) {
+		case owner:
+			y = append(y, v)
+		case wildcard:
+			w = append(w, v)
+		}
+	}
+	if len(y) == 0 {
+		y = w
+	}
+	sort.Sort(Sorter{y, func(a, b *RR) int {
+		return bytes.Compare(CanonicalRData(a.RData, a.Type), CanonicalRData(b.RData, b.Type))
+	}})
+	return
+}
+
+// ValidAt reports whether t is within the signature validity period of rd,
+// i.e. not before rd.Inception and not after rd.Expiration. The comparisons
+// use the RFC 1982 serial number arithmetic (RFC 4034/3.1.5).
+func (rd *RRSIG) ValidAt(t time.Time) bool {
+	secs := uint32(t.Unix())
+	return !SerialLess(secs, rd.Inception) && !SerialLess(rd.Expiration, secs)
+}
+
+// Valid is ValidAt(Now()).
+func (rd *RRSIG) Valid() bool {
+	return rd.ValidAt(Now())
+}
+
+// ExpiringSignatures returns the RRSIG records of r expiring within the
+// duration within from now, i.e. having an Expiration not before now and not
+// after now+within. The comparisons use the RFC 1982 serial number arithmetic
+// (RFC 4034/3.1.5), so within must be less than 2^31 seconds. Signatures
+// which have expired already are not included, see RRSIG.ValidAt.
+func (r RRs) ExpiringSignatures(within time.Duration, now time.Time) (y RRs) {
+	from := uint32(now.Unix())
+	to := from + uint32(within/time.Second)
+	for _, rec := range r {
+		if x, ok := rec.RData.(*RRSIG); ok && !SerialLess(x.Expiration, from) && !SerialLess(to, x.Expiration) {
+			y = append(y, rec)
+		}
+	}
+	return
+}
+
+// SignedData returns the data covered by the SIG(0) transaction signature rd
+// of msg (RFC 2931/3.1): the RDATA of rd, excluding the signature and with
+// the signer's name in canonical form, followed by msg. msg is the DNS message
+// in wire format before the SIG(0) RR was appended, i.e. without it counted
+// in ARCOUNT. When verifying a response to a signed request, msg must be the
+// request, including its SIG(0) RR, followed by the response.
+func (rd *SIG) SignedData(msg []byte) []byte {
+	x := *rd
+	x.Name = dns.CanonicalName(x.Name)
+	x.Signature = nil
+	return append(wireBytes(&x), msg...)
+}
+
+// Verify checks that rd is a valid SIG(0) transaction signature of msg, as
+// defined by SignedData, made by the private key of key. The algorithm and key
+// tag of rd must match key and the time returned by Now must be within the
+// validity period of rd. Keys with the KEY_NOAUTH flag set, including the
+// NOKEY ones, can't be used for authentication (RFC 2535/3.1.2) and are
+// rejected. Supported algorithms are those of (*RRSIG).Verify.
+func (rd *SIG) Verify(key *KEY, msg []byte) (err error) {
+	dnskey := (*DNSKEY)(key)
+	switch {
+	case rd.Type != 0:
+		return fmt.Errorf("(*SIG).Verify: type covered %s is not zero", rd.Type)
+	case key.NoKey():
+		return fmt.Errorf("(*SIG).Verify: key flags %#04x, no key", key.Flags)
+	case key.Flags&KEY_NOAUTH != 0:
+		return fmt.Errorf("(*SIG).Verify: key flags %#04x, use for authentication prohibited", key.Flags)
+	case rd.Algorithm != key.Algorithm:
+		return fmt.Errorf("(*SIG).Verify: algorithm %d, key algorithm %d", rd.Algorithm, key.Algorithm)
+	case rd.KeyTag != dnskey.KeyTag():
+		return fmt.Errorf("(*SIG).Verify: key tag %d, key has key tag %d", rd.KeyTag, dnskey.KeyTag())
+	case !(*RRSIG)(rd).Valid():
+		return fmt.Errorf("(*SIG).Verify: signature not valid at %s", Now().UTC())
+	}
+
+	if err = verifySignature(dnskey, rd.Algorithm, rd.SignedData(msg), rd.Signature); err != nil {
+		return fmt.Errorf("(*SIG).Verify: %s", err)
+	}
+
+	return
+}
+
+type byteSlices [][]byte
+
+func (s byteSlices) Len() int           { return len(s) }
+func (s byteSlices) Less(i, j int) bool { return bytes.Compare(s[i], s[j]) < 0 }
+func (s byteSlices) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+
+// KeyTag returns the key tag of rd as defined in RFC 4034/Appendix B.
+func (rd *DNSKEY) KeyTag() uint16 {
+	b := wireBytes(rd)
+	if rd.Algorithm == AlgorithmRSA_MD5 {
+		if len(b) < 4 {
+			return 0
+		}
+
+		return uint16(b[len(b)-3])<<8 | uint16(b[len(b)-2])
+	}
+
+	var ac uint32
+	for i, v := range b {
+		if i&1 == 0 {
+			ac += uint32(v) << 8
+		} else {
+			ac += uint32(v)
+		}
+	}
+	ac += ac >> 16 & 0xFFFF
+	return uint16(ac)
+}
+
+// ToDS returns the SHA-1 DS RData (RFC 4034/5.1.4) of the DNSKEY RData rd
+// owned by owner.
+func (rd *DNSKEY) ToDS(owner string) *DS {
+	b := dns.NewWirebuf()
+	b.DisableCompression()
+	dns.DomainName(dns.CanonicalName(owner)).Encode(b)
+	rd.Encode(b)
+	h := sha1.New()
+	h.Write(b.Buf)
+	return &DS{rd.KeyTag(), rd.Algorithm, HashAlgorithmSHA1, h.Sum(nil)}
+}
+
+// parseDSAKey returns the DSA public key in the RFC 2536/2 format found in
+// key: the octet T, followed by Q (20 octets), P, G and Y (64 + T*8 octets
+// each).
+func parseDSAKey(key []byte) (k *dsa.PublicKey, err error) {
+	if len(key) == 0 {
+		return nil, fmt.Errorf("parseDSAKey: missing key data")
+	}
+
+	t := int(key[0])
+	if t > 8 {
+		return nil, fmt.Errorf("parseDSAKey: invalid T %d", t)
+	}
+
+	n := 64 + 8*t
+	if g, e := len(key), 1+20+3*n; g != e {
+		return nil, fmt.Errorf("parseDSAKey: key length %d, expected %d", g, e)
+	}
+
+	key = key[1:]
+	next := func(n int) (x *big.Int) {
+		x, key = new(big.Int).SetBytes(key[:n]), key[n:]
+		return
+	}
+
+	k = &dsa.PublicKey{}
+	k.Q = next(20)
+	k.P = next(n)
+	k.G = next(n)
+	k.Y = next(n)
+	return
+}
+
+// RSAPublicKey returns the RSA public key held by rd in the RFC 3110/2 format:
+// the exponent length as one octet or, if it's zero, as the next two octets,
+// followed by the exponent and the modulus.
+func (rd *DNSKEY) RSAPublicKey() (k *rsa.PublicKey, err error) {
+	switch rd.Algorithm {
+	case AlgorithmRSA_MD5, AlgorithmRSA_SHA1, AlgorithmRSA_SHA1_NSEC3_SHA1, AlgorithmRSA_SHA256, AlgorithmRSA_SHA512:
+	default:
+		return nil, fmt.Errorf("(*DNSKEY).RSAPublicKey: not a RSA key, algorithm %d", rd.Algorithm)
+	}
+
+	b := rd.Key
+	if len(b) == 0 {
+		return nil, fmt.Errorf("(*DNSKEY).RSAPublicKey: missing key data")
+	}
+
+	n, b := int(b[0]), b[1:]
+	if n == 0 {
+		if len(b) < 2 {
+			return nil, fmt.Errorf("(*DNSKEY).RSAPublicKey: truncated exponent length")
+		}
+
+		n, b = int(b[0])<<8|int(b[1]), b[2:]
+	}
+	switch {
+	case n == 0:
+		return nil, fmt.Errorf("(*DNSKEY).RSAPublicKey: zero exponent length")
+	case n >= len(b):
+		return nil, fmt.Errorf("(*DNSKEY).RSAPublicKey: exponent length %d, key data %d", n, len(b))
+	}
+
+	e := new(big.Int).SetBytes(b[:n])
+	if !e.IsInt64() || e.Int64() > 1<<31-1 || e.Sign() == 0 {
+		return nil, fmt.Errorf("(*DNSKEY).RSAPublicKey: unsupported exponent %s", e)
+	}
+
+	return &rsa.PublicKey{N: new(big.Int).SetBytes(b[n:]), E: int(e.Int64())}, nil
+}
+
+// ECDSAPublicKey returns the ECDSA public key held by rd in the RFC 6605/4
+// format, i.e. the uncompressed point X | Y on the P-256 or P-384 curve.
+func (rd *DNSKEY) ECDSAPublicKey() (k *ecdsa.PublicKey, err error) {
+	var curve elliptic.Curve
+	switch rd.Algorithm {
+	case AlgorithmECDSA_P256_SHA256:
+		curve = elliptic.P256()
+	case AlgorithmECDSA_P384_SHA384:
+		curve = elliptic.P384()
+	default:
+		return nil, fmt.Errorf("(*DNSKEY).ECDSAPublicKey: not an ECDSA key, algorithm %d", rd.Algorithm)
+	}
+
+	n := (curve.Params().BitSize + 7) / 8
+	if g, e := len(rd.Key), 2*n; g != e {
+		return nil, fmt.Errorf("(*DNSKEY).ECDSAPublicKey: key length %d, expected %d", g, e)
+	}
+
+	k = &ecdsa.PublicKey{
+		Curve: curve,
+		X:     new(big.Int).SetBytes(rd.Key[:n]),
+		Y:     new(big.Int).SetBytes(rd.Key[n:]),
+	}
+	if !curve.IsOnCurve(k.X, k.Y) {
+		return nil, fmt.Errorf("(*DNSKEY).ECDSAPublicKey: invalid point")
+	}
+
+	return
+}
+
+// Verify checks that sig is a valid signature of rrset made by the DNSKEY
+// resource record key (RFC 4035/5.3). The signer's name, algorithm and key tag
+// of sig must match key, the time returned by Now must be within the validity
+// period of sig. Supported algorithms are those of SignRRSet, DSA,
+// DSA-NSEC3-SHA1, ECDSAP256SHA256 and ECDSAP384SHA384.
+func (sig *RRSIG) Verify(key *RR, rrset RRs) (err error) {
+	dnskey, ok := key.RData.(*DNSKEY)
+	if !ok {
+		return fmt.Errorf("(*RRSIG).Verify: %T is not a DNSKEY", key.RData)
+	}
+
+	switch {
+	case !dns.NamesEqual(sig.Name, key.Name):
+		return fmt.Errorf("(*RRSIG).Verify: signer %s, key owner %s", sig.Name, key.Name)
+	case sig.Algorithm != dnskey.Algorithm:
+		return fmt.Errorf("(*RRSIG).Verify: algorithm %d, key algorithm %d", sig.Algorithm, dnskey.Algorithm)
+	case sig.KeyTag != dnskey.KeyTag():
+		return fmt.Errorf("(*RRSIG).Verify: key tag %d, key has key tag %d", sig.KeyTag, dnskey.KeyTag())
+	case !sig.Valid():
+		return fmt.Errorf("(*RRSIG).Verify: signature not valid at %s", Now().UTC())
+	}
+
+	data, err := sig.SignedData(rrset)
+	if err != nil {
+		return
+	}
+
+	if err = verifySignature(dnskey, sig.Algorithm, data, sig.Signature); err != nil {
+		return fmt.Errorf("(*RRSIG).Verify: %s", err)
+	}
+
+	return
+}
+
+// VerifyRRSet returns the first RRSIG of sigs which is a valid signature of
+// rrset made by a DNSKEY of keys (RFC 4035/5.3). Signatures not covering
+// rrset, not valid at the time returned by Now and keys without the Zone Key
+// flag are ignored. Every signature is checked against all keys matching its
+// signer's name, algorithm and key tag, as key tags are not unique. If no
+// signature verifies, the errors of all attempts are returned.
+func VerifyRRSet(rrset RRs, sigs RRs, keys RRs) (sig *RRSIG, err error) {
+	if len(rrset) == 0 {
+		return nil, fmt.Errorf("VerifyRRSet: empty RRset")
+	}
+
+	var errs []string
+	r0 := rrset[0]
+	for _, s := range sigs {
+		rd, ok := s.RData.(*RRSIG)
+		if !ok || rd.Type != r0.Type || s.Class != r0.Class || !dns.NamesEqual(s.Name, r0.Name) {
+			continue
+		}
+
+		if !rd.Valid() {
+			errs = append(errs, fmt.Sprintf("key tag %d: signature not valid at %s", rd.KeyTag, Now().UTC()))
+			continue
+		}
+
+		for _, key := range keys {
+			k, ok := key.RData.(*DNSKEY)
+			if !ok || k.Flags&DNSKEY_ZONE == 0 || k.Algorithm != rd.Algorithm || k.KeyTag() != rd.KeyTag ||
+				!dns.NamesEqual(key.Name, rd.Name) {
+				continue
+			}
+
+			if err = rd.Verify(key, rrset); err == nil {
+				return rd, nil
+			}
+
+			errs = append(errs, fmt.Sprintf("key tag %d: %s", rd.KeyTag, err))
+		}
+	}
+	if len(errs) == 0 {
+		return nil, fmt.Errorf("VerifyRRSet: no matching RRSIG and DNSKEY")
+	}
+
+	return nil, fmt.Errorf("VerifyRRSet: %s", strings.Join(errs, "; "))
+}
+
+// verifySignature checks that signature is a valid signature of data made by
+// the private key of dnskey using algorithm alg.
+func verifySignature(dnskey *DNSKEY, alg AlgorithmType, data, signature []byte) (err error) {
+	switch alg {
+	case AlgorithmDSA_SHA1, AlgorithmDSA_NSEC3_SHA1:
+		return verifyDSA(dnskey, data, signature)
+	}
+
+	h, err := algorithmHash(alg)
+	if err != nil {
+		return
+	}
+
+	hash := h.New()
+	hash.Write(data)
+	digest := hash.Sum(nil)
+	switch alg {
+	case AlgorithmECDSA_P256_SHA256, AlgorithmECDSA_P384_SHA384:
+		pub, err := dnskey.ECDSAPublicKey()
+		if err != nil {
+			return err
+		}
+
+		if len(signature) != len(dnskey.Key) {
+			return fmt.Errorf("signature length %d, expected %d", len(signature), len(dnskey.Key))
+		}
+
+		n := len(signature) / 2
+		r, s := new(big.Int).SetBytes(signature[:n]), new(big.Int).SetBytes(signature[n:])
+		if !ecdsa.Verify(pub, digest, r, s) {
+			return fmt.Errorf("invalid signature")
+		}
+	default:
+		pub, err := dnskey.RSAPublicKey()
+		if err != nil {
+			return err
+		}
+
+		return rsa.VerifyPKCS1v15(pub, h, digest, signature)
+	}
+	return
+}
+
+// verifyDSA checks that signature, in the RFC 2536/3 format T, R, S, is a
+// valid DSA signature of the SHA-1 digest of data made by the private key of
+// dnskey.
+func verifyDSA(dnskey *DNSKEY, data, signature []byte) (err error) {
+	pub, err := parseDSAKey(dnskey.Key)
+	if err != nil {
+		return
+	}
+
+	if g, e := len(signature), 1+2*20; g != e {
+		return fmt.Errorf("signature length %d, expected %d", g, e)
+	}
+
+	digest := sha1.Sum(data)
+	r, s := new(big.Int).SetBytes(signature[1:21]), new(big.Int).SetBytes(signature[21:])
+	if !dsa.Verify(pub, digest[:], r, s) {
+		return fmt.Errorf("invalid signature")
+	}
+
+	return
+}
+
+// algorithmHash returns the hash function used by signature algorithm a.
+func algorithmHash(a AlgorithmType) (h crypto.Hash, err error) {
+	switch a {
+	case AlgorithmRSA_SHA1, AlgorithmRSA_SHA1_NSEC3_SHA1:
+		return crypto.SHA1, nil
+	case AlgorithmRSA_SHA256, AlgorithmECDSA_P256_SHA256:
+		return crypto.SHA256, nil
+	case AlgorithmECDSA_P384_SHA384:
+		return crypto.SHA384, nil
+	case AlgorithmRSA_SHA512:
+		return crypto.SHA512, nil
+	}
+	return 0, fmt.Errorf("unsupported signature algorithm %d", a)
+}
+
+// SignRRSet returns an RRSIG for rrset made using the DNSKEY resource record
+// key and its private key priv. The RRSIG owner name is the owner name of
+// rrset and the signer's name is the owner name of key. Supported algorithms
+// are RSASHA1, RSASHA1-NSEC3-SHA1, RSASHA256 and RSASHA512, priv must be a
+// *rsa.PrivateKey.
+//
+// The Labels field doesn't count a leading wildcard label of the owner name
+// (RFC 4035/2.2). All records of rrset must have the same owner name, class,
+// type and TTL.
+func SignRRSet(rrset RRs, key *RR, priv crypto.PrivateKey, inception, expiration uint32) (sig *RRSIG, err error) {
+	if len(rrset) == 0 {
+		return nil, fmt.Errorf("SignRRSet: empty RRset")
+	}
+
+	dnskey, ok := key.RData.(*DNSKEY)
+	if !ok {
+		return nil, fmt.Errorf("SignRRSet: %T is not a DNSKEY", key.RData)
+	}
+
+	h, err := algorithmHash(dnskey.Algorithm)
+	if err != nil {
+		return nil, fmt.Errorf("SignRRSet: %s", err)
+	}
+
+	rsakey, ok := priv.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("SignRRSet: unsupported private key %T", priv)
+	}
+
+	if err = rrset.CheckTTL(); err != nil {
+		return nil, fmt.Errorf("SignRRSet: %s", err)
+	}
+
+	r0 := rrset[0]
+	sig = &RRSIG{
+		Type:       r0.Type,
+		Algorithm:  dnskey.Algorithm,
+		Labels:     byte(dns.CountLabels(r0.Name)),
+		TTL:        r0.TTL,
+		Expiration: expiration,
+		Inception:  inception,
+		KeyTag:     dnskey.KeyTag(),
+		Name:       dns.CanonicalName(key.Name),
+	}
+	data, err := sig.SignedData(rrset)
+	if err != nil {
+		return nil, err
+	}
+
+	hash := h.New()
+	hash.Write(data)
+	if sig.Signature, err = rsa.SignPKCS1v15(rand.Reader, rsakey, h, hash.Sum(nil)); err != nil {
+		return nil, err
+	}
+
+	return
+}
+
+// Sign returns r followed by an RRSIG resource record for every RRset of r
+// made by SignRRSet using the DNSKEY resource record key and its private key
+// priv. Existing RRSIGs, meta and pseudo type records are not signed.
+func (r RRs) Sign(key *RR, priv crypto.PrivateKey, inception, expiration uint32) (y RRs, err error) {
+	y = append(y, r...)
+	for _, set := range r.RRsets() {
+		r0 := set[0]
+		if r0.Type == TYPE_RRSIG || !r0.Type.IsData() {
+			continue
+		}
+
+		sig, err := SignRRSet(set, key, priv, inception, expiration)
+		if err != nil {
+			return nil, err
+		}
+
+		y = append(y, &RR{r0.Name, TYPE_RRSIG, r0.Class, r0.TTL, sig})
+	}
+	return
+}
+
+// isSubdomain reports whether name is below parent. The names are compared
+// case-insensitively.
+func isSubdomain(name, parent string) bool {
+	ln, lp := nameLabels(name), nameLabels(parent)
+	if len(ln) <= len(lp) {
+		return false
+	}
+
+	for i, v := range lp {
+		if ln[len(ln)-len(lp)+i] != v {
+			return false
+		}
+	}
+	return true
+}
+
+// zoneNode collects the records of an owner name of a zone.
+type zoneNode struct {
+	name  string
+	class Class
+	types []Type
+}
+
+// zoneNodes returns the authoritative owner names of the zone r in canonical
+// order (RFC 4034/6.1) together with their types, pseudo type records
+// excluded. Names below a delegation point, e.g. glue, are not authoritative.
+// The delegation points are reported in cuts. The zone apex is the owner of
+// the SOA resource record, soa is nil if there's none.
+func zoneNodes(r RRs) (nodes []*zoneNode, cuts map[string]bool, soa *RR) {
+	index := map[string]*zoneNode{}
+	for _, v := range r {
+		if v.Type.IsPseudo() {
+			continue
+		}
+
+		k := dns.CanonicalName(v.Name)
+		n := index[k]
+		if n == nil {
+			n = &zoneNode{name: v.Name, class: v.Class}
+			index[k] = n
+			nodes = append(nodes, n)
+		}
+		n.types = append(n.types, v.Type)
+		if v.Type == TYPE_SOA && soa == nil {
+			soa = v
+		}
+	}
+
+	cuts = map[string]bool{}
+	for k, n := range index {
+		for _, t := range n.types {
+			if t == TYPE_NS && (soa == nil || k != dns.CanonicalName(soa.Name)) {
+				cuts[k] = true
+			}
+		}
+	}
+
+	w := 0
+	for _, n := range nodes {
+		below := false
+		for cut := range cuts {
+			if isSubdomain(n.name, cut) {
+				below = true
+				break
+			}
+		}
+		if !below {
+			nodes[w] = n
+			w++
+		}
+	}
+	nodes = nodes[:w]
+	sort.Sort(zoneNodesSorter(nodes))
+	return
+}
+
+type zoneNodesSorter []*zoneNode
+
+func (s zoneNodesSorter) Len() int           { return len(s) }
+func (s zoneNodesSorter) Less(i, j int) bool { return compareCanonicalNames(s[i].name, s[j].name) < 0 }
+func (s zoneNodesSorter) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+
+// GenerateNSEC returns the NSEC chain (RFC 4034/4) of the zone r: a NSEC
+// resource record for every authoritative owner name of r, linking it to the
+// next owner name in canonical order, the last one linking to the first one,
+// i.e. to the zone apex. The type bit maps list the types present at the owner
+// name plus NSEC and RRSIG. The NSEC TTL is taken from the SOA resource record
+// of r by NegativeTTL, it's zero if r has no SOA. Names below a delegation
+// point, like glue, get no NSEC and are not linked.
+func GenerateNSEC(r RRs) (y RRs) {
+	nodes, _, soa := zoneNodes(r)
+	var ttl int32
+	if soa != nil {
+		ttl, _ = NegativeTTL(soa)
+	}
+	for i, n := range nodes {
+		next := nodes[(i+1)%len(nodes)]
+		types := append([]Type{TYPE_NSEC, TYPE_RRSIG}, n.types...)
+		y = append(y, &RR{n.name, TYPE_NSEC, n.class, ttl, &NSEC{next.name, TypesEncode(types)}})
+	}
+	return
+}
+
+// MaxNSEC3Iterations is the highest NSEC3 iterations count HashName accepts.
+// Every iteration costs a hash computation, so high counts in records
+// received from the network make validators vulnerable to denial of service
+// (RFC 9276/3.2). The default follows common validator implementations.
+var MaxNSEC3Iterations uint16 = 150
+
+// CheckLimits returns an error if the iterations count of rd exceeds
+// maxIterations. RFC 9276/3.1 recommends zero additional iterations.
+func (rd *NSEC3PARAM) CheckLimits(maxIterations uint16) error {
+	if rd.Iterations > maxIterations {
+		return fmt.Errorf("(*NSEC3PARAM).CheckLimits: %d iterations > %d", rd.Iterations, maxIterations)
+	}
+
+	return nil
+}
+
+// HashName returns the NSEC3 hash (RFC 5155/5) of name using the hash
+// algorithm, iterations and salt of rd. Iterations counts above
+// MaxNSEC3Iterations are rejected.
+func (rd *NSEC3PARAM) HashName(name string) (h []byte, err error) {
+	if err = rd.CheckLimits(MaxNSEC3Iterations); err != nil {
+		return nil, fmt.Errorf("(*NSEC3PARAM).HashName: %d iterations > MaxNSEC3Iterations", rd.Iterations)
+	}
+
+	return rd.hashName(name)
+}
+
+// hashName is HashName without the iterations limit.
+func (rd *NSEC3PARAM) hashName(name string) (h []byte, err error) {
+	if rd.HashAlgorithm != HashAlgorithmSHA1 {
+		return nil, fmt.Errorf("(*NSEC3PARAM).HashName: unsupported hash algorithm %d", rd.HashAlgorithm)
+	}
+
+	b := dns.NewWirebuf()
+	b.DisableCompression()
+	dns.DomainName(dns.CanonicalName(name)).Encode(b)
+	h = b.Buf
+	for i := 0; i <= int(rd.Iterations); i++ {
+		x := sha1.New()
+		x.Write(h)
+		x.Write(rd.Salt)
+		h = x.Sum(nil)
+	}
+	return
+}
+
+// GenerateSalt sets the salt of rd to n random octets read from crypto/rand.
+// The salt field length is one octet, so n must be in 0 to 255; n == 0 means
+// no salt.
+func (rd *NSEC3PARAM) GenerateSalt(n int) (err error) {
+	if n < 0 || n > 255 {
+		return fmt.Errorf("(*NSEC3PARAM).GenerateSalt: invalid salt length %d", n)
+	}
+
+	salt := make([]byte, n)
+	if _, err = rand.Read(salt); err != nil {
+		return fmt.Errorf("(*NSEC3PARAM).GenerateSalt: %s", err)
+	}
+
+	rd.Salt = salt
+	return
+}
+
+// NewNSEC3PARAM returns NSEC3PARAM RData using SHA-1 hashing, the given
+// number of additional iterations and a random salt of saltLen octets, see
+// GenerateSalt. The flags are zero, the Opt-Out flag is set by GenerateNSEC3
+// when requested.
+func NewNSEC3PARAM(iterations uint16, saltLen int) (rd *NSEC3PARAM, err error) {
+	rd = &NSEC3PARAM{HashAlgorithm: HashAlgorithmSHA1, Iterations: iterations}
+	if err = rd.GenerateSalt(saltLen); err != nil {
+		return nil, err
+	}
+
+	return
+}
+
+// GenerateNSEC3 returns the NSEC3 chain (RFC 5155/7.1) of the zone r using
+// params: a NSEC3 resource record for every authoritative owner name and empty
+// non-terminal of r, owned by the hashed name under the zone apex and linking
+// to the next hashed owner name in hash order. The type bit maps list the
+// types present at the original owner name plus RRSIG, unless the name is an
+// empty non-terminal or an insecure delegation, i.e. one with no DS RRset. If
+// optOut is true, the Opt-Out flag is set and insecure delegations get no
+// NSEC3 resource record. The NSEC3 TTL is taken from the SOA resource record
+// of r by NegativeTTL, r must have one. MaxNSEC3Iterations doesn't apply to
+// GenerateNSEC3.
+func GenerateNSEC3(r RRs, params *NSEC3PARAM, optOut bool) (y RRs, err error) {
+	nodes, cuts, soa := zoneNodes(r)
+	if soa == nil {
+		return nil, fmt.Errorf("GenerateNSEC3: missing SOA")
+	}
+
+	apex := soa.Name
+	ttl, _ := NegativeTTL(soa)
+	rdata := *params
+	rdata.Flags &^= 1
+	if optOut {
+		rdata.Flags |= 1
+	}
+
+	// Owner names and their types, empty non-terminals have nil types.
+	names := map[string][]Type{}
+	for _, n := range nodes {
+		k := dns.CanonicalName(n.name)
+		types := append([]Type{TYPE_RRSIG}, n.types...)
+		if cuts[k] && !hasType(n.types, TYPE_DS) {
+			if optOut {
+				continue
+			}
+
+			types = n.types
+		}
+
+		names[k] = types
+		for labels := nameLabels(k); isSubdomain(k, apex); {
+			labels = labels[1:]
+			if k = strings.Join(append(labels, ""), "."); names[k] == nil {
+				names[k] = nil
+			}
+		}
+	}
+
+	hashes := make(byteSlices, 0, len(names))
+	types := map[string][]Type{}
+	for name, t := range names {
+		h, err := params.hashName(name)
+		if err != nil {
+			return nil, fmt.Errorf("GenerateNSEC3: %s", err)
+		}
+
+		hashes = append(hashes, h)
+		types[string(h)] = t
+	}
+	sort.Sort(hashes)
+
+	for i, h := range hashes {
+		rd := &NSEC3{rdata, hashes[(i+1)%len(hashes)], TypesEncode(types[string(h)])}
+		owner := strings.ToLower(string(strutil.Base32ExtEncode(h))) + "." + dns.RootedName(apex)
+		y = append(y, &RR{owner, TYPE_NSEC3, soa.Class, ttl, rd})
+	}
+	return
+}
+
+func hasType(types []Type, t Type) bool {
+	for _, v := range types {
+		if v == t {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,102 @@
+// Copyright (c) 2011 CZ.NIC z.s.p.o. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// blame: jnml, labs.nic.cz
+
+package rr
+
+import (
+	"github.com/cznic/dns"
+)
+
+// NameInterner deduplicates domain names, e.g. the owner names of the
+// records of a large in-memory zone, so that equal names share one backing
+// string. NameInterner is not safe for concurrent access.
+type NameInterner struct {
+	m map[string]string
+}
+
+// NewNameInterner returns a newly created NameInterner.
+func NewNameInterner() *NameInterner {
+	return &NameInterner{m: map[string]string{}}
+}
+
+// Intern returns the canonical form of name, see dns.CanonicalName. The
+// same string is returned for all names with the same canonical form.
+func (i *NameInterner) Intern(name string) string {
+	if i.m == nil {
+		i.m = map[string]string{}
+	}
+
+	c := dns.CanonicalName(name)
+	if s, ok := i.m[c]; ok {
+		return s
+	}
+
+	i.m[c] = c
+	return c
+}
+
+// Len returns the number of distinct names in i.
+func (i *NameInterner) Len() int {
+	return len(i.m)
+}
+
+// InternNames replaces the owner names of r and the domain names in their
+// RData by the names returned by i.Intern. Only the RData names which are
+// converted to lower case in the DNSSEC canonical form (RFC 4034/6.2) are
+// interned, see canonicalRData, so signatures over r stay valid.
+func (r RRs) InternNames(i *NameInterner) {
+	for _, rec := range r {
+		rec.Name = i.Intern(rec.Name)
+		switch x := rec.RData.(type) {
+		case *AFSDB:
+			x.Hostname = i.Intern(x.Hostname)
+		case *CNAME:
+			x.Name = i.Intern(x.Name)
+		case *DNAME:
+			x.Name = i.Intern(x.Name)
+		case *KX:
+			x.Exchanger = i.Intern(x.Exchanger)
+		case *MB:
+			x.MADNAME = i.Intern(x.MADNAME)
+		case *MD:
+			x.MADNAME = i.Intern(x.MADNAME)
+		case *MF:
+			x.MADNAME = i.Intern(x.MADNAME)
+		case *MG:
+			x.MGNAME = i.Intern(x.MGNAME)
+		case *MINFO:
+			x.RMAILBX = i.Intern(x.RMAILBX)
+			x.EMAILBX = i.Intern(x.EMAILBX)
+		case *MR:
+			x.NEWNAME = i.Intern(x.NEWNAME)
+		case *MX:
+			x.Exchange = i.Intern(x.Exchange)
+		case *NAPTR:
+			x.Replacement = i.Intern(x.Replacement)
+		case *NS:
+			x.NSDName = i.Intern(x.NSDName)
+		case *PTR:
+			x.PTRDName = i.Intern(x.PTRDName)
+		case *PX:
+			x.MAP822 = i.Intern(x.MAP822)
+			x.MAPX400 = i.Intern(x.MAPX400)
+		case *RP:
+			x.Mbox = i.Intern(x.Mbox)
+			x.Txt = i.Intern(x.Txt)
+		case *RRSIG:
+			x.Name = i.Intern(x.Name)
+		case *RT:
+			x.Hostname = i.Intern(x.Hostname)
+		case *SIG:
+			x.Name = i.Intern(x.Name)
+		case *SOA:
+			x.MName = i.Intern(x.MName)
+			x.RName = i.Intern(x.RName)
+		case *SRV:
+			x.Target = i.Intern(x.Target)
+		}
+	}
+}
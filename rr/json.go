@@ -0,0 +1,186 @@
+// Copyright (c) 2011 CZ.NIC z.s.p.o. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// blame: jnml, labs.nic.cz
+
+package rr
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/cznic/dns"
+	"net"
+	"reflect"
+	"unicode"
+)
+
+// RData fields which are presented in hex. Other []byte fields are base64
+// encoded.
+var jsonHexFields = map[string]bool{
+	"Certificate": true,
+	"Digest":      true,
+	"Fingerprint": true,
+	"HIT":         true,
+	"KeyData":     true,
+	"MAC":         true,
+	"NSAP":        true,
+	"OtherData":   true,
+	"Salt":        true,
+}
+
+type jsonRR struct {
+	Name  string          `json:"name"`
+	Type  Type            `json:"type"`
+	Class Class           `json:"class"`
+	TTL   int32           `json:"ttl"`
+	RData json.RawMessage `json:"rdata"`
+}
+
+// MarshalJSON implements json.Marshaler. The RData is marshaled as an object
+// with the RData field names in lower camel case, e.g.
+//
+//	{"name":"example.com.","type":"A","class":"IN","ttl":3600,"rdata":{"address":"1.2.3.4"}}
+func (rr *RR) MarshalJSON() (b []byte, err error) {
+	x := jsonRR{Name: rr.Name, Type: rr.Type, Class: rr.Class, TTL: rr.TTL}
+	if x.RData, err = marshalRData(rr.RData); err != nil {
+		return
+	}
+
+	return json.Marshal(x)
+}
+
+// UnmarshalJSON implements json.Unmarshaler. The concrete type of RData is
+// determined by the "type" member.
+func (rr *RR) UnmarshalJSON(b []byte) (err error) {
+	var x jsonRR
+	if err = json.Unmarshal(b, &x); err != nil {
+		return
+	}
+
+	rd := newRData(x.Type)
+	if len(x.RData) != 0 && string(x.RData) != "null" {
+		if err = unmarshalRData(x.RData, rd); err != nil {
+			return
+		}
+	}
+
+	*rr = RR{x.Name, x.Type, x.Class, x.TTL, rd}
+	return
+}
+
+// jsonName returns the lower camel case form of a Go field name, e.g.
+// Address -> address, NSDName -> nsdName, HIT -> hit.
+func jsonName(s string) string {
+	r := []rune(s)
+	n := 0
+	for n < len(r) && unicode.IsUpper(r[n]) {
+		n++
+	}
+	if n > 1 && n < len(r) {
+		n--
+	}
+	for i := 0; i < n; i++ {
+		r[i] = unicode.ToLower(r[i])
+	}
+	return string(r)
+}
+
+// jsonFields calls f for all fields of the struct v, flattening embedded
+// structs.
+func jsonFields(v reflect.Value, f func(name string, fv reflect.Value)) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.Anonymous && sf.Type.Kind() == reflect.Struct {
+			jsonFields(v.Field(i), f)
+			continue
+		}
+
+		f(sf.Name, v.Field(i))
+	}
+}
+
+func marshalRData(rd dns.Wirer) (b []byte, err error) {
+	if rd == nil {
+		return []byte("null"), nil
+	}
+
+	v := reflect.Indirect(reflect.ValueOf(rd))
+	if v.Kind() != reflect.Struct {
+		return json.Marshal(v.Interface())
+	}
+
+	m := map[string]interface{}{}
+	jsonFields(v, func(name string, fv reflect.Value) {
+		x := fv.Interface()
+		switch y := x.(type) {
+		case []byte:
+			if jsonHexFields[name] {
+				x = hex.EncodeToString(y)
+			}
+		case net.IP:
+			if y == nil {
+				x = nil
+			}
+		}
+		m[jsonName(name)] = x
+	})
+	return json.Marshal(m)
+}
+
+func unmarshalRData(b []byte, rd dns.Wirer) (err error) {
+	v := reflect.Indirect(reflect.ValueOf(rd))
+	if v.Kind() != reflect.Struct {
+		return json.Unmarshal(b, rd)
+	}
+
+	var m map[string]json.RawMessage
+	if err = json.Unmarshal(b, &m); err != nil {
+		return
+	}
+
+	jsonFields(v, func(name string, fv reflect.Value) {
+		raw, ok := m[jsonName(name)]
+		if !ok || err != nil {
+			return
+		}
+
+		if fv.Kind() == reflect.Interface { // IPSECKEY.Gateway
+			var s *string
+			if err = json.Unmarshal(raw, &s); err != nil || s == nil {
+				return
+			}
+
+			if ip := net.ParseIP(*s); ip != nil {
+				fv.Set(reflect.ValueOf(ip))
+				return
+			}
+
+			fv.Set(reflect.ValueOf(*s))
+			return
+		}
+
+		if _, ok := fv.Interface().([]byte); ok && jsonHexFields[name] {
+			var s string
+			if err = json.Unmarshal(raw, &s); err != nil {
+				return
+			}
+
+			var h []byte
+			if h, err = hex.DecodeString(s); err != nil {
+				return
+			}
+
+			fv.SetBytes(h)
+			return
+		}
+
+		err = json.Unmarshal(raw, fv.Addr().Interface())
+	})
+	if err != nil {
+		err = fmt.Errorf("%T: %s", rd, err)
+	}
+	return
+}
@@ -0,0 +1,130 @@
+// Copyright (c) 2011 CZ.NIC z.s.p.o. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// blame: jnml, labs.nic.cz
+
+package rr
+
+import (
+	"fmt"
+	"github.com/cznic/dns"
+	"sync"
+	"time"
+)
+
+type negKey struct {
+	name  string
+	typ   Type
+	class Class
+}
+
+type negEntry struct {
+	nxdomain bool
+	expires  time.Time
+}
+
+// NegativeCache holds negative answers (RFC 2308), i.e. NODATA and NXDOMAIN
+// pseudo RRs, keyed by owner name, type and class. NegativeCache is safe for
+// concurrent access.
+type NegativeCache struct {
+	// Now returns the current time. If Now is nil, the package level Now
+	// is used.
+	Now func() time.Time
+	mu  sync.Mutex
+	m   map[negKey]negEntry
+}
+
+// NewNegativeCache returns a newly created NegativeCache.
+func NewNegativeCache() *NegativeCache {
+	return &NegativeCache{m: map[negKey]negEntry{}}
+}
+
+func (c *NegativeCache) now() time.Time {
+	if c.Now != nil {
+		return c.Now()
+	}
+
+	return Now()
+}
+
+// NegativeTTL returns the TTL of a negative answer accompanied by the SOA
+// resource record soa, i.e. the minimum of the SOA RR TTL and the SOA MINIMUM
+// field (RFC 2308/5).
+func NegativeTTL(soa *RR) (ttl int32, err error) {
+	rd, ok := soa.RData.(*SOA)
+	if !ok {
+		return 0, fmt.Errorf("NegativeTTL: %T is not a SOA", soa.RData)
+	}
+
+	ttl = soa.TTL
+	if m := int32(rd.Minimum); m >= 0 && m < ttl {
+		ttl = m
+	}
+	if ttl < 0 {
+		ttl = 0
+	}
+	return
+}
+
+// Add caches the negative answer r, which must have either NODATA or NXDOMAIN
+// RData, for the TTL derived from the SOA resource record soa by NegativeTTL.
+// A NXDOMAIN answer covers all types of its owner name.
+func (c *NegativeCache) Add(r *RR, soa *RR) (err error) {
+	ttl, err := NegativeTTL(soa)
+	if err != nil {
+		return
+	}
+
+	k := negKey{dns.CanonicalName(r.Name), 0, r.Class}
+	e := negEntry{expires: c.now().Add(time.Duration(ttl) * time.Second)}
+	switch x := r.RData.(type) {
+	case *NODATA:
+		k.typ = x.Type
+	case *NXDOMAIN:
+		k.typ, e.nxdomain = TYPE_NXDOMAIN, true
+	default:
+		return fmt.Errorf("(*NegativeCache).Add: %T is not a negative answer", r.RData)
+	}
+
+	c.mu.Lock()         // W++
+	defer c.mu.Unlock() // W--
+
+	if c.m == nil {
+		c.m = map[negKey]negEntry{}
+	}
+	c.m[k] = e
+	return
+}
+
+// Get is GetClass for class IN.
+func (c *NegativeCache) Get(name string, t Type) (present, negative, expired bool) {
+	return c.GetClass(name, t, CLASS_IN)
+}
+
+// GetClass looks up a negative answer for name, type t and class. Negative
+// reports whether a negative answer is cached, present reports whether the
+// answer is NODATA, i.e. the name exists but has no RRs of type t. If the
+// cached answer TTL has elapsed, the entry is removed and expired is true.
+// Present and negative then reflect the removed entry.
+func (c *NegativeCache) GetClass(name string, t Type, class Class) (present, negative, expired bool) {
+	name = dns.CanonicalName(name)
+	now := c.now()
+
+	c.mu.Lock()         // W++
+	defer c.mu.Unlock() // W--
+
+	for _, k := range []negKey{{name, TYPE_NXDOMAIN, class}, {name, t, class}} {
+		e, ok := c.m[k]
+		if !ok {
+			continue
+		}
+
+		if !now.Before(e.expires) {
+			delete(c.m, k)
+			expired = true
+		}
+		return !e.nxdomain, true, expired
+	}
+	return
+}
@@ -31,11 +31,7 @@ func (b *Bytes) Append(rrs RRs) {
 // Pack packs RRs to b.
 func (b *Bytes) Pack(rrs RRs) {
 	w := dns.NewWirebuf()
-	for _, rec := range rrs {
-		//n0 := len(w.Buf)
-		rec.Encode(w)
-		//fmt.Printf("%d->%d, .Pack(%q)\n", n0, len(w.Buf), rec)
-	}
+	rrs.Encode(w)
 	*b = make([]byte, len(w.Buf)) // repack tight
 	copy(*b, w.Buf)
 }
@@ -40,10 +40,10 @@ TYPE         Value and meaning                              Reference
 //AAAA         28 IP6 Address                                 [RFC3596] done
 //LOC          29 Location Information                        [RFC1876] done
 NXT          30 Next Domain (OBSOLETE)                      [RFC3755][RFC2535]
-EID          31 Endpoint Identifier                         [Patton][Patton1995]
-NIMLOC       32 Nimrod Locator                              [Patton][Patton1995]
+//EID          31 Endpoint Identifier                         [Patton][Patton1995] done
+//NIMLOC       32 Nimrod Locator                              [Patton][Patton1995] done
 //SRV          33 Server Selection                            [RFC2782] done
-ATMA         34 ATM Address                                 [ATMDOC]
+//ATMA         34 ATM Address                                 [ATMDOC] done
 //NAPTR        35 Naming Authority Pointer                    [RFC2915][RFC2168][RFC3403] done
 //KX           36 Key Exchanger                               [RFC2230] done
 //CERT         37 CERT                                        [RFC4398] done
@@ -51,7 +51,7 @@ A6           38 A6 (OBSOLETE - use AAAA)                    [RFC3226][RFC2874][R
 //DNAME        39 DNAME                                       [RFC2672] done
 SINK         40 SINK                                        [Eastlake][Eastlake2002]
 //OPT          41 OPT                                         [RFC2671][RFC3225] done
-APL          42 APL                                         [RFC3123]
+//APL          42 APL                                         [RFC3123] done
 //DS           43 Delegation Signer                           [RFC4034][RFC3658] done
 //SSHFP        44 SSH Key Fingerprint                         [RFC4255] done
 //IPSECKEY     45 IPSECKEY                                    [RFC4025] done
@@ -61,13 +61,21 @@ APL          42 APL                                         [RFC3123]
 //DHCID        49 DHCID                                       [RFC4701] done
 //NSEC3        50 NSEC3                                       [RFC5155] done
 //NSEC3PARAM   51 NSEC3PARAM                                  [RFC5155] done
-Unassigned   52-54
+Unassigned   52
+//SMIMEA       53 S/MIME cert association                     [RFC8162] done
+Unassigned   54
 //HIP          55 Host Identity Protocol                      [RFC5205] done
-NINFO        56 NINFO                                       [Reid]
+//NINFO        56 NINFO                                       [Reid] done
 RKEY         57 RKEY                                        [Reid]
 //TALINK       58 Trust Anchor LINK                           [Wijngaards] done
 CDS          59 Child DS                                    [Barwood]
-Unassigned   60-98
+CDNSKEY      60 Child DNSKEY                                [RFC7344]
+//OPENPGPKEY   61 OpenPGP Key                                 [RFC7929] done
+//CSYNC        62 Child-To-Parent Synchronization             [RFC7477] done
+//ZONEMD       63 Message Digest Over Zone Data               [RFC8976] done
+//SVCB         64 General Purpose Service Binding             [RFC9460] done
+//HTTPS        65 HTTPS Binding                               [RFC9460] done
+Unassigned   66-98
 //SPF          99                                             [RFC4408] done
 UINFO        100                                            [IANA-Reserved]
 UID          101                                            [IANA-Reserved]
@@ -174,8 +182,11 @@ import (
 	"fmt"
 	"github.com/cznic/dns"
 	"github.com/cznic/strutil"
-	"log"
+	"math"
+	"math/rand"
 	"net"
+	"reflect"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -192,7 +203,197 @@ func init() {
 }
 
 func quote(s string) string {
-	return dns.CharString(s).Quoted()
+	return escapeCharString(s)
+}
+
+// escapeCharString returns the <character-string> s in the RFC 1035/5.1
+// presentation format, without the enclosing quotes. Backslashes and double
+// quotes are escaped as \\ and \", bytes outside the printable ASCII range
+// are escaped as \DDD.
+func escapeCharString(s string) string {
+	var buf bytes.Buffer
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '"' || c == '\\':
+			buf.WriteByte('\\')
+			buf.WriteByte(c)
+		case c < ' ' || c > '~':
+			fmt.Fprintf(&buf, "\\%03d", c)
+		default:
+			buf.WriteByte(c)
+		}
+	}
+	return buf.String()
+}
+
+// UnescapeCharString returns the <character-string> presented as s, without
+// the enclosing quotes, in the RFC 1035/5.1 master file format. It replaces
+// \DDD with the octet having the decimal value DDD and \X, where X is not a
+// digit, with X. It is the inverse of the escaping done by String methods.
+func UnescapeCharString(s string) (string, error) {
+	if strings.IndexByte(s, '\\') < 0 {
+		return s, nil
+	}
+
+	var buf bytes.Buffer
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c != '\\' {
+			buf.WriteByte(c)
+			continue
+		}
+
+		if i++; i == len(s) {
+			return "", fmt.Errorf("UnescapeCharString: trailing backslash in %q", s)
+		}
+
+		if c = s[i]; c < '0' || c > '9' {
+			buf.WriteByte(c)
+			continue
+		}
+
+		if i+3 > len(s) {
+			return "", fmt.Errorf("UnescapeCharString: invalid escape in %q", s)
+		}
+
+		n, err := strconv.ParseUint(s[i:i+3], 10, 8)
+		if err != nil {
+			return "", fmt.Errorf("UnescapeCharString: invalid escape in %q", s)
+		}
+
+		buf.WriteByte(byte(n))
+		i += 2
+	}
+	return buf.String(), nil
+}
+
+// CharStrings is a list of <character-string>s (RFC 1035/3.3), the RDATA
+// format of eg. TXT and SPF. Every item is a separate <character-string> on
+// the wire, so the boundaries between items survive a round trip.
+type CharStrings []string
+
+// NewCharStrings returns s split into <character-string>s of at most 255
+// octets each. An empty s results in a single empty <character-string>.
+func NewCharStrings(s string) (c CharStrings) {
+	for len(s) > 255 {
+		c = append(c, s[:255])
+		s = s[255:]
+	}
+	return append(c, s)
+}
+
+// Join returns the items of c concatenated, i.e. the inverse of
+// NewCharStrings.
+func (c CharStrings) Join() string {
+	return strings.Join(c, "")
+}
+
+// Implementation of dns.Wirer
+func (c CharStrings) Encode(b *dns.Wirebuf) {
+	for _, s := range c {
+		dns.CharString(s).Encode(b)
+	}
+}
+
+// Implementation of dns.Wirer. Decode consumes all of b following pos.
+func (c *CharStrings) Decode(b []byte, pos *int, sniffer dns.WireDecodeSniffer) (err error) {
+	s := CharStrings{}
+	for *pos < len(b) {
+		var part dns.CharString
+		if err = part.Decode(b, pos, sniffer); err != nil {
+			return
+		}
+
+		s = append(s, string(part))
+	}
+	*c = s
+	return
+}
+
+// String returns c in the presentation format, i.e. as space separated
+// quoted and escaped <character-string>s.
+func (c CharStrings) String() string {
+	a := make([]string, len(c))
+	for i, s := range c {
+		a[i] = `"` + quote(s) + `"`
+	}
+	return strings.Join(a, " ")
+}
+
+// rdataFields returns tokens, the RDATA fields in the presentation format,
+// without the parentheses which group multi line RDATA (RFC 1035/5.1).
+// Parentheses may be separate tokens or attached to a field.
+func rdataFields(tokens []string) (fields []string) {
+	for _, t := range tokens {
+		t = strings.Trim(t, "()")
+		if t != "" {
+			fields = append(fields, t)
+		}
+	}
+	return
+}
+
+// parseUint parses the decimal field s of an RDATA presentation format which
+// must fit in bits.
+func parseUint(field, s string, bits int) (n uint64, err error) {
+	if n, err = strconv.ParseUint(s, 10, bits); err != nil {
+		return 0, fmt.Errorf("invalid %s %q", field, s)
+	}
+
+	return
+}
+
+// checkUncompressed returns an error if the <domain-name> found in b at pos
+// uses name compression. RFC 4034/6.2 and RFC 6672/2.5 forbid compression of
+// some RDATA domain names, e.g. of the signer's name in RRSIG.
+func checkUncompressed(b []byte, pos int) error {
+	for pos < len(b) {
+		switch n := int(b[pos]); {
+		case n == 0:
+			return nil
+		case n&0xC0 != 0:
+			return fmt.Errorf("compressed or invalid domain name at %#x", pos)
+		default:
+			pos += n + 1
+		}
+	}
+	return nil
+}
+
+// escapeName returns the domain name name in the RFC 1035/5.1 presentation
+// format. Labels of name are separated by dots, so a dot within a label can be
+// held in name only in the escaped form "\\.". Such escapes, like any other
+// backslash escape found in name, are kept as they are. Other characters
+// special in master files are escaped with a backslash, spaces and octets
+// outside the printable ASCII range are escaped as \DDD.
+func escapeName(name string) string {
+	i := strings.IndexFunc(name, func(r rune) bool {
+		return r <= ' ' || r > '~' || strings.ContainsRune(`"();@$`, r)
+	})
+	if i < 0 {
+		return name
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(name[:i])
+	for ; i < len(name); i++ {
+		switch c := name[i]; {
+		case c == '\\':
+			buf.WriteByte(c)
+			if i+1 < len(name) {
+				i++
+				buf.WriteByte(name[i])
+			}
+		case c <= ' ' || c > '~':
+			fmt.Fprintf(&buf, "\\%03d", c)
+		case strings.IndexByte(`"();@$`, c) >= 0:
+			buf.WriteByte('\\')
+			buf.WriteByte(c)
+		default:
+			buf.WriteByte(c)
+		}
+	}
+	return buf.String()
 }
 
 // A holds the zone A RData
@@ -200,6 +401,16 @@ type A struct {
 	Address net.IP // A 32 bit Internet address.
 }
 
+// NewA returns A RData holding ip or an error if ip is not an IPv4 address.
+func NewA(ip net.IP) (rd *A, err error) {
+	rd = &A{ip}
+	if err = rd.Validate(); err != nil {
+		return nil, err
+	}
+
+	return
+}
+
 // Implementation of dns.Wirer
 func (rd *A) Encode(b *dns.Wirebuf) {
 	ip4(rd.Address).Encode(b)
@@ -227,6 +438,17 @@ type AAAA struct {
 	Address net.IP // A 128 bit Internet address.
 }
 
+// NewAAAA returns AAAA RData holding ip or an error if ip is not a 16 byte
+// IPv6 address. IPv4 addresses, including the IPv4-mapped ones, are rejected.
+func NewAAAA(ip net.IP) (rd *AAAA, err error) {
+	rd = &AAAA{ip}
+	if err = rd.Validate(); err != nil {
+		return nil, err
+	}
+
+	return
+}
+
 // Implementation of dns.Wirer
 func (rd *AAAA) Encode(b *dns.Wirebuf) {
 	ip6(rd.Address).Encode(b)
@@ -286,7 +508,202 @@ func (rd *AFSDB) Decode(b []byte, pos *int, sniffer dns.WireDecodeSniffer) (err
 }
 
 func (rd *AFSDB) String() string {
-	return fmt.Sprintf("%d %s", rd.SubType, rd.Hostname)
+	return fmt.Sprintf("%d %s", rd.SubType, escapeName(rd.Hostname))
+}
+
+// APLItem is an address prefix list item of the APL RR RData.
+type APLItem struct {
+	// Address family, 1 for IPv4 and 2 for IPv6 (RFC 3123/4).
+	Family uint16
+	// Prefix length, up to 32 for IPv4 and up to 128 for IPv6.
+	Prefix byte
+	// Negation flag, i.e. the item is a negated address range.
+	Negation bool
+	// Address family dependent part, i.e. the address. Trailing zero
+	// octets are not encoded.
+	AFD []byte
+}
+
+// afd returns the AFD of i, an IPv4 address held in 16 octets is converted to
+// its 4 octet form.
+func (i APLItem) afd() []byte {
+	if i.Family == 1 {
+		if ip := net.IP(i.AFD).To4(); ip != nil {
+			return ip
+		}
+	}
+
+	return i.AFD
+}
+
+// String returns i in the presentation format, e.g. "!1:192.168.38.0/28".
+// Items of other families than IPv4 and IPv6 have the AFD in hex.
+func (i APLItem) String() string {
+	neg := ""
+	if i.Negation {
+		neg = "!"
+	}
+
+	var n int
+	switch i.Family {
+	case 1:
+		n = net.IPv4len
+	case 2:
+		n = net.IPv6len
+	}
+
+	afd := i.afd()
+	addr := hex.EncodeToString(afd)
+	if n != 0 && len(afd) <= n {
+		ip := make(net.IP, n)
+		copy(ip, afd)
+		addr = ip.String()
+	}
+	return fmt.Sprintf("%s%d:%s/%d", neg, i.Family, addr, i.Prefix)
+}
+
+// APL holds the APL RR RData (RFC 3123).
+type APL struct {
+	Items []APLItem
+}
+
+// Implementation of dns.Wirer
+func (rd *APL) Encode(b *dns.Wirebuf) {
+	for _, i := range rd.Items {
+		afd := i.afd()
+		for len(afd) != 0 && afd[len(afd)-1] == 0 {
+			afd = afd[:len(afd)-1]
+		}
+		n := len(afd)
+		if i.Negation {
+			n |= 0x80
+		}
+		dns.Octets2(i.Family).Encode(b)
+		dns.Octet(i.Prefix).Encode(b)
+		dns.Octet(n).Encode(b)
+		b.Buf = append(b.Buf, afd...)
+	}
+}
+
+// Implementation of dns.Wirer
+func (rd *APL) Decode(b []byte, pos *int, sniffer dns.WireDecodeSniffer) (err error) {
+	p0 := &b[*pos]
+	rd.Items = nil
+	for *pos < len(b) {
+		var i APLItem
+		if err = (*dns.Octets2)(&i.Family).Decode(b, pos, sniffer); err != nil {
+			return
+		}
+
+		if err = (*dns.Octet)(&i.Prefix).Decode(b, pos, sniffer); err != nil {
+			return
+		}
+
+		var n dns.Octet
+		if err = n.Decode(b, pos, sniffer); err != nil {
+			return
+		}
+
+		i.Negation = n&0x80 != 0
+		afdlen := int(n & 0x7f)
+		if *pos+afdlen > len(b) {
+			return fmt.Errorf("(*APL).Decode: AFD length %d overflows RDATA", afdlen)
+		}
+
+		// RFC 3123/4.1, 4.2
+		var maxlen, maxprefix int
+		switch i.Family {
+		case 1:
+			maxlen, maxprefix = net.IPv4len, 32
+		case 2:
+			maxlen, maxprefix = net.IPv6len, 128
+		}
+		if maxlen != 0 && afdlen > maxlen {
+			return fmt.Errorf("(*APL).Decode: family %d, AFD length %d > %d", i.Family, afdlen, maxlen)
+		}
+
+		if maxlen != 0 && int(i.Prefix) > maxprefix {
+			return fmt.Errorf("(*APL).Decode: family %d, prefix %d > %d", i.Family, i.Prefix, maxprefix)
+		}
+
+		i.AFD = make([]byte, afdlen)
+		copy(i.AFD, b[*pos:])
+		*pos += afdlen
+		rd.Items = append(rd.Items, i)
+	}
+
+	if sniffer != nil {
+		sniffer(p0, &b[*pos-1], dns.SniffRDataAPL, rd)
+	}
+	return
+}
+
+func (rd *APL) String() string {
+	a := make([]string, len(rd.Items))
+	for i, v := range rd.Items {
+		a[i] = v.String()
+	}
+	return strings.Join(a, " ")
+}
+
+// Values of the ATMA RData Format field
+const (
+	ATMAFormatAESA byte = iota // ATM End System Address, 20 octets
+	ATMAFormatE164             // E.164 address, ASCII decimal digits
+)
+
+// ATMA represents ATMA RR RDATA, an ATM address (ATM Forum, af-dans-0152.000).
+// The RDATA is one octet of Format followed by the address. An AESA address is
+// a binary string of 20 octets, an E.164 address is a string of ASCII digits.
+type ATMA struct {
+	Format  byte
+	Address []byte
+}
+
+// Implementation of dns.Wirer
+func (rd *ATMA) Encode(b *dns.Wirebuf) {
+	b.Buf = append(b.Buf, rd.Format)
+	b.Buf = append(b.Buf, rd.Address...)
+}
+
+// Implementation of dns.Wirer
+func (rd *ATMA) Decode(b []byte, pos *int, sniffer dns.WireDecodeSniffer) (err error) {
+	p0 := &b[*pos]
+	if err = (*dns.Octet)(&rd.Format).Decode(b, pos, sniffer); err != nil {
+		return
+	}
+
+	n := len(b) - *pos
+	if n <= 0 {
+		return fmt.Errorf("(*ATMA).Decode: no address")
+	}
+
+	rd.Address = append([]byte{}, b[*pos:]...)
+	*pos += n
+	if sniffer != nil {
+		sniffer(p0, &b[*pos-1], dns.SniffRDataATMA, rd)
+	}
+	return
+}
+
+// String returns the presentation format of rd. An AESA address is dotted hex
+// split into the AFI, IDI, HO-DSP, ESI and SEL parts, e.g.
+// 39.246f.000e7c9c031200010001.000012345678.00; an E.164 address is its
+// digits.
+func (rd *ATMA) String() string {
+	switch rd.Format {
+	case ATMAFormatAESA:
+		a := rd.Address
+		if len(a) != 20 {
+			return hex.EncodeToString(a)
+		}
+
+		return fmt.Sprintf("%x.%x.%x.%x.%x", a[:1], a[1:3], a[3:13], a[13:19], a[19:])
+	case ATMAFormatE164:
+		return string(rd.Address)
+	}
+
+	return fmt.Sprintf("%d %x", rd.Format, rd.Address)
 }
 
 // CertType is the type of the Type field in the CERT RData
@@ -431,7 +848,57 @@ func (rd *CNAME) Decode(b []byte, pos *int, sniffer dns.WireDecodeSniffer) (err
 }
 
 func (rd CNAME) String() string {
-	return rd.Name
+	return escapeName(rd.Name)
+}
+
+// Values of the CSYNC Flags field (RFC 7477/2.1.1.2)
+const (
+	CSYNC_IMMEDIATE  = 1 << iota // The parent may process the CSYNC RR immediately.
+	CSYNC_SOAMINIMUM             // The SOA serial must not be less than SOASerial.
+)
+
+// CSYNC holds the CSYNC RR RData (RFC 7477). It signals which RRsets of the
+// child zone apex the parent should copy to the delegation.
+type CSYNC struct {
+	// The SOA serial of the child zone the CSYNC RR applies to, if the
+	// CSYNC_SOAMINIMUM flag is set.
+	SOASerial uint32
+	// See the CSYNC_* constants.
+	Flags uint16
+	// The types to be synchronized, in the NSEC format (RFC 4034/4.1.2),
+	// see TypesEncode and TypesDecode.
+	TypeBitMaps []byte
+}
+
+// Implementation of dns.Wirer
+func (rd *CSYNC) Encode(b *dns.Wirebuf) {
+	dns.Octets4(rd.SOASerial).Encode(b)
+	dns.Octets2(rd.Flags).Encode(b)
+	b.Buf = append(b.Buf, rd.TypeBitMaps...)
+}
+
+// Implementation of dns.Wirer
+func (rd *CSYNC) Decode(b []byte, pos *int, sniffer dns.WireDecodeSniffer) (err error) {
+	p0 := &b[*pos]
+	if err = (*dns.Octets4)(&rd.SOASerial).Decode(b, pos, sniffer); err != nil {
+		return
+	}
+
+	if err = (*dns.Octets2)(&rd.Flags).Decode(b, pos, sniffer); err != nil {
+		return
+	}
+
+	end := len(b)
+	rd.TypeBitMaps = append([]byte{}, b[*pos:end]...)
+	*pos = end
+	if sniffer != nil {
+		sniffer(p0, &b[*pos-1], dns.SniffRDataCSYNC, rd)
+	}
+	return
+}
+
+func (rd *CSYNC) String() string {
+	return fmt.Sprintf("%d %d %s", rd.SOASerial, rd.Flags, bitmapString(rd.TypeBitMaps))
 }
 
 // DHCID represents the RDATA of an DHCID RR.
@@ -503,12 +970,18 @@ type DNAME struct {
 
 // Implementation of dns.Wirer
 func (rd DNAME) Encode(b *dns.Wirebuf) {
+	b.DisableCompression()
 	(dns.DomainName)(rd.Name).Encode(b)
+	b.EnableCompression()
 }
 
 // Implementation of dns.Wirer
 func (rd *DNAME) Decode(b []byte, pos *int, sniffer dns.WireDecodeSniffer) (err error) {
 	p0 := &b[*pos]
+	if err = checkUncompressed(b, *pos); err != nil {
+		return fmt.Errorf("(*DNAME).Decode: %s", err)
+	}
+
 	if err = (*dns.DomainName)(&rd.Name).Decode(b, pos, sniffer); err != nil {
 		return
 	}
@@ -520,7 +993,7 @@ func (rd *DNAME) Decode(b []byte, pos *int, sniffer dns.WireDecodeSniffer) (err
 }
 
 func (rd DNAME) String() string {
-	return rd.Name
+	return escapeName(rd.Name)
 }
 
 // DNSSEC Algorithm Types
@@ -544,12 +1017,21 @@ func (rd DNAME) String() string {
 //	  3   DSA/SHA-1 [DSA]          y      [RFC2536]  OPTIONAL
 //	  4   Elliptic Curve [ECC]              TBA       -
 //	  5   RSA/SHA-1 [RSASHA1]      y      [RFC3110]  MANDATORY
+//	  6   DSA-NSEC3-SHA1           y      [RFC5155]
+//	  7   RSASHA1-NSEC3-SHA1       y      [RFC5155]
+//	  8   RSA/SHA-256 [RSASHA256]  y      [RFC5702]
+//	 10   RSA/SHA-512 [RSASHA512]  y      [RFC5702]
+//	 12   GOST R 34.10-2001        y      [RFC5933]
+//	 13   ECDSAP256SHA256          y      [RFC6605]
+//	 14   ECDSAP384SHA384          y      [RFC6605]
+//	 15   ED25519                  y      [RFC8080]
+//	 16   ED448                    y      [RFC8080]
 //	252   Indirect [INDIRECT]      n                  -
 //	253   Private [PRIVATEDNS]     y      see below  OPTIONAL
 //	254   Private [PRIVATEOID]     y      see below  OPTIONAL
 //	255   reserved
 //
-//	6 - 251  Available for assignment by IETF Standards Action.
+//	17 - 251  Available for assignment by IETF Standards Action.
 type AlgorithmType byte
 
 // AlgorithmType values
@@ -560,12 +1042,84 @@ const (
 	AlgorithmDSA_SHA1
 	AlgorithmElliptic
 	AlgorithmRSA_SHA1
-	AlgorithmIndirect AlgorithmType = iota + 246 // 252
+	AlgorithmDSA_NSEC3_SHA1
+	AlgorithmRSA_SHA1_NSEC3_SHA1
+	AlgorithmRSA_SHA256
+	_
+	AlgorithmRSA_SHA512
+	_
+	AlgorithmECC_GOST
+	AlgorithmECDSA_P256_SHA256
+	AlgorithmECDSA_P384_SHA384
+	AlgorithmED25519
+	AlgorithmED448
+	AlgorithmIndirect AlgorithmType = iota + 235 // 252
 	AlgorithmPrivateDNS
 	AlgorithmPrivateOID
 	AlgorithmReserved1255
 )
 
+var algorithmStr = map[AlgorithmType]string{
+	AlgorithmRSA_MD5:             "RSAMD5",
+	AlgorithmDiffie_Hellman:      "DH",
+	AlgorithmDSA_SHA1:            "DSA",
+	AlgorithmElliptic:            "ECC",
+	AlgorithmRSA_SHA1:            "RSASHA1",
+	AlgorithmDSA_NSEC3_SHA1:      "DSA-NSEC3-SHA1",
+	AlgorithmRSA_SHA1_NSEC3_SHA1: "RSASHA1-NSEC3-SHA1",
+	AlgorithmRSA_SHA256:          "RSASHA256",
+	AlgorithmRSA_SHA512:          "RSASHA512",
+	AlgorithmECC_GOST:            "ECC-GOST",
+	AlgorithmECDSA_P256_SHA256:   "ECDSAP256SHA256",
+	AlgorithmECDSA_P384_SHA384:   "ECDSAP384SHA384",
+	AlgorithmED25519:             "ED25519",
+	AlgorithmED448:               "ED448",
+	AlgorithmIndirect:            "INDIRECT",
+	AlgorithmPrivateDNS:          "PRIVATEDNS",
+	AlgorithmPrivateOID:          "PRIVATEOID",
+}
+
+var algorithmByName = map[string]AlgorithmType{}
+
+func init() {
+	for a, s := range algorithmStr {
+		algorithmByName[s] = a
+	}
+}
+
+// AlgorithmByName returns the AlgorithmType which has the mnemonic s, e.g.
+// RSASHA256. The lookup is case insensitive.
+func AlgorithmByName(s string) (a AlgorithmType, ok bool) {
+	a, ok = algorithmByName[strings.ToUpper(s)]
+	return
+}
+
+// parseAlgorithm parses the algorithm field s of an RDATA presentation format,
+// either a decimal number or a mnemonic (RFC 4034/2.2, 3.2, 5.3).
+func parseAlgorithm(s string) (a AlgorithmType, err error) {
+	if s != "" && s[0] >= '0' && s[0] <= '9' {
+		n, err := parseUint("algorithm", s, 8)
+		return AlgorithmType(n), err
+	}
+
+	var ok bool
+	if a, ok = AlgorithmByName(s); !ok {
+		return 0, fmt.Errorf("invalid algorithm %q", s)
+	}
+
+	return
+}
+
+// String returns the mnemonic of a, or its decimal value if a has no
+// mnemonic.
+func (a AlgorithmType) String() (s string) {
+	var ok bool
+	if s, ok = algorithmStr[a]; !ok {
+		return strconv.Itoa(int(a))
+	}
+	return
+}
+
 // Class is a RR CLASS
 type Class uint16
 
@@ -594,6 +1148,60 @@ func (c Class) String() (s string) {
 	return
 }
 
+var classByName = map[string]Class{}
+
+func init() {
+	for c, s := range classStr {
+		if s != "" {
+			classByName[s] = c
+		}
+	}
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (c Class) MarshalText() ([]byte, error) {
+	return []byte(c.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (c *Class) UnmarshalText(b []byte) (err error) {
+	var ok bool
+	if *c, ok = ClassByName(string(b)); !ok {
+		return fmt.Errorf("unknown class %q", b)
+	}
+
+	return
+}
+
+// ClassByName returns the Class which has the mnemonic s. The lookup is case
+// insensitive. The generic CLASSnnnn form (RFC 3597/5) is recognized as well.
+func ClassByName(s string) (c Class, ok bool) {
+	s = strings.ToUpper(s)
+	if c, ok = classByName[s]; ok {
+		return
+	}
+
+	var n uint16
+	if n, ok = genericCode(s, "CLASS"); ok {
+		c = Class(n)
+	}
+	return
+}
+
+// genericCode parses the RFC 3597/5 generic form prefix+decimal, e.g. TYPE123.
+func genericCode(s, prefix string) (n uint16, ok bool) {
+	if !strings.HasPrefix(s, prefix) || len(s) == len(prefix) {
+		return
+	}
+
+	u, err := strconv.ParseUint(s[len(prefix):], 10, 16)
+	if err != nil {
+		return
+	}
+
+	return uint16(u), true
+}
+
 // Implementation of dns.Wirer
 func (c Class) Encode(b *dns.Wirebuf) {
 	dns.Octets2(c).Encode(b)
@@ -723,12 +1331,57 @@ type DNSKEY struct {
 	Key []byte
 }
 
+// Bits of the DNSKEY Flags field (RFC 4034/2.1.1)
+const (
+	DNSKEY_ZONE = 0x0100 // Zone Key flag, bit 7.
+	DNSKEY_SEP  = 0x0001 // Secure Entry Point flag, bit 15.
+)
+
+// ParseDNSKEY parses the DNSKEY RDATA presentation format fields (RFC
+// 4034/2.2) as returned by String: the flags and protocol numbers, the
+// algorithm number or mnemonic, see AlgorithmByName, followed by the base64
+// encoded public key, which may be split into more tokens. Grouping
+// parentheses are ignored.
+func ParseDNSKEY(tokens []string) (rd *DNSKEY, err error) {
+	f := rdataFields(tokens)
+	if len(f) < 4 {
+		return nil, fmt.Errorf("ParseDNSKEY(%q): expected <flags> <protocol> <algorithm> <key>", tokens)
+	}
+
+	var n [2]uint64
+	for i, v := range []struct {
+		name string
+		bits int
+	}{{"flags", 16}, {"protocol", 8}} {
+		if n[i], err = parseUint(v.name, f[i], v.bits); err != nil {
+			return nil, fmt.Errorf("ParseDNSKEY(%q): %s", tokens, err)
+		}
+	}
+
+	alg, err := parseAlgorithm(f[2])
+	if err != nil {
+		return nil, fmt.Errorf("ParseDNSKEY(%q): %s", tokens, err)
+	}
+
+	key, err := strutil.Base64Decode([]byte(strings.Join(f[3:], "")))
+	if err != nil {
+		return nil, fmt.Errorf("ParseDNSKEY(%q): %s", tokens, err)
+	}
+
+	return &DNSKEY{uint16(n[0]), byte(n[1]), alg, key}, nil
+}
+
 func NewDNSKEY(Flags uint16, Algorithm AlgorithmType, Key []byte) *DNSKEY {
 	return &DNSKEY{Flags, 3, Algorithm, Key}
 }
 
 // Implementation of dns.Wirer
 func (rd *DNSKEY) Encode(b *dns.Wirebuf) {
+	rd.encode(b)
+}
+
+// encode encodes the wire layout shared by DNSKEY and KEY.
+func (rd *DNSKEY) encode(b *dns.Wirebuf) {
 	dns.Octets2(rd.Flags).Encode(b)
 	dns.Octet(rd.Protocol).Encode(b)
 	dns.Octet(rd.Algorithm).Encode(b)
@@ -738,6 +1391,23 @@ func (rd *DNSKEY) Encode(b *dns.Wirebuf) {
 // Implementation of dns.Wirer
 func (rd *DNSKEY) Decode(b []byte, pos *int, sniffer dns.WireDecodeSniffer) (err error) {
 	p0 := &b[*pos]
+	if err = rd.decode(b, pos, sniffer); err != nil {
+		return fmt.Errorf("(*DNSKEY).Decode: %s", err)
+	}
+
+	if len(rd.Key) == 0 {
+		return fmt.Errorf("(*DNSKEY).Decode: no key data")
+	}
+
+	if sniffer != nil {
+		sniffer(p0, &b[*pos-1], dns.SniffRDataDNSKEY, rd)
+	}
+	return
+}
+
+// decode decodes the wire layout shared by DNSKEY and KEY. The key may be
+// empty, the callers decide whether that's valid.
+func (rd *DNSKEY) decode(b []byte, pos *int, sniffer dns.WireDecodeSniffer) (err error) {
 	if err = (*dns.Octets2)(&rd.Flags).Decode(b, pos, sniffer); err != nil {
 		return
 	}
@@ -748,15 +1418,9 @@ func (rd *DNSKEY) Decode(b []byte, pos *int, sniffer dns.WireDecodeSniffer) (err
 		return
 	}
 	n := len(b) - *pos
-	if n <= 0 {
-		return fmt.Errorf("(*DNSKEY).Decode: no key data")
-	}
 	rd.Key = make([]byte, n)
 	copy(rd.Key, b[*pos:])
 	*pos += n
-	if sniffer != nil {
-		sniffer(p0, &b[*pos-1], dns.SniffRDataDNSKEY, rd)
-	}
 	return
 }
 
@@ -821,6 +1485,41 @@ func (rd *DS) Decode(b []byte, pos *int, sniffer dns.WireDecodeSniffer) (err err
 	return
 }
 
+// ParseDS parses the DS RDATA presentation format fields (RFC 4034/5.3) as
+// returned by String: the key tag number, the algorithm number or mnemonic,
+// see AlgorithmByName, and the digest type number followed by the hex encoded
+// digest, which may be split into more tokens. Grouping parentheses are
+// ignored.
+func ParseDS(tokens []string) (rd *DS, err error) {
+	f := rdataFields(tokens)
+	if len(f) < 4 {
+		return nil, fmt.Errorf("ParseDS(%q): expected <key tag> <algorithm> <digest type> <digest>", tokens)
+	}
+
+	var n [2]uint64
+	for i, v := range []struct {
+		name  string
+		field string
+		bits  int
+	}{{"key tag", f[0], 16}, {"digest type", f[2], 8}} {
+		if n[i], err = parseUint(v.name, v.field, v.bits); err != nil {
+			return nil, fmt.Errorf("ParseDS(%q): %s", tokens, err)
+		}
+	}
+
+	alg, err := parseAlgorithm(f[1])
+	if err != nil {
+		return nil, fmt.Errorf("ParseDS(%q): %s", tokens, err)
+	}
+
+	digest, err := hex.DecodeString(strings.Join(f[3:], ""))
+	if err != nil {
+		return nil, fmt.Errorf("ParseDS(%q): %s", tokens, err)
+	}
+
+	return &DS{uint16(n[0]), alg, HashAlgorithm(n[1]), digest}, nil
+}
+
 func (rd *DS) String() string {
 	if asserts && len(rd.Digest) == 0 {
 		panic("internal error")
@@ -888,6 +1587,41 @@ func (ip *ip6) Decode(b []byte, pos *int, sniffer dns.WireDecodeSniffer) (err er
 	return
 }
 
+// EID represents EID RR RDATA, a Nimrod Endpoint Identifier (Patton,
+// draft-ietf-nimrod-dns-02). The RDATA is an opaque string of octets
+// presented in hex.
+type EID struct {
+	Data []byte
+}
+
+// Implementation of dns.Wirer
+func (rd *EID) Encode(b *dns.Wirebuf) {
+	b.Buf = append(b.Buf, rd.Data...)
+}
+
+// Implementation of dns.Wirer
+func (rd *EID) Decode(b []byte, pos *int, sniffer dns.WireDecodeSniffer) (err error) {
+	if *pos >= len(b) {
+		rd.Data = []byte{}
+		if sniffer != nil {
+			sniffer(nil, nil, dns.SniffRDataEID, rd)
+		}
+		return
+	}
+
+	p0 := &b[*pos]
+	rd.Data = append([]byte{}, b[*pos:]...)
+	*pos = len(b)
+	if sniffer != nil {
+		sniffer(p0, &b[*pos-1], dns.SniffRDataEID, rd)
+	}
+	return
+}
+
+func (rd *EID) String() string {
+	return hex.EncodeToString(rd.Data)
+}
+
 // The geographical location is defined with the mnemonic GPOS and type code
 // 27.
 //
@@ -895,62 +1629,84 @@ func (ip *ip6) Decode(b []byte, pos *int, sniffer dns.WireDecodeSniffer) (err er
 // reasons of simplicity.  This also guarantees a concise unambiguous
 // description of a location by enforcing three compulsory numerical values to
 // be specified.
+//
+// The coordinates are kept as the strings found in the RDATA, so decoding and
+// encoding a GPOS RR doesn't change it.
 type GPOS struct {
 	// The real number describing the longitude encoded as a printable
 	// string. The precision is limited by 256 charcters within the range
 	// -90..90 degrees. Positive numbers indicate locations north of the
 	// equator.
-	Longitude float64
+	Longitude string
 	// The real number describing the latitude encoded as a printable
 	// string. The precision is limited by 256 charcters within the range
 	// -180..180 degrees. Positive numbers indicate locations east of the
 	// prime meridian.
-	Latitude float64
+	Latitude string
 	// The real number describing the altitude (in meters) from mean
 	// sea-level encoded as a printable string. The precision is limited by
 	// 256 charcters. Positive numbers indicate locations above mean
 	// sea-level.
-	Altitude float64
-}
-
-// Implementation of dns.Wirer
-func (rd *GPOS) Encode(b *dns.Wirebuf) {
-	var s dns.CharString
-	s = dns.CharString(fmt.Sprintf("%f", rd.Longitude))
-	s.Encode(b)
-	s = dns.CharString(fmt.Sprintf("%f", rd.Latitude))
-	s.Encode(b)
-	s = dns.CharString(fmt.Sprintf("%f", rd.Altitude))
-	s.Encode(b)
+	Altitude string
 }
 
-// Implementation of dns.Wirer
-func (rd *GPOS) Decode(b []byte, pos *int, sniffer dns.WireDecodeSniffer) (err error) {
-	p0 := &b[*pos]
-	var s dns.CharString
+// isGPOSReal reports whether s is a real number as used by the GPOS RR
+// coordinates, i.e. an optional sign, one or more decimal digits and an
+// optional fraction consisting of a dot and one or more decimal digits.
+// Exponents, hexadecimal numbers, NaN and infinities are not real numbers in
+// this sense.
+func isGPOSReal(s string) bool {
+	if s != "" && (s[0] == '+' || s[0] == '-') {
+		s = s[1:]
+	}
 
-	if err = s.Decode(b, pos, sniffer); err != nil {
-		return
+	i := 0
+	for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+		i++
+	}
+	if i == 0 {
+		return false
 	}
 
-	if _, err = fmt.Sscanf(string(s), "%f", &rd.Longitude); err != nil {
-		return
+	if i == len(s) {
+		return true
 	}
 
-	if err = s.Decode(b, pos, sniffer); err != nil {
-		return
+	if s[i] != '.' || i+1 == len(s) {
+		return false
 	}
 
-	if _, err = fmt.Sscanf(string(s), "%f", &rd.Latitude); err != nil {
-		return
+	for _, c := range []byte(s[i+1:]) {
+		if c < '0' || c > '9' {
+			return false
+		}
 	}
+	return true
+}
 
-	if err = s.Decode(b, pos, sniffer); err != nil {
-		return
+// Implementation of dns.Wirer
+func (rd *GPOS) Encode(b *dns.Wirebuf) {
+	dns.CharString(rd.Longitude).Encode(b)
+	dns.CharString(rd.Latitude).Encode(b)
+	dns.CharString(rd.Altitude).Encode(b)
+}
+
+// Implementation of dns.Wirer. The RDATA must consist of exactly three
+// <character-string>s, each holding a real number, see isGPOSReal.
+func (rd *GPOS) Decode(b []byte, pos *int, sniffer dns.WireDecodeSniffer) (err error) {
+	p0 := &b[*pos]
+	for _, v := range []*string{&rd.Longitude, &rd.Latitude, &rd.Altitude} {
+		if err = (*dns.CharString)(v).Decode(b, pos, sniffer); err != nil {
+			return
+		}
+
+		if !isGPOSReal(*v) {
+			return fmt.Errorf("(*GPOS).Decode: invalid coordinate %q", *v)
+		}
 	}
 
-	if _, err = fmt.Sscanf(string(s), "%f", &rd.Altitude); err != nil {
-		return
+	if *pos != len(b) {
+		return fmt.Errorf("(*GPOS).Decode: %d octets after the altitude", len(b)-*pos)
 	}
 
 	if sniffer != nil {
@@ -960,7 +1716,7 @@ func (rd *GPOS) Decode(b []byte, pos *int, sniffer dns.WireDecodeSniffer) (err e
 }
 
 func (rd *GPOS) String() string {
-	return fmt.Sprintf("%f %f %f", rd.Longitude, rd.Latitude, rd.Altitude)
+	return fmt.Sprintf("%s %s %s", rd.Longitude, rd.Latitude, rd.Altitude)
 }
 
 // HINFO records are used to acquire general information about a host.  The
@@ -1088,7 +1844,7 @@ func (rd *HIP) Decode(b []byte, pos *int, sniffer dns.WireDecodeSniffer) (err er
 		return
 	}
 
-	if *pos+int(hitLength) > len(b)+1 {
+	if *pos+int(hitLength) > len(b) {
 		return fmt.Errorf("(*rr.HIP).Decode() - buffer underflow")
 	}
 
@@ -1096,7 +1852,7 @@ func (rd *HIP) Decode(b []byte, pos *int, sniffer dns.WireDecodeSniffer) (err er
 	copy(rd.HIT, b[*pos:*pos+int(hitLength)])
 	*pos += int(hitLength)
 
-	if *pos+int(pkLength) > len(b)+1 {
+	if *pos+int(pkLength) > len(b) {
 		return fmt.Errorf("(*rr.HIP).Decode() - buffer underflow")
 	}
 
@@ -1106,6 +1862,10 @@ func (rd *HIP) Decode(b []byte, pos *int, sniffer dns.WireDecodeSniffer) (err er
 
 	rd.RendezvousServers = nil
 	for *pos < len(b) {
+		if err = checkUncompressed(b, *pos); err != nil {
+			return fmt.Errorf("(*rr.HIP).Decode() - %s", err)
+		}
+
 		var s dns.DomainName
 		if err = s.Decode(b, pos, sniffer); err != nil {
 			return
@@ -1123,7 +1883,7 @@ func (rd *HIP) Decode(b []byte, pos *int, sniffer dns.WireDecodeSniffer) (err er
 func (rd *HIP) String() string {
 	a := []string{}
 	for _, v := range rd.RendezvousServers {
-		a = append(a, v)
+		a = append(a, escapeName(v))
 	}
 	s := ""
 	if len(a) != 0 {
@@ -1366,7 +2126,7 @@ func (rd *IPSECKEY) String() (s string) {
 	case GatewayIPV4, GatewayIPV6:
 		return fmt.Sprintf("%d %d %d %s %s", rd.Precedence, rd.GatewayType, rd.Algorithm, rd.Gateway.(net.IP), strutil.Base64Encode(rd.PublicKey))
 	case GatewayDomain:
-		return fmt.Sprintf("%d %d %d %s %s", rd.Precedence, rd.GatewayType, rd.Algorithm, rd.Gateway.(string), strutil.Base64Encode(rd.PublicKey))
+		return fmt.Sprintf("%d %d %d %s %s", rd.Precedence, rd.GatewayType, rd.Algorithm, escapeName(rd.Gateway.(string)), strutil.Base64Encode(rd.PublicKey))
 	}
 	panic("unreachable")
 }
@@ -1386,14 +2146,17 @@ type ISDN struct {
 	ISDN string
 	// <sa> specifies the subaddress (SA).  The format of <sa> in master
 	// files is a <character-string> syntactically identical to that used
-	// in TXT and HINFO.
+	// in TXT and HINFO.  The subaddress is optional, an empty Sa is not
+	// encoded.
 	Sa string
 }
 
 // Implementation of dns.Wirer
 func (rd *ISDN) Encode(b *dns.Wirebuf) {
 	(dns.CharString)(rd.ISDN).Encode(b)
-	(dns.CharString)(rd.Sa).Encode(b)
+	if rd.Sa != "" {
+		(dns.CharString)(rd.Sa).Encode(b)
+	}
 }
 
 // Implementation of dns.Wirer
@@ -1403,8 +2166,11 @@ func (rd *ISDN) Decode(b []byte, pos *int, sniffer dns.WireDecodeSniffer) (err e
 		return
 	}
 
-	if err = (*dns.CharString)(&rd.Sa).Decode(b, pos, sniffer); err != nil {
-		return
+	rd.Sa = ""
+	if *pos < len(b) {
+		if err = (*dns.CharString)(&rd.Sa).Decode(b, pos, sniffer); err != nil {
+			return
+		}
 	}
 
 	if sniffer != nil {
@@ -1414,6 +2180,10 @@ func (rd *ISDN) Decode(b []byte, pos *int, sniffer dns.WireDecodeSniffer) (err e
 }
 
 func (rd *ISDN) String() string {
+	if rd.Sa == "" {
+		return fmt.Sprintf(`"%s"`, quote(rd.ISDN))
+	}
+
 	return fmt.Sprintf(`"%s" "%s"`, quote(rd.ISDN), quote(rd.Sa))
 }
 
@@ -1426,26 +2196,13 @@ func (rd *ISDN) String() string {
 // A KEY RR is, like any other RR, authenticated by a SIG RR.  KEY RRs must be
 // signed by a zone level key.
 type KEY struct {
-	// Bit 7 of the Flags field is the Zone Key flag.  If bit 7 has value 1,
-	// then the KEY record holds a DNS zone key, and the KEY RR's
-	// owner name MUST be the name of a zone.  If bit 7 has value 0, then
-	// the KEY record holds some other type of DNS public key and MUST
-	// NOT be used to verify RRSIGs that cover RRsets.
-	//
-	// Bit 15 of the Flags field is the Secure Entry Point flag, described
-	// in [RFC3757].  If bit 15 has value 1, then the KEY record holds a
-	// key intended for use as a secure entry point.  This flag is only
-	// intended to be a hint to zone signing or debugging software as to the
-	// intended use of this KEY record; validators MUST NOT alter their
-	// behavior during the signature validation process in any way based on
-	// the setting of this bit.  This also means that a KEY RR with the
-	// SEP bit set would also need the Zone Key flag set in order to be able
-	// to generate signatures legally.  A KEY RR with the SEP set and the
-	// Zone Key flag not set MUST NOT be used to verify RRSIGs that cover
-	// RRsets.
-	//
-	// Bits 0-6 and 8-14 are reserved: these bits MUST have value 0 upon
-	// creation of the KEY RR and MUST be ignored upon receipt.
+	// The flags of a KEY RR are not those of a DNSKEY RR (RFC 2535/3.1.2).
+	// Bits 0 and 1 are the "no authentication" and "no confidentiality"
+	// flags, both of them set means there is no key information. Bit 3 is
+	// the extension flag, bits 6 and 7 are the name type: 00 for a user
+	// key, 01 for a zone key, 10 for the key of a host or other end entity
+	// and 11 is reserved. Bits 12-15 are the signatory field. Bit 0 is the
+	// most significant one. See the KEY_* constants.
 	Flags uint16
 	// The Protocol Field MUST have value 3, and the KEY RR MUST be
 	// treated as invalid during signature verification if it is found to be
@@ -1467,31 +2224,22 @@ func NewKEY(Flags uint16, Algorithm AlgorithmType, Key []byte) *KEY {
 
 // Implementation of dns.Wirer
 func (rd *KEY) Encode(b *dns.Wirebuf) {
-	dns.Octets2(rd.Flags).Encode(b)
-	dns.Octet(rd.Protocol).Encode(b)
-	dns.Octet(rd.Algorithm).Encode(b)
-	b.Buf = append(b.Buf, rd.Key...)
+	(*DNSKEY)(rd).encode(b)
 }
 
 // Implementation of dns.Wirer
 func (rd *KEY) Decode(b []byte, pos *int, sniffer dns.WireDecodeSniffer) (err error) {
 	p0 := &b[*pos]
-	if err = (*dns.Octets2)(&rd.Flags).Decode(b, pos, sniffer); err != nil {
-		return
+	if err = (*DNSKEY)(rd).decode(b, pos, sniffer); err != nil {
+		return fmt.Errorf("(*KEY).Decode: %s", err)
 	}
-	if err = (*dns.Octet)(&rd.Protocol).Decode(b, pos, sniffer); err != nil {
-		return
-	}
-	if err = (*dns.Octet)(&rd.Algorithm).Decode(b, pos, sniffer); err != nil {
-		return
-	}
-	n := len(b) - *pos
-	if n <= 0 {
+
+	// RFC 2535/3.1.5: The key field is empty if the flags say there's no
+	// key.
+	if len(rd.Key) == 0 && !rd.NoKey() {
 		return fmt.Errorf("(*KEY).Decode: no key data")
 	}
-	rd.Key = make([]byte, n)
-	copy(rd.Key, b[*pos:])
-	*pos += n
+
 	if sniffer != nil {
 		sniffer(p0, &b[*pos-1], dns.SniffRDataKEY, rd)
 	}
@@ -1502,6 +2250,37 @@ func (rd *KEY) String() string {
 	return fmt.Sprintf("%d %d %d %s", rd.Flags, rd.Protocol, rd.Algorithm, strutil.Base64Encode(rd.Key))
 }
 
+// Bits and fields of the KEY RR Flags (RFC 2535/3.1.2)
+const (
+	KEY_NOAUTH        = 0x8000 // Use of the key is prohibited for authentication.
+	KEY_NOCONF        = 0x4000 // Use of the key is prohibited for confidentiality.
+	KEY_NOKEY         = KEY_NOAUTH | KEY_NOCONF
+	KEY_EXTEND        = 0x1000 // Flags extension follows the algorithm field.
+	KEY_NAMTYP        = 0x0300 // Name type field mask.
+	KEY_NAMTYP_USER   = 0x0000 // Key associated with a user or account.
+	KEY_NAMTYP_ZONE   = 0x0100 // Zone key.
+	KEY_NAMTYP_ENTITY = 0x0200 // Key associated with a host or other end entity.
+	KEY_SIGNATORY     = 0x000f // Signatory field mask.
+)
+
+// NoKey reports whether rd holds no key information, i.e. whether both the
+// KEY_NOAUTH and KEY_NOCONF flags are set.
+func (rd *KEY) NoKey() bool {
+	return rd.Flags&KEY_NOKEY == KEY_NOKEY
+}
+
+// NameType returns the name type field of rd, i.e. one of KEY_NAMTYP_USER,
+// KEY_NAMTYP_ZONE, KEY_NAMTYP_ENTITY or the reserved value KEY_NAMTYP.
+func (rd *KEY) NameType() uint16 {
+	return rd.Flags & KEY_NAMTYP
+}
+
+// Signatory returns the signatory field of rd, which is meaningful for zone
+// and entity keys used with dynamic update (RFC 2137/3.1.1).
+func (rd *KEY) Signatory() uint16 {
+	return rd.Flags & KEY_SIGNATORY
+}
+
 type KX struct {
 	// A 16 bit non-negative integer which specifies the preference given
 	// to this RR among other KX records at the same owner.  Lower values
@@ -1539,7 +2318,7 @@ func (rd *KX) Decode(b []byte, pos *int, sniffer dns.WireDecodeSniffer) (err err
 }
 
 func (rd *KX) String() string {
-	return fmt.Sprintf("%d %s", rd.Preference, rd.Exchanger)
+	return fmt.Sprintf("%d %s", rd.Preference, escapeName(rd.Exchanger))
 }
 
 // The LOC record is expressed in a master file in the following format:
@@ -1640,31 +2419,31 @@ func (rd *LOC) Encode(b *dns.Wirebuf) {
 // Implementation of dns.Wirer
 func (rd *LOC) Decode(b []byte, pos *int, sniffer dns.WireDecodeSniffer) (err error) {
 	p0 := &b[*pos]
-	if (*dns.Octet)(&rd.Version).Decode(b, pos, sniffer); err != nil {
+	if err = (*dns.Octet)(&rd.Version).Decode(b, pos, sniffer); err != nil {
 		return
 	}
 
-	if (*dns.Octet)(&rd.Size).Decode(b, pos, sniffer); err != nil {
+	if err = (*dns.Octet)(&rd.Size).Decode(b, pos, sniffer); err != nil {
 		return
 	}
 
-	if (*dns.Octet)(&rd.HorizPre).Decode(b, pos, sniffer); err != nil {
+	if err = (*dns.Octet)(&rd.HorizPre).Decode(b, pos, sniffer); err != nil {
 		return
 	}
 
-	if (*dns.Octet)(&rd.VertPre).Decode(b, pos, sniffer); err != nil {
+	if err = (*dns.Octet)(&rd.VertPre).Decode(b, pos, sniffer); err != nil {
 		return
 	}
 
-	if (*dns.Octets4)(&rd.Longitude).Decode(b, pos, sniffer); err != nil {
+	if err = (*dns.Octets4)(&rd.Longitude).Decode(b, pos, sniffer); err != nil {
 		return
 	}
 
-	if (*dns.Octets4)(&rd.Latitude).Decode(b, pos, sniffer); err != nil {
+	if err = (*dns.Octets4)(&rd.Latitude).Decode(b, pos, sniffer); err != nil {
 		return
 	}
 
-	if (*dns.Octets4)(&rd.Altitude).Decode(b, pos, sniffer); err != nil {
+	if err = (*dns.Octets4)(&rd.Altitude).Decode(b, pos, sniffer); err != nil {
 		return
 	}
 
@@ -1785,6 +2564,21 @@ func (rd *LOC) String() string {
 	)
 }
 
+// decodeName decodes the RDATA of the RR types consisting of a single, possibly
+// compressed, domain name, like MB, MD, MF, MG and MR, into name. The sniffer,
+// if any, is called with tag and rd.
+func decodeName(b []byte, pos *int, sniffer dns.WireDecodeSniffer, name *string, tag dns.WireDecodeSniffed, rd dns.Wirer) (err error) {
+	p0 := &b[*pos]
+	if err = (*dns.DomainName)(name).Decode(b, pos, sniffer); err != nil {
+		return
+	}
+
+	if sniffer != nil {
+		sniffer(p0, &b[*pos-1], tag, rd)
+	}
+	return
+}
+
 // MB records cause additional section processing which looks up an A type RRs
 // corresponding to MADNAME.
 type MB struct {
@@ -1800,19 +2594,11 @@ func (rd *MB) Encode(b *dns.Wirebuf) {
 
 // Implementation of dns.Wirer
 func (rd *MB) Decode(b []byte, pos *int, sniffer dns.WireDecodeSniffer) (err error) {
-	p0 := &b[*pos]
-	if err = (*dns.DomainName)(&rd.MADNAME).Decode(b, pos, sniffer); err != nil {
-		return
-	}
-
-	if sniffer != nil {
-		sniffer(p0, &b[*pos-1], dns.SniffRDataMB, rd)
-	}
-	return
+	return decodeName(b, pos, sniffer, &rd.MADNAME, dns.SniffRDataMB, rd)
 }
 
 func (rd *MB) String() string {
-	return rd.MADNAME
+	return escapeName(rd.MADNAME)
 }
 
 // MD records cause additional section processing which looks up an A type
@@ -1835,25 +2621,17 @@ func (rd *MD) Encode(b *dns.Wirebuf) {
 
 // Implementation of dns.Wirer
 func (rd *MD) Decode(b []byte, pos *int, sniffer dns.WireDecodeSniffer) (err error) {
-	p0 := &b[*pos]
-	if err = (*dns.DomainName)(&rd.MADNAME).Decode(b, pos, sniffer); err != nil {
-		return
-	}
-
-	if sniffer != nil {
-		sniffer(p0, &b[*pos-1], dns.SniffRDataMD, rd)
-	}
-	return
+	return decodeName(b, pos, sniffer, &rd.MADNAME, dns.SniffRDataMD, rd)
 }
 
 func (rd *MD) String() string {
-	return rd.MADNAME
+	return escapeName(rd.MADNAME)
 }
 
 // MF records cause additional section processing which looks up an A type
 // record corresponding to MADNAME.
 //
-// MF is obsolete.  See the definition of MX and [RFC-974] for details ofw the
+// MF is obsolete.  See the definition of MX and [RFC-974] for details of the
 // new scheme.  The recommended policy for dealing with MD RRs found in a
 // master file is to reject them, or to convert them to MX RRs with a
 // preference of 10.
@@ -1870,19 +2648,11 @@ func (rd *MF) Encode(b *dns.Wirebuf) {
 
 // Implementation of dns.Wirer
 func (rd *MF) Decode(b []byte, pos *int, sniffer dns.WireDecodeSniffer) (err error) {
-	p0 := &b[*pos]
-	if err = (*dns.DomainName)(&rd.MADNAME).Decode(b, pos, sniffer); err != nil {
-		return
-	}
-
-	if sniffer != nil {
-		sniffer(p0, &b[*pos-1], dns.SniffRDataMF, rd)
-	}
-	return
+	return decodeName(b, pos, sniffer, &rd.MADNAME, dns.SniffRDataMF, rd)
 }
 
 func (rd *MF) String() string {
-	return rd.MADNAME
+	return escapeName(rd.MADNAME)
 }
 
 // MG records cause no additional section processing.
@@ -1899,19 +2669,11 @@ func (rd *MG) Encode(b *dns.Wirebuf) {
 
 // Implementation of dns.Wirer
 func (rd *MG) Decode(b []byte, pos *int, sniffer dns.WireDecodeSniffer) (err error) {
-	p0 := &b[*pos]
-	if err = (*dns.DomainName)(&rd.MGNAME).Decode(b, pos, sniffer); err != nil {
-		return
-	}
-
-	if sniffer != nil {
-		sniffer(p0, &b[*pos-1], dns.SniffRDataMG, rd)
-	}
-	return
+	return decodeName(b, pos, sniffer, &rd.MGNAME, dns.SniffRDataMG, rd)
 }
 
 func (rd *MG) String() string {
-	return rd.MGNAME
+	return escapeName(rd.MGNAME)
 }
 
 // MINFO records cause no additional section processing.  Although these
@@ -1957,7 +2719,7 @@ func (rd *MINFO) Decode(b []byte, pos *int, sniffer dns.WireDecodeSniffer) (err
 }
 
 func (rd *MINFO) String() string {
-	return fmt.Sprintf("%s %s", rd.RMAILBX, rd.EMAILBX)
+	return fmt.Sprintf("%s %s", escapeName(rd.RMAILBX), escapeName(rd.EMAILBX))
 }
 
 // MR records cause no additional section processing.  The main use for MR is
@@ -1975,19 +2737,11 @@ func (rd *MR) Encode(b *dns.Wirebuf) {
 
 // Implementation of dns.Wirer
 func (rd *MR) Decode(b []byte, pos *int, sniffer dns.WireDecodeSniffer) (err error) {
-	p0 := &b[*pos]
-	if err = (*dns.DomainName)(&rd.NEWNAME).Decode(b, pos, sniffer); err != nil {
-		return
-	}
-
-	if sniffer != nil {
-		sniffer(p0, &b[*pos-1], dns.SniffRDataMR, rd)
-	}
-	return
+	return decodeName(b, pos, sniffer, &rd.NEWNAME, dns.SniffRDataMR, rd)
 }
 
 func (rd *MR) String() string {
-	return rd.NEWNAME
+	return escapeName(rd.NEWNAME)
 }
 
 // MX holds the zone MX RData
@@ -2025,7 +2779,7 @@ func (rd *MX) Decode(b []byte, pos *int, sniffer dns.WireDecodeSniffer) (err err
 }
 
 func (rd *MX) String() string {
-	return fmt.Sprintf("%d %s", rd.Preference, rd.Exchange)
+	return fmt.Sprintf("%d %s", rd.Preference, escapeName(rd.Exchange))
 }
 
 type NAPTR struct {
@@ -2152,7 +2906,71 @@ func (rd *NAPTR) Decode(b []byte, pos *int, sniffer dns.WireDecodeSniffer) (err
 }
 
 func (rd *NAPTR) String() string {
-	return fmt.Sprintf("%d %d \"%s\" \"%s\" \"%s\" %s", rd.Order, rd.Preference, quote(rd.Flags), quote(rd.Services), quote(rd.Regexp), rd.Replacement)
+	return fmt.Sprintf("%d %d \"%s\" \"%s\" \"%s\" %s", rd.Order, rd.Preference, quote(rd.Flags), quote(rd.Services), quote(rd.Regexp), escapeName(rd.Replacement))
+}
+
+// NINFO represents NINFO RR RDATA, the zone status information (Reid,
+// draft-reid-dnsext-zs-01). The format of the RDATA is identical to TXT, one
+// or more <character-string>s.
+type NINFO struct {
+	ZSData []string
+}
+
+// Implementation of dns.Wirer
+func (rd *NINFO) Encode(b *dns.Wirebuf) {
+	CharStrings(rd.ZSData).Encode(b)
+}
+
+// Implementation of dns.Wirer
+func (rd *NINFO) Decode(b []byte, pos *int, sniffer dns.WireDecodeSniffer) (err error) {
+	p0 := &b[*pos]
+	if err = (*CharStrings)(&rd.ZSData).Decode(b, pos, sniffer); err != nil {
+		return
+	}
+
+	if sniffer != nil {
+		sniffer(p0, &b[*pos-1], dns.SniffRDataNINFO, rd)
+	}
+	return
+}
+
+func (rd *NINFO) String() string {
+	return CharStrings(rd.ZSData).String()
+}
+
+// NIMLOC represents NIMLOC RR RDATA, a Nimrod Locator (Patton,
+// draft-ietf-nimrod-dns-02). The RDATA is an opaque string of octets
+// presented in hex.
+type NIMLOC struct {
+	Data []byte
+}
+
+// Implementation of dns.Wirer
+func (rd *NIMLOC) Encode(b *dns.Wirebuf) {
+	b.Buf = append(b.Buf, rd.Data...)
+}
+
+// Implementation of dns.Wirer
+func (rd *NIMLOC) Decode(b []byte, pos *int, sniffer dns.WireDecodeSniffer) (err error) {
+	if *pos >= len(b) {
+		rd.Data = []byte{}
+		if sniffer != nil {
+			sniffer(nil, nil, dns.SniffRDataNIMLOC, rd)
+		}
+		return
+	}
+
+	p0 := &b[*pos]
+	rd.Data = append([]byte{}, b[*pos:]...)
+	*pos = len(b)
+	if sniffer != nil {
+		sniffer(p0, &b[*pos-1], dns.SniffRDataNIMLOC, rd)
+	}
+	return
+}
+
+func (rd *NIMLOC) String() string {
+	return hex.EncodeToString(rd.Data)
 }
 
 // NODATA is used for negative caching of authoritative answers
@@ -2229,7 +3047,7 @@ func (rd *NS) Decode(b []byte, pos *int, sniffer dns.WireDecodeSniffer) (err err
 }
 
 func (rd *NS) String() string {
-	return rd.NSDName
+	return escapeName(rd.NSDName)
 }
 
 // The NSAP RR is used to map from domain names to NSAPs. Name-to-NSAP mapping
@@ -2274,6 +3092,27 @@ func (rd *NSAP) String() string {
 	return fmt.Sprintf("0x%x", rd.NSAP) // CANNOT be replace by `%#x`
 }
 
+// ParseNSAP parses the NSAP RR presentation format (RFC 1706/5): "0x"
+// followed by an even number of hexadecimal digits. Dots may be used to
+// separate the digits for readability, as in
+// "0x47.0005.80.005a00.0000.0001.e133.ffffff000162.00".
+func ParseNSAP(s string) (rd *NSAP, err error) {
+	if len(s) < 2 || s[0] != '0' || s[1] != 'x' && s[1] != 'X' {
+		return nil, fmt.Errorf("ParseNSAP: missing 0x prefix in %q", s)
+	}
+
+	b, err := hex.DecodeString(strings.Replace(s[2:], ".", "", -1))
+	if err != nil {
+		return nil, fmt.Errorf("ParseNSAP: %q: %s", s, err)
+	}
+
+	if len(b) == 0 {
+		return nil, fmt.Errorf("ParseNSAP: empty NSAP %q", s)
+	}
+
+	return &NSAP{b}, nil
+}
+
 // NSAP_PTR has a function analogous to the PTR record used for IP addresses
 type NSAP_PTR struct {
 	Name string
@@ -2298,7 +3137,7 @@ func (rd *NSAP_PTR) Decode(b []byte, pos *int, sniffer dns.WireDecodeSniffer) (e
 }
 
 func (rd NSAP_PTR) String() string {
-	return rd.Name
+	return escapeName(rd.Name)
 }
 
 // HashAlgorithm is the type of the hash algorithm in the NSEC3 RR
@@ -2358,13 +3197,19 @@ type NSEC struct {
 
 // Implementation of dns.Wirer
 func (rd *NSEC) Encode(b *dns.Wirebuf) {
+	b.DisableCompression()
 	(dns.DomainName)(rd.NextDomainName).Encode(b)
+	b.EnableCompression()
 	b.Buf = append(b.Buf, rd.TypeBitMaps...)
 }
 
 // Implementation of dns.Wirer
 func (rd *NSEC) Decode(b []byte, pos *int, sniffer dns.WireDecodeSniffer) (err error) {
 	p0 := &b[*pos]
+	if err = checkUncompressed(b, *pos); err != nil {
+		return fmt.Errorf("(*NSEC).Decode: %s", err)
+	}
+
 	if err = (*dns.DomainName)(&rd.NextDomainName).Decode(b, pos, sniffer); err != nil {
 		return
 	}
@@ -2379,12 +3224,40 @@ func (rd *NSEC) Decode(b []byte, pos *int, sniffer dns.WireDecodeSniffer) (err e
 }
 
 func (rd *NSEC) String() string {
-	types, err := TypesDecode(rd.TypeBitMaps)
-	if err != nil {
-		panic(err)
+	return fmt.Sprintf("%s %s", escapeName(rd.NextDomainName), bitmapString(rd.TypeBitMaps))
+}
+
+// IsMinimallyCovering reports whether rd is an on-line signed "white lie"
+// (RFC 4470) or "black lie" NSEC synthesized for qname, i.e. whether the Next
+// Domain Name is the immediate successor of qname in the canonical order,
+// qname prepended by a label consisting of a single zero octet (RFC 4470/4).
+// The owner name of such NSEC RR is either the immediate predecessor of
+// qname (white lies, proving qname doesn't exist) or qname itself (black
+// lies, claiming NODATA for qname). The owner is not a part of the RDATA, use
+// RR.IsMinimallyCoveringNSEC to check it as well.
+func (rd *NSEC) IsMinimallyCovering(qname string) bool {
+	next := dns.CanonicalName(rd.NextDomainName)
+	for _, epsilon := range []string{"\x00.", `\000.`} {
+		if !strings.HasPrefix(next, epsilon) {
+			continue
+		}
+
+		if next = next[len(epsilon):]; next == "" {
+			next = "."
+		}
+		return next == dns.CanonicalName(qname)
 	}
+	return false
+}
 
-	return fmt.Sprintf("%s %s", rd.NextDomainName, TypesString(types))
+// IsMinimallyCoveringNSEC reports whether rr is an NSEC RR whose owner and
+// Next Domain Name tightly bracket qname (RFC 4470). The Next Domain Name must
+// be the immediate successor of qname, see NSEC.IsMinimallyCovering, and the
+// owner must be either qname itself (black lies) or a name sorting before
+// qname in the canonical order (white lies).
+func (rr *RR) IsMinimallyCoveringNSEC(qname string) bool {
+	rd, ok := rr.RData.(*NSEC)
+	return ok && rd.IsMinimallyCovering(qname) && compareCanonicalNames(rr.Name, qname) <= 0
 }
 
 // The NSEC3 Resource Record (RR) provides authenticated denial of
@@ -2439,12 +3312,7 @@ func (rd *NSEC3) Decode(b []byte, pos *int, sniffer dns.WireDecodeSniffer) (err
 }
 
 func (rd *NSEC3) String() string {
-	types, err := TypesDecode(rd.TypeBitMaps)
-	if err != nil {
-		panic(err)
-	}
-
-	return fmt.Sprintf("%s %s %s", rd.NSEC3PARAM.String(), strutil.Base32ExtEncode(rd.NextHashedOwnerName), TypesString(types))
+	return fmt.Sprintf("%s %s %s", rd.NSEC3PARAM.String(), strutil.Base32ExtEncode(rd.NextHashedOwnerName), bitmapString(rd.TypeBitMaps))
 }
 
 // The NSEC3PARAM RR contains the NSEC3 parameters (hash algorithm,
@@ -2517,6 +3385,8 @@ func (rd *NSEC3PARAM) String() string {
 	return fmt.Sprintf("%d %d %d %s", rd.HashAlgorithm, rd.Flags, rd.Iterations, s)
 }
 
+// NULL represents NULL RR RDATA (RFC 1035/3.3.10).  Anything at all may be in
+// the RDATA field so long as it is 65535 octets or less.
 type NULL struct {
 	Data []byte
 }
@@ -2558,26 +3428,59 @@ func (rd *NULL) String() string {
 	return fmt.Sprintf("\\# %d %x", len(rd.Data), rd.Data)
 }
 
-// OPT_DATA holds an {attribute, value} pair of the OPT RR
-type OPT_DATA struct {
-	Code uint16
-	Data []byte
+// OPENPGPKEY holds the OPENPGPKEY RR RData (RFC 7929), an OpenPGP
+// Transferable Public Key. The owner name is derived from the hashed local
+// part of an e-mail address.
+type OPENPGPKEY struct {
+	PublicKey []byte
 }
 
 // Implementation of dns.Wirer
-func (rd *OPT_DATA) Encode(b *dns.Wirebuf) {
-	dns.Octets2(rd.Code).Encode(b)
-	dns.Octets2(len(rd.Data)).Encode(b)
-	b.Buf = append(b.Buf, rd.Data...)
+func (rd *OPENPGPKEY) Encode(b *dns.Wirebuf) {
+	b.Buf = append(b.Buf, rd.PublicKey...)
 }
 
 // Implementation of dns.Wirer
-func (rd *OPT_DATA) Decode(b []byte, pos *int, sniffer dns.WireDecodeSniffer) (err error) {
+func (rd *OPENPGPKEY) Decode(b []byte, pos *int, sniffer dns.WireDecodeSniffer) (err error) {
 	p0 := &b[*pos]
-	if err = (*dns.Octets2)(&rd.Code).Decode(b, pos, sniffer); err != nil {
-		return
+	n := len(b) - *pos
+	if n <= 0 {
+		return fmt.Errorf("(*OPENPGPKEY).Decode: no key data")
 	}
-	var n dns.Octets2
+
+	rd.PublicKey = make([]byte, n)
+	copy(rd.PublicKey, b[*pos:])
+	*pos += n
+	if sniffer != nil {
+		sniffer(p0, &b[*pos-1], dns.SniffRDataOPENPGPKEY, rd)
+	}
+	return
+}
+
+func (rd *OPENPGPKEY) String() string {
+	return string(strutil.Base64Encode(rd.PublicKey))
+}
+
+// OPT_DATA holds an {attribute, value} pair of the OPT RR
+type OPT_DATA struct {
+	Code uint16 `json:"code"`
+	Data []byte `json:"data"`
+}
+
+// Implementation of dns.Wirer
+func (rd *OPT_DATA) Encode(b *dns.Wirebuf) {
+	dns.Octets2(rd.Code).Encode(b)
+	dns.Octets2(len(rd.Data)).Encode(b)
+	b.Buf = append(b.Buf, rd.Data...)
+}
+
+// Implementation of dns.Wirer
+func (rd *OPT_DATA) Decode(b []byte, pos *int, sniffer dns.WireDecodeSniffer) (err error) {
+	p0 := &b[*pos]
+	if err = (*dns.Octets2)(&rd.Code).Decode(b, pos, sniffer); err != nil {
+		return
+	}
+	var n dns.Octets2
 	if err = n.Decode(b, pos, sniffer); err != nil {
 		return
 	}
@@ -2708,7 +3611,7 @@ func (rd *PTR) Decode(b []byte, pos *int, sniffer dns.WireDecodeSniffer) (err er
 }
 
 func (rd *PTR) String() string {
-	return rd.PTRDName
+	return escapeName(rd.PTRDName)
 }
 
 type PX struct {
@@ -2753,7 +3656,7 @@ func (rd *PX) Decode(b []byte, pos *int, sniffer dns.WireDecodeSniffer) (err err
 }
 
 func (rd *PX) String() string {
-	return fmt.Sprintf("%d %s %s", rd.Preference, rd.MAP822, rd.MAPX400)
+	return fmt.Sprintf("%d %s %s", rd.Preference, escapeName(rd.MAP822), escapeName(rd.MAPX400))
 }
 
 // RDATA hodls DNS RR rdata for a unknown/unsupported RR type (RFC3597).
@@ -2792,6 +3695,32 @@ func (rd *RDATA) String() string {
 	return "\\# 0"
 }
 
+// ParseGeneric sets rd from the RFC 3597/5 generic presentation form, ie. the
+// tokens "\#", the RDATA length in octets and zero or more words of hex
+// digits.
+func (rd *RDATA) ParseGeneric(tokens []string) (err error) {
+	if len(tokens) < 2 || tokens[0] != `\#` {
+		return fmt.Errorf("(*RDATA).ParseGeneric(%q): expected \\# <length> [<hex>...]", tokens)
+	}
+
+	n, err := strconv.ParseUint(tokens[1], 10, 16)
+	if err != nil {
+		return fmt.Errorf("(*RDATA).ParseGeneric(%q): invalid length %q", tokens, tokens[1])
+	}
+
+	b, err := hex.DecodeString(strings.Join(tokens[2:], ""))
+	if err != nil {
+		return fmt.Errorf("(*RDATA).ParseGeneric(%q): %s", tokens, err)
+	}
+
+	if len(b) != int(n) {
+		return fmt.Errorf("(*RDATA).ParseGeneric(%q): length %d, got %d octets", tokens, n, len(b))
+	}
+
+	*rd = b
+	return
+}
+
 // RR holds a zone resource record data.
 type RR struct {
 	// An owner name, i.e., the name of the node to which this resource record pertains.
@@ -2813,16 +3742,219 @@ type RR struct {
 	RData dns.Wirer
 }
 
+// NewRR returns a new RR of class IN owned by name. The RR type is inferred
+// from the concrete type of rdata, NewRR returns nil if it can't be, e.g. for
+// a *RDATA. For other classes set the Class field of the result.
+func NewRR(name string, ttl int32, rdata dns.Wirer) *RR {
+	t, ok := rdataType(rdata)
+	if !ok {
+		return nil
+	}
+
+	return &RR{name, t, CLASS_IN, ttl, rdata}
+}
+
+// Header holds the fields of a resource record other than its RData.
+type Header struct {
+	Name string
+	Type
+	Class
+	TTL int32
+}
+
+// Header returns the header of rr.
+func (rr *RR) Header() Header {
+	return Header{rr.Name, rr.Type, rr.Class, rr.TTL}
+}
+
+// SetHeader sets the name, type, class and TTL of rr from h. The RData of rr
+// is not changed.
+func (rr *RR) SetHeader(h Header) {
+	rr.Name, rr.Type, rr.Class, rr.TTL = h.Name, h.Type, h.Class, h.TTL
+}
+
+// Copy returns a deep copy of rr. No part of the result, including any byte
+// slices, IP addresses or maps of its RData, shares memory with rr.
+func (rr *RR) Copy() *RR {
+	if rr == nil {
+		return nil
+	}
+
+	y := *rr
+	if rr.RData != nil {
+		y.RData = deepCopy(reflect.ValueOf(rr.RData)).Interface().(dns.Wirer)
+	}
+	return &y
+}
+
+// WithTTL returns a copy of rr, see Copy, with the TTL set to ttl.
+func (rr *RR) WithTTL(ttl int32) *RR {
+	y := rr.Copy()
+	y.TTL = ttl
+	return y
+}
+
+// IP returns the address of an A or AAAA record rr. Ok is false for records
+// of other types. The returned IP is not a copy of the RData Address.
+func (rr *RR) IP() (ip net.IP, ok bool) {
+	switch x := rr.RData.(type) {
+	case *A:
+		return x.Address, true
+	case *AAAA:
+		return x.Address, true
+	}
+	return
+}
+
+// IsAddress reports whether rr is an A or AAAA record.
+func (rr *RR) IsAddress() bool {
+	_, ok := rr.IP()
+	return ok
+}
+
+func deepCopy(v reflect.Value) reflect.Value {
+	t := v.Type()
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return v
+		}
+
+		y := reflect.New(t.Elem())
+		y.Elem().Set(deepCopy(v.Elem()))
+		return y
+	case reflect.Interface:
+		y := reflect.New(t).Elem()
+		if !v.IsNil() {
+			y.Set(deepCopy(v.Elem()))
+		}
+		return y
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+
+		y := reflect.MakeSlice(t, v.Len(), v.Len())
+		if t.Elem().Kind() == reflect.Uint8 {
+			reflect.Copy(y, v)
+			return y
+		}
+
+		for i := 0; i < v.Len(); i++ {
+			y.Index(i).Set(deepCopy(v.Index(i)))
+		}
+		return y
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+
+		y := reflect.MakeMap(t)
+		for _, k := range v.MapKeys() {
+			y.SetMapIndex(deepCopy(k), deepCopy(v.MapIndex(k)))
+		}
+		return y
+	case reflect.Struct:
+		y := reflect.New(t).Elem()
+		y.Set(v) // unexported fields, eg. of time.Time, are copied shallow
+		for i := 0; i < v.NumField(); i++ {
+			if t.Field(i).PkgPath == "" {
+				y.Field(i).Set(deepCopy(v.Field(i)))
+			}
+		}
+		return y
+	case reflect.Array:
+		y := reflect.New(t).Elem()
+		for i := 0; i < v.Len(); i++ {
+			y.Index(i).Set(deepCopy(v.Index(i)))
+		}
+		return y
+	}
+	return v
+}
+
+// ParseTTL parses a TTL in the presentation format: a number of seconds, like
+// "3600", or a sequence of numbers with units s, m, h, d or w (seconds,
+// minutes, hours, days or weeks, case insensitive), like "1h30m" or "2w3d".
+// A number without a unit at the end of the sequence is in seconds. The
+// result must fit in 0..2^31-1.
+func ParseTTL(s string) (ttl int32, err error) {
+	if s == "" {
+		return 0, fmt.Errorf("ParseTTL: empty TTL")
+	}
+
+	var total, n int64
+	digits := false
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c >= '0' && c <= '9' {
+			if n = 10*n + int64(c-'0'); n > math.MaxInt32 {
+				return 0, fmt.Errorf("ParseTTL: %q overflows", s)
+			}
+
+			digits = true
+			continue
+		}
+
+		var unit int64
+		switch c {
+		case 's', 'S':
+			unit = 1
+		case 'm', 'M':
+			unit = 60
+		case 'h', 'H':
+			unit = 3600
+		case 'd', 'D':
+			unit = 86400
+		case 'w', 'W':
+			unit = 604800
+		default:
+			return 0, fmt.Errorf("ParseTTL: invalid TTL %q", s)
+		}
+
+		if !digits {
+			return 0, fmt.Errorf("ParseTTL: invalid TTL %q", s)
+		}
+
+		if total += n * unit; total > math.MaxInt32 {
+			return 0, fmt.Errorf("ParseTTL: %q overflows", s)
+		}
+
+		n, digits = 0, false
+	}
+
+	if total += n; total > math.MaxInt32 {
+		return 0, fmt.Errorf("ParseTTL: %q overflows", s)
+	}
+
+	return int32(total), nil
+}
+
+// genericRData returns the RData of rr as RFC 3597 generic RDATA.
+func (rr *RR) genericRData() *RDATA {
+	w := dns.NewWirebuf()
+	w.DisableCompression()
+	rr.RData.Encode(w)
+	rd := RDATA(w.Buf)
+	return &rd
+}
+
+// GenericString is like String but it renders the RData of rr in the RFC
+// 3597/5 generic form, whatever its type is. Intended for debugging.
+func (rr *RR) GenericString() string {
+	return fmt.Sprintf("%s\t%s\t%d\t%s %s", escapeName(rr.Name), rr.Class, rr.TTL, rr.Type, rr.genericRData())
+}
+
 func (rr *RR) String() string {
 	switch rr.Type {
 	default:
-		return fmt.Sprintf("%s\t%s\t%d\t%s %s", rr.Name, rr.Class, rr.TTL, rr.Type, rr.RData)
+		return fmt.Sprintf("%s\t%s\t%d\t%s %s", escapeName(rr.Name), rr.Class, rr.TTL, rr.Type, rr.RData)
 	case TYPE_OPT:
 		r := &EXT_RCODE{}
 		r.FromTTL(rr.TTL)
 		return fmt.Sprintf(
 			"%s\t%d\t%s\t%s %s",
-			rr.Name,
+			escapeName(rr.Name),
 			uint16(rr.Class),
 			r,
 			rr.Type,
@@ -2832,174 +3964,351 @@ func (rr *RR) String() string {
 	panic("unreachable")
 }
 
-// Implementation of dns.Wirer
+// Implementation of dns.Wirer. Encode doesn't check rr, use EncodeErr to
+// reject e.g. RDATA longer than 65535 octets.
 func (rr *RR) Encode(b *dns.Wirebuf) {
+	rr.encode(b, nil)
+}
+
+// EncodeInto appends rr to b like Encode. It's meant for a b reused for many
+// messages, emptied by dns.Wirebuf.Reset before each of them. Reset keeps the
+// capacity of b.Buf and of the compression table, so once b grows large
+// enough, encoding into it doesn't allocate.
+func (rr *RR) EncodeInto(b *dns.Wirebuf) {
+	rr.encode(b, nil)
+}
+
+// encode encodes rr to b. If mark is not nil it's called after every RR field
+// with the field name and the offset of the field start in b.Buf. An error is
+// returned if the RDATA doesn't fit the RDLENGTH field, b.Buf then holds the
+// RR with a truncated RDLENGTH.
+func (rr *RR) encode(b *dns.Wirebuf, mark func(field string, p0 int)) (err error) {
+	if mark == nil {
+		mark = func(string, int) {}
+	}
+
+	p := len(b.Buf)
 	dns.DomainName(rr.Name).Encode(b)
+	mark("name", p)
+	p = len(b.Buf)
 	rr.Type.Encode(b)
+	mark("type", p)
+	p = len(b.Buf)
 	rr.Class.Encode(b)
+	mark("class", p)
+	p = len(b.Buf)
 	dns.Octets4(rr.TTL).Encode(b)
+	mark("ttl", p)
 	p0 := len(b.Buf)
 	b.Buf = append(b.Buf, 0, 0)
+	mark("rdlength", p0)
+	p = len(b.Buf)
 	rr.RData.Encode(b)
+	mark("rdata", p)
 	n := len(b.Buf) - (p0 + 2)
+	if n > 0xFFFF {
+		err = fmt.Errorf("can't encode %s RR %s, RDATA length %d > 65535", rr.Type, rr.Name, n)
+	}
+
 	b.Buf[p0] = byte(n >> 8)
 	b.Buf[p0+1] = byte(n)
+	return
 }
 
-// Implementation of dns.Wirer
-func (rr *RR) Decode(b []byte, pos *int, sniffer dns.WireDecodeSniffer) (err error) {
-	if *pos >= len(b) {
-		return fmt.Errorf("(*rr.RR).Decode() - buffer underflow, len(b) %d(%#x), pos %d(%#x)", len(b), len(b), *pos, *pos)
+// EncodeErr is like Encode but returns an error if rr can't be encoded. rr is
+// checked using Check and Validate first. This catches data Encode would
+// silently get wrong, like an IPv6 address or a MX RDATA in an A RR. An
+// RDATA longer than 65535 octets is an error as well. On error b is restored
+// to the state before the call.
+func (rr *RR) EncodeErr(b *dns.Wirebuf) (err error) {
+	if err = rr.Check(); err != nil {
+		return fmt.Errorf("(*RR).EncodeErr: %s", err)
 	}
 
-	p0 := &b[*pos]
-	if err = (*dns.DomainName)(&rr.Name).Decode(b, pos, sniffer); err != nil {
-		return
+	if err = rr.Validate(); err != nil {
+		return fmt.Errorf("(*RR).EncodeErr: %s", err)
 	}
 
-	if err = (*dns.Octets2)(&rr.Type).Decode(b, pos, sniffer); err != nil {
-		return
+	p := len(b.Buf)
+	if err = rr.encode(b, nil); err != nil {
+		b.Truncate(p)
+		return fmt.Errorf("(*RR).EncodeErr: %s", err)
 	}
 
-	if err = (*dns.Octets2)(&rr.Class).Decode(b, pos, sniffer); err != nil {
-		return
+	return
+}
+
+// HexDump returns a hexdump -C like listing of rr in the wire format followed
+// by the offsets of the individual RR fields. The same encoder as in Encode is
+// used, so the dump shows exactly what goes to the wire (using a fresh
+// dns.Wirebuf, so with name compression enabled).
+func (rr *RR) HexDump() string {
+	type field struct {
+		name     string
+		from, to int
 	}
 
-	var ttl dns.Octets4
-	if err = ttl.Decode(b, pos, sniffer); err != nil {
-		return
+	var fields []field
+	w := dns.NewWirebuf()
+	rr.encode(w, func(name string, p0 int) {
+		fields = append(fields, field{name, p0, len(w.Buf)})
+	})
+
+	buf := &bytes.Buffer{}
+	for y := 0; y < len(w.Buf); y += 16 {
+		line := w.Buf[y:]
+		if len(line) > 16 {
+			line = line[:16]
+		}
+
+		fmt.Fprintf(buf, "%08x ", y)
+		for i := 0; i < 16; i++ {
+			if i == 8 {
+				buf.WriteByte(' ')
+			}
+			if i < len(line) {
+				fmt.Fprintf(buf, " %02x", line[i])
+			} else {
+				buf.WriteString("   ")
+			}
+		}
+		buf.WriteString("  |")
+		for _, c := range line {
+			if c < 0x20 || c > 0x7e {
+				c = '.'
+			}
+			buf.WriteByte(c)
+		}
+		buf.WriteString("|\n")
 	}
+	fmt.Fprintf(buf, "%08x\n", len(w.Buf))
+	for _, f := range fields {
+		fmt.Fprintf(buf, "%-8s %08x-%08x %d\n", f.name, f.from, f.to, f.to-f.from)
+	}
+	return buf.String()
+}
 
-	rr.TTL = int32(ttl)
+// rdataTypes maps the concrete RData types returned by newRData to their RR
+// types. Concrete types used by more than one RR type are not included.
+var rdataTypes = map[reflect.Type]Type{}
 
-	var rdlength dns.Octets2
-	if err = rdlength.Decode(b, pos, sniffer); err != nil {
+func init() {
+	ambiguous := map[reflect.Type]bool{}
+	for t := range Types {
+		rd := newRData(t)
+		if _, ok := rd.(*RDATA); ok {
+			continue
+		}
+
+		k := reflect.TypeOf(rd)
+		if _, ok := rdataTypes[k]; ok {
+			ambiguous[k] = true
+		}
+		rdataTypes[k] = t
+	}
+	for k := range ambiguous {
+		delete(rdataTypes, k)
+	}
+}
+
+// rdataType returns the RR type of the concrete RData type of d. It reports
+// false for a *RDATA or for RData not used by exactly one RR type.
+func rdataType(d dns.Wirer) (t Type, ok bool) {
+	if d == nil {
 		return
 	}
 
-	switch rr.Type {
+	t, ok = rdataTypes[reflect.TypeOf(d)]
+	return
+}
+
+// newRData returns a zero value RData for RR type t. Types not supported by
+// this package get a *RDATA.
+func newRData(t Type) dns.Wirer {
+	switch t {
 	case TYPE_A:
-		rr.RData = &A{}
+		return &A{}
 	case TYPE_AAAA:
-		rr.RData = &AAAA{}
+		return &AAAA{}
 	case TYPE_AFSDB:
-		rr.RData = &AFSDB{}
+		return &AFSDB{}
+	case TYPE_APL:
+		return &APL{}
+	case TYPE_ATMA:
+		return &ATMA{}
 	case TYPE_CERT:
-		rr.RData = &CERT{}
+		return &CERT{}
 	case TYPE_CNAME:
-		rr.RData = &CNAME{}
+		return &CNAME{}
+	case TYPE_CSYNC:
+		return &CSYNC{}
 	case TYPE_DHCID:
-		rr.RData = &DHCID{}
+		return &DHCID{}
 	case TYPE_DLV:
-		rr.RData = &DLV{}
+		return &DLV{}
 	case TYPE_DNAME:
-		rr.RData = &DNAME{}
+		return &DNAME{}
 	case TYPE_DNSKEY:
-		rr.RData = &DNSKEY{}
+		return &DNSKEY{}
 	case TYPE_DS:
-		rr.RData = &DS{}
+		return &DS{}
+	case TYPE_EID:
+		return &EID{}
 	case TYPE_GPOS:
-		rr.RData = &GPOS{}
+		return &GPOS{}
 	case TYPE_HINFO:
-		rr.RData = &HINFO{}
+		return &HINFO{}
 	case TYPE_HIP:
-		rr.RData = &HIP{}
+		return &HIP{}
+	case TYPE_HTTPS:
+		return &HTTPS{}
 	case TYPE_IPSECKEY:
-		rr.RData = &IPSECKEY{}
+		return &IPSECKEY{}
 	case TYPE_ISDN:
-		rr.RData = &ISDN{}
+		return &ISDN{}
 	case TYPE_KEY:
-		rr.RData = &KEY{}
+		return &KEY{}
 	case TYPE_KX:
-		rr.RData = &KX{}
+		return &KX{}
 	case TYPE_LOC:
-		rr.RData = &LOC{}
+		return &LOC{}
 	case TYPE_MB:
-		rr.RData = &MB{}
+		return &MB{}
 	case TYPE_MD:
-		rr.RData = &MD{}
+		return &MD{}
 	case TYPE_MF:
-		rr.RData = &MF{}
+		return &MF{}
 	case TYPE_MG:
-		rr.RData = &MG{}
+		return &MG{}
 	case TYPE_MINFO:
-		rr.RData = &MINFO{}
+		return &MINFO{}
 	case TYPE_MR:
-		rr.RData = &MR{}
+		return &MR{}
 	case TYPE_MX:
-		rr.RData = &MX{}
+		return &MX{}
 	case TYPE_NAPTR:
-		rr.RData = &NAPTR{}
+		return &NAPTR{}
+	case TYPE_NINFO:
+		return &NINFO{}
+	case TYPE_NIMLOC:
+		return &NIMLOC{}
 	case TYPE_NODATA:
-		rr.RData = &NODATA{}
+		return &NODATA{}
 	case TYPE_NS:
-		rr.RData = &NS{}
+		return &NS{}
 	case TYPE_NSAP:
-		rr.RData = &NSAP{}
+		return &NSAP{}
 	case TYPE_NSAP_PTR:
-		rr.RData = &NSAP_PTR{}
+		return &NSAP_PTR{}
 	case TYPE_NXDOMAIN:
-		rr.RData = &NXDOMAIN{}
+		return &NXDOMAIN{}
 	case TYPE_NSEC:
-		rr.RData = &NSEC{}
+		return &NSEC{}
 	case TYPE_NSEC3:
-		rr.RData = &NSEC3{}
+		return &NSEC3{}
 	case TYPE_NSEC3PARAM:
-		rr.RData = &NSEC3PARAM{}
+		return &NSEC3PARAM{}
 	case TYPE_NULL:
-		rr.RData = &NULL{}
+		return &NULL{}
+	case TYPE_OPENPGPKEY:
+		return &OPENPGPKEY{}
 	case TYPE_OPT:
-		rr.RData = &OPT{}
+		return &OPT{}
 	case TYPE_PTR:
-		rr.RData = &PTR{}
+		return &PTR{}
 	case TYPE_PX:
-		rr.RData = &PX{}
+		return &PX{}
 	case TYPE_RP:
-		rr.RData = &RP{}
+		return &RP{}
 	case TYPE_RRSIG:
-		rr.RData = &RRSIG{}
+		return &RRSIG{}
 	case TYPE_RT:
-		rr.RData = &RT{}
+		return &RT{}
 	case TYPE_SIG:
-		rr.RData = &SIG{}
+		return &SIG{}
+	case TYPE_SMIMEA:
+		return &SMIMEA{}
 	case TYPE_SOA:
-		rr.RData = &SOA{}
+		return &SOA{}
 	case TYPE_SPF:
-		rr.RData = &SPF{}
+		return &SPF{}
 	case TYPE_SRV:
-		rr.RData = &SRV{}
+		return &SRV{}
 	case TYPE_SSHFP:
-		rr.RData = &SSHFP{}
+		return &SSHFP{}
+	case TYPE_SVCB:
+		return &SVCB{}
 	case TYPE_TA:
-		rr.RData = &TA{}
+		return &TA{}
 	case TYPE_TALINK:
-		rr.RData = &TALINK{}
+		return &TALINK{}
 	case TYPE_TKEY:
-		rr.RData = &TKEY{}
+		return &TKEY{}
 	case TYPE_TLSA:
-		rr.RData = &TLSA{}
+		return &TLSA{}
 	case TYPE_TSIG:
-		rr.RData = &TSIG{}
+		return &TSIG{}
 	case TYPE_TXT:
-		rr.RData = &TXT{}
+		return &TXT{}
 	case TYPE_URI:
-		rr.RData = &URI{}
+		return &URI{}
 	case TYPE_WKS:
-		rr.RData = &WKS{}
+		return &WKS{}
 	case TYPE_X25:
-		rr.RData = &X25{}
+		return &X25{}
+	case TYPE_ZONEMD:
+		return &ZONEMD{}
 	default:
-		rr.RData = &RDATA{}
+		return &RDATA{}
+	}
+}
+
+// Implementation of dns.Wirer
+func (rr *RR) Decode(b []byte, pos *int, sniffer dns.WireDecodeSniffer) (err error) {
+	if *pos >= len(b) {
+		return fmt.Errorf("(*rr.RR).Decode() - buffer underflow, len(b) %d(%#x), pos %d(%#x)", len(b), len(b), *pos, *pos)
+	}
+
+	p0 := &b[*pos]
+	if err = (*dns.DomainName)(&rr.Name).Decode(b, pos, sniffer); err != nil {
+		return
+	}
+
+	if err = (*dns.Octets2)(&rr.Type).Decode(b, pos, sniffer); err != nil {
+		return
+	}
+
+	if err = (*dns.Octets2)(&rr.Class).Decode(b, pos, sniffer); err != nil {
+		return
+	}
+
+	var ttl dns.Octets4
+	if err = ttl.Decode(b, pos, sniffer); err != nil {
+		return
+	}
+
+	rr.TTL = int32(ttl)
+
+	var rdlength dns.Octets2
+	if err = rdlength.Decode(b, pos, sniffer); err != nil {
+		return
 	}
 
-	if *pos+int(rdlength) > len(b) {
-		return fmt.Errorf("malformed packet, len(RData) %d, len(buf) %d", rdlength, len(b)-*pos)
+	rr.RData = newRData(rr.Type)
+
+	end := *pos + int(rdlength)
+	if end > len(b) {
+		return fmt.Errorf("(*rr.RR).Decode() - buffer underflow, len(RData) %d, len(buf) %d", rdlength, len(b)-*pos)
 	}
 
 	if rdlength != 0 {
-		if err = rr.RData.Decode(b[:*pos+int(rdlength)], pos, sniffer); err != nil {
+		if err = rr.RData.Decode(b[:end], pos, sniffer); err != nil {
 			return
 		}
+
+		if *pos != end {
+			return fmt.Errorf("(*rr.RR).Decode() - %s RDATA length %d, decoded %d", rr.Type, rdlength, int(rdlength)-(end-*pos))
+		}
 	}
 
 	if sniffer != nil {
@@ -3008,20 +4317,70 @@ func (rr *RR) Decode(b []byte, pos *int, sniffer dns.WireDecodeSniffer) (err err
 	return
 }
 
+// SafeDecodeRR decodes a RR from b at *pos like RR.Decode does. Any panic
+// while decoding, e.g. due to malformed input from an untrusted source, is
+// returned as an error.
+func SafeDecodeRR(b []byte, pos *int) (rr *RR, err error) {
+	defer func() {
+		if e := recover(); e != nil {
+			rr, err = nil, fmt.Errorf("SafeDecodeRR: %v", e)
+		}
+	}()
+
+	rr = &RR{}
+	if err = rr.Decode(b, pos, nil); err != nil {
+		return nil, err
+	}
+
+	return
+}
+
+// wireBytes returns the uncompressed wire form of w.
+func wireBytes(w dns.Wirer) []byte {
+	b := dns.NewWirebuf()
+	b.DisableCompression()
+	w.Encode(b)
+	return b.Buf
+}
+
+// RDataLen returns the length of the uncompressed wire form of w. Within a
+// message the RDATA of some RR types may be shorter because of name
+// compression but never longer. RDATA longer than 65535 octets can't be
+// encoded.
+func RDataLen(w dns.Wirer) int {
+	return len(wireBytes(w))
+}
+
+// rdataEqual compares the canonical wire form (RFC 4034/6.2) of a and b. It's
+// the fallback of RR.Equal for RData types without a dedicated case, so new
+// RData types don't need one unless they embed domain names not covered by
+// the canonical form. Nil RData is equal only to nil RData.
+func rdataEqual(a, b dns.Wirer) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+
+	return bytes.Equal(canonicalRData(a), canonicalRData(b))
+}
+
 // Equal compares a and b as per rfc2136/1.1
 func (a *RR) Equal(b *RR) (equal bool) {
 	//defer func() {
 	//fmt.Printf("Equal(%q vs %q):%t\n", a, b, equal)
 	//}()
 
-	if a.Type != b.Type || a.Class != b.Class || strings.ToLower(a.Name) != strings.ToLower(b.Name) {
+	if a.Type != b.Type || a.Class != b.Class || !dns.NamesEqual(a.Name, b.Name) {
 		return
 	}
 
 	// Name, Type, Class match
+	if reflect.TypeOf(a.RData) != reflect.TypeOf(b.RData) {
+		return rdataEqual(a.RData, b.RData)
+	}
+
 	switch x := a.RData.(type) {
 	default:
-		log.Fatalf("rr.RR.Equal() - internal error %T", x)
+		return rdataEqual(a.RData, b.RData)
 	case *RDATA:
 		return bytes.Equal(*x, *b.RData.(*RDATA))
 	case *A:
@@ -3031,7 +4390,26 @@ func (a *RR) Equal(b *RR) (equal bool) {
 	case *AFSDB:
 		y := b.RData.(*AFSDB)
 		return x.SubType == y.SubType &&
-			strings.ToLower(x.Hostname) == strings.ToLower(y.Hostname)
+			dns.NamesEqual(x.Hostname, y.Hostname)
+	case *APL:
+		y := b.RData.(*APL)
+		if len(x.Items) != len(y.Items) {
+			return false
+		}
+
+		for i, v := range x.Items {
+			w := y.Items[i]
+			if v.Family != w.Family ||
+				v.Prefix != w.Prefix ||
+				v.Negation != w.Negation ||
+				!bytes.Equal(bytes.TrimRight(v.AFD, "\x00"), bytes.TrimRight(w.AFD, "\x00")) {
+				return false
+			}
+		}
+		return true
+	case *ATMA:
+		y := b.RData.(*ATMA)
+		return x.Format == y.Format && bytes.Equal(x.Address, y.Address)
 	case *CERT:
 		y := b.RData.(*CERT)
 		return x.Type == y.Type &&
@@ -3039,7 +4417,12 @@ func (a *RR) Equal(b *RR) (equal bool) {
 			x.Algorithm == y.Algorithm &&
 			bytes.Equal(x.Cert, y.Cert)
 	case *CNAME:
-		return strings.ToLower(x.Name) == strings.ToLower(b.RData.(*CNAME).Name)
+		return dns.NamesEqual(x.Name, b.RData.(*CNAME).Name)
+	case *CSYNC:
+		y := b.RData.(*CSYNC)
+		return x.SOASerial == y.SOASerial &&
+			x.Flags == y.Flags &&
+			bytes.Equal(x.TypeBitMaps, y.TypeBitMaps)
 	case *DHCID:
 		y := b.RData.(*DHCID)
 		return bytes.Equal(x.Data, y.Data)
@@ -3050,7 +4433,7 @@ func (a *RR) Equal(b *RR) (equal bool) {
 			x.DigestType == y.DigestType &&
 			bytes.Equal(x.Digest, y.Digest)
 	case *DNAME:
-		return strings.ToLower(x.Name) == strings.ToLower(b.RData.(*DNAME).Name)
+		return dns.NamesEqual(x.Name, b.RData.(*DNAME).Name)
 	case *DNSKEY:
 		y := b.RData.(*DNSKEY)
 		return x.Flags == y.Flags &&
@@ -3063,6 +4446,8 @@ func (a *RR) Equal(b *RR) (equal bool) {
 			x.Algorithm == y.Algorithm &&
 			x.DigestType == y.DigestType &&
 			bytes.Equal(x.Digest, y.Digest)
+	case *EID:
+		return bytes.Equal(x.Data, b.RData.(*EID).Data)
 	case *GPOS:
 		y := b.RData.(*GPOS)
 		return x.Longitude == y.Longitude &&
@@ -3071,16 +4456,18 @@ func (a *RR) Equal(b *RR) (equal bool) {
 	case *HINFO:
 		y := b.RData.(*HINFO)
 		return x.Cpu == y.Cpu && x.Os == y.Os
+	case *HTTPS:
+		return x.equal(&b.RData.(*HTTPS).SVCB)
 	case *HIP:
 		y := b.RData.(*HIP)
-		if y.PKAlgorithm != y.PKAlgorithm ||
+		if x.PKAlgorithm != y.PKAlgorithm ||
 			!bytes.Equal(x.HIT, y.HIT) ||
 			!bytes.Equal(x.PublicKey, y.PublicKey) ||
 			len(x.RendezvousServers) != len(y.RendezvousServers) {
 			return false
 		}
 		for i, v := range x.RendezvousServers {
-			if strings.ToLower(v) != strings.ToLower(y.RendezvousServers[i]) {
+			if !dns.NamesEqual(v, y.RendezvousServers[i]) {
 				return false
 			}
 		}
@@ -3135,7 +4522,7 @@ func (a *RR) Equal(b *RR) (equal bool) {
 	case *KX:
 		y := b.RData.(*KX)
 		return x.Preference == y.Preference &&
-			strings.ToLower(x.Exchanger) == strings.ToLower(y.Exchanger)
+			dns.NamesEqual(x.Exchanger, y.Exchanger)
 	case *LOC:
 		y := b.RData.(*LOC)
 		return x.Version == y.Version &&
@@ -3147,27 +4534,27 @@ func (a *RR) Equal(b *RR) (equal bool) {
 			x.Altitude == y.Altitude
 	case *MB:
 		y := b.RData.(*MB)
-		return strings.ToLower(x.MADNAME) == strings.ToLower(y.MADNAME)
+		return dns.NamesEqual(x.MADNAME, y.MADNAME)
 	case *MD:
 		y := b.RData.(*MD)
-		return strings.ToLower(x.MADNAME) == strings.ToLower(y.MADNAME)
+		return dns.NamesEqual(x.MADNAME, y.MADNAME)
 	case *MF:
 		y := b.RData.(*MF)
-		return strings.ToLower(x.MADNAME) == strings.ToLower(y.MADNAME)
+		return dns.NamesEqual(x.MADNAME, y.MADNAME)
 	case *MG:
 		y := b.RData.(*MG)
-		return strings.ToLower(x.MGNAME) == strings.ToLower(y.MGNAME)
+		return dns.NamesEqual(x.MGNAME, y.MGNAME)
 	case *MINFO:
 		y := b.RData.(*MINFO)
-		return strings.ToLower(x.RMAILBX) == strings.ToLower(y.RMAILBX) &&
-			strings.ToLower(x.EMAILBX) == strings.ToLower(y.EMAILBX)
+		return dns.NamesEqual(x.RMAILBX, y.RMAILBX) &&
+			dns.NamesEqual(x.EMAILBX, y.EMAILBX)
 	case *MR:
 		y := b.RData.(*MR)
-		return strings.ToLower(x.NEWNAME) == strings.ToLower(y.NEWNAME)
+		return dns.NamesEqual(x.NEWNAME, y.NEWNAME)
 	case *MX:
 		y := b.RData.(*MX)
 		return x.Preference == y.Preference &&
-			strings.ToLower(x.Exchange) == strings.ToLower(y.Exchange)
+			dns.NamesEqual(x.Exchange, y.Exchange)
 	case *NAPTR:
 		y := b.RData.(*NAPTR)
 		return x.Order == y.Order &&
@@ -3175,7 +4562,22 @@ func (a *RR) Equal(b *RR) (equal bool) {
 			x.Flags == y.Flags &&
 			x.Services == y.Services &&
 			x.Regexp == y.Regexp &&
-			strings.ToLower(x.Replacement) == strings.ToLower(y.Replacement)
+			dns.NamesEqual(x.Replacement, y.Replacement)
+	case *NINFO:
+		y := b.RData.(*NINFO)
+		if len(x.ZSData) != len(y.ZSData) {
+			return false
+		}
+
+		for i, s := range x.ZSData {
+			if s != y.ZSData[i] {
+				return false
+			}
+		}
+
+		return true
+	case *NIMLOC:
+		return bytes.Equal(x.Data, b.RData.(*NIMLOC).Data)
 	case *NODATA:
 		y := b.RData.(*NODATA)
 		return x.Type == y.Type
@@ -3183,11 +4585,11 @@ func (a *RR) Equal(b *RR) (equal bool) {
 		return true
 	case *NS:
 		y := b.RData.(*NS)
-		return strings.ToLower(x.NSDName) == strings.ToLower(y.NSDName)
+		return dns.NamesEqual(x.NSDName, y.NSDName)
 	case *NSAP:
 		return bytes.Compare(x.NSAP, b.RData.(*NSAP).NSAP) == 0
 	case *NSAP_PTR:
-		return strings.ToLower(x.Name) == strings.ToLower(b.RData.(*NSAP_PTR).Name)
+		return dns.NamesEqual(x.Name, b.RData.(*NSAP_PTR).Name)
 	case *NSEC:
 		y := b.RData.(*NSEC)
 		return x.NextDomainName == y.NextDomainName &&
@@ -3209,6 +4611,8 @@ func (a *RR) Equal(b *RR) (equal bool) {
 	case *NULL:
 		y := b.RData.(*NULL)
 		return bytes.Equal(x.Data, y.Data)
+	case *OPENPGPKEY:
+		return bytes.Equal(x.PublicKey, b.RData.(*OPENPGPKEY).PublicKey)
 	case *OPT:
 		y := b.RData.(*OPT)
 		if len(x.Values) != len(y.Values) {
@@ -3228,16 +4632,16 @@ func (a *RR) Equal(b *RR) (equal bool) {
 		return true
 	case *PTR:
 		y := b.RData.(*PTR)
-		return strings.ToLower(x.PTRDName) == strings.ToLower(y.PTRDName)
+		return dns.NamesEqual(x.PTRDName, y.PTRDName)
 	case *PX:
 		y := b.RData.(*PX)
 		return x.Preference == y.Preference &&
-			strings.ToLower(x.MAP822) == strings.ToLower(y.MAP822) &&
-			strings.ToLower(x.MAPX400) == strings.ToLower(y.MAPX400)
+			dns.NamesEqual(x.MAP822, y.MAP822) &&
+			dns.NamesEqual(x.MAPX400, y.MAPX400)
 	case *RP:
 		y := b.RData.(*RP)
-		return strings.ToLower(x.Mbox) == strings.ToLower(y.Mbox) &&
-			strings.ToLower(x.Txt) == strings.ToLower(y.Txt)
+		return dns.NamesEqual(x.Mbox, y.Mbox) &&
+			dns.NamesEqual(x.Txt, y.Txt)
 	case *RRSIG:
 		y := b.RData.(*RRSIG)
 		return x.Type == y.Type &&
@@ -3246,12 +4650,12 @@ func (a *RR) Equal(b *RR) (equal bool) {
 			x.TTL == y.TTL &&
 			x.Expiration == y.Expiration &&
 			x.KeyTag == y.KeyTag &&
-			strings.ToLower(x.Name) == strings.ToLower(y.Name) &&
+			dns.NamesEqual(x.Name, y.Name) &&
 			bytes.Equal(x.Signature, y.Signature)
 	case *RT:
 		y := b.RData.(*RT)
 		return x.Preference == y.Preference &&
-			strings.ToLower(x.Hostname) == strings.ToLower(y.Hostname)
+			dns.NamesEqual(x.Hostname, y.Hostname)
 	case *SIG:
 		y := b.RData.(*SIG)
 		return x.Type == y.Type &&
@@ -3260,12 +4664,18 @@ func (a *RR) Equal(b *RR) (equal bool) {
 			x.TTL == y.TTL &&
 			x.Expiration == y.Expiration &&
 			x.KeyTag == y.KeyTag &&
-			strings.ToLower(x.Name) == strings.ToLower(y.Name) &&
+			dns.NamesEqual(x.Name, y.Name) &&
 			bytes.Equal(x.Signature, y.Signature)
+	case *SMIMEA:
+		y := b.RData.(*SMIMEA)
+		return x.Usage == y.Usage &&
+			x.Selector == y.Selector &&
+			x.MatchingType == y.MatchingType &&
+			bytes.Equal(x.Certificate, y.Certificate)
 	case *SOA:
 		y := b.RData.(*SOA)
-		return strings.ToLower(x.MName) == strings.ToLower(y.MName) &&
-			strings.ToLower(x.RName) == strings.ToLower(y.RName) &&
+		return dns.NamesEqual(x.MName, y.MName) &&
+			dns.NamesEqual(x.RName, y.RName) &&
 			x.Serial == y.Serial &&
 			x.Refresh == y.Refresh &&
 			x.Retry == y.Retry &&
@@ -3288,12 +4698,14 @@ func (a *RR) Equal(b *RR) (equal bool) {
 		return x.Priority == y.Priority &&
 			x.Weight == y.Weight &&
 			x.Port == y.Port &&
-			strings.ToLower(x.Target) == strings.ToLower(y.Target)
+			dns.NamesEqual(x.Target, y.Target)
 	case *SSHFP:
 		y := b.RData.(*SSHFP)
 		return x.Algorithm == y.Algorithm &&
 			x.Type == y.Type &&
 			bytes.Equal(x.Fingerprint, y.Fingerprint)
+	case *SVCB:
+		return x.equal(b.RData.(*SVCB))
 	case *TA:
 		y := b.RData.(*TA)
 		return x.KeyTag == y.KeyTag &&
@@ -3302,26 +4714,20 @@ func (a *RR) Equal(b *RR) (equal bool) {
 			bytes.Equal(x.Digest, y.Digest)
 	case *TALINK:
 		y := b.RData.(*TALINK)
-		return strings.ToLower(x.PrevName) == strings.ToLower(y.PrevName) &&
-			strings.ToLower(x.NextName) == strings.ToLower(y.NextName)
+		return dns.NamesEqual(x.PrevName, y.PrevName) &&
+			dns.NamesEqual(x.NextName, y.NextName)
 	case *TKEY:
 		y := b.RData.(*TKEY)
-		return strings.ToLower(x.Algorithm) == strings.ToLower(y.Algorithm) &&
+		return dns.NamesEqual(x.Algorithm, y.Algorithm) &&
 			x.Inception.Unix() == y.Inception.Unix() &&
 			x.Expiration.Unix() == y.Expiration.Unix() &&
 			x.Mode == y.Mode &&
 			x.Error == y.Error &&
 			bytes.Equal(x.KeyData, y.KeyData) &&
 			bytes.Equal(x.OtherData, y.OtherData)
-	case *TLSA:
-		y := b.RData.(*TLSA)
-		return x.Usage == y.Usage &&
-			x.Selector == y.Selector &&
-			x.MatchingType == y.MatchingType &&
-			bytes.Equal(x.Certificate, y.Certificate)
 	case *TSIG:
 		y := b.RData.(*TSIG)
-		return strings.ToLower(x.AlgorithmName) == strings.ToLower(y.AlgorithmName) &&
+		return dns.NamesEqual(x.AlgorithmName, y.AlgorithmName) &&
 			x.TimeSigned.Unix() == y.TimeSigned.Unix() &&
 			x.Fudge == y.Fudge &&
 			bytes.Equal(x.MAC, y.MAC) &&
@@ -3373,8 +4779,13 @@ func (a *RR) Equal(b *RR) (equal bool) {
 		return true
 	case *X25:
 		return x.PSDN == b.RData.(*X25).PSDN
+	case *ZONEMD:
+		y := b.RData.(*ZONEMD)
+		return x.Serial == y.Serial &&
+			x.Scheme == y.Scheme &&
+			x.HashAlgorithm == y.HashAlgorithm &&
+			bytes.Equal(x.Digest, y.Digest)
 	}
-	return
 }
 
 // RRs is a slice of resource records with attached convenience methods
@@ -3392,7 +4803,155 @@ func (r RRs) Filter(want func(r *RR) bool) (wanted, other RRs) {
 	return
 }
 
-func (r RRs) String() string {
+// ByName returns the records of r owned by name. Names are compared
+// case-insensitively.
+func (r RRs) ByName(name string) (y RRs) {
+	name = dns.CanonicalName(name)
+	y, _ = r.Filter(func(r *RR) bool { return dns.CanonicalName(r.Name) == name })
+	return
+}
+
+// ByType returns the records of r of type t.
+func (r RRs) ByType(t Type) (y RRs) {
+	y, _ = r.Filter(func(r *RR) bool { return r.Type == t })
+	return
+}
+
+// ByClass returns the records of r of class c.
+func (r RRs) ByClass(c Class) (y RRs) {
+	y, _ = r.Filter(func(r *RR) bool { return r.Class == c })
+	return
+}
+
+// Copy returns a deep copy of r, see RR.Copy.
+func (r RRs) Copy() RRs {
+	if r == nil {
+		return nil
+	}
+
+	y := make(RRs, len(r))
+	for i, rec := range r {
+		y[i] = rec.Copy()
+	}
+	return y
+}
+
+// Age returns copies of the records of r, see RR.Copy, with the TTLs reduced
+// by seconds, e.g. to age records held in a cache. Records with a TTL not
+// greater than seconds have expired, the same as in CachedRR.Expired, and are
+// not included in the result. r is not modified.
+func (r RRs) Age(seconds int32) (y RRs) {
+	for _, rec := range r {
+		if rec.TTL <= seconds {
+			continue
+		}
+
+		y = append(y, rec.WithTTL(rec.TTL-seconds))
+	}
+	return
+}
+
+// Sorter implements sort.Interface for RRs ordered by Cmp, which returns a
+// negative number, zero or a positive number if a < b, a == b or a > b.
+type Sorter struct {
+	RRs RRs
+	Cmp func(a, b *RR) int
+}
+
+func (s Sorter) Len() int           { return len(s.RRs) }
+func (s Sorter) Less(i, j int) bool { return s.Cmp(s.RRs[i], s.RRs[j]) < 0 }
+func (s Sorter) Swap(i, j int)      { s.RRs[i], s.RRs[j] = s.RRs[j], s.RRs[i] }
+
+// Compare orders a and b by owner name, type, class and RDATA, in that
+// order. Names are compared label by label starting at the root, ignoring
+// ASCII case, so subdomains sort right after their parent domain. RDATA are
+// compared in their uncompressed wire form. The order is intended for
+// presentation, it's not the DNSSEC canonical order of RFC 4034/6.
+func Compare(a, b *RR) int {
+	if n := compareNames(a.Name, b.Name); n != 0 {
+		return n
+	}
+
+	switch {
+	case a.Type < b.Type:
+		return -1
+	case a.Type > b.Type:
+		return 1
+	case a.Class < b.Class:
+		return -1
+	case a.Class > b.Class:
+		return 1
+	}
+
+	return bytes.Compare(wireBytes(a.RData), wireBytes(b.RData))
+}
+
+func compareNames(a, b string) int {
+	la, lb := nameLabels(a), nameLabels(b)
+	for i, j := len(la)-1, len(lb)-1; i >= 0 && j >= 0; i, j = i-1, j-1 {
+		if n := strings.Compare(la[i], lb[j]); n != 0 {
+			return n
+		}
+	}
+	return len(la) - len(lb)
+}
+
+// compareCanonicalNames orders the domain names a and b in the DNSSEC
+// canonical order (RFC 4034/6.1): labels are compared starting at the root as
+// octet strings, with escapes resolved and ASCII letters folded to lower case,
+// and a name sorts before its subdomains.
+func compareCanonicalNames(a, b string) int {
+	la, lb := nameLabels(a), nameLabels(b)
+	for i, j := len(la)-1, len(lb)-1; i >= 0 && j >= 0; i, j = i-1, j-1 {
+		if n := bytes.Compare(canonicalLabel(la[i]), canonicalLabel(lb[j])); n != 0 {
+			return n
+		}
+	}
+	return len(la) - len(lb)
+}
+
+// canonicalLabel returns the octets of the presentation format label s with
+// ASCII letters folded to lower case.
+func canonicalLabel(s string) []byte {
+	u, err := UnescapeCharString(s)
+	if err != nil {
+		u = s
+	}
+
+	b := []byte(u)
+	for i, c := range b {
+		if c >= 'A' && c <= 'Z' {
+			b[i] = c + 'a' - 'A'
+		}
+	}
+	return b
+}
+
+// nameLabels returns the labels of the canonical form of name, escapes are
+// honored. The root label is not included.
+func nameLabels(name string) (labels []string) {
+	name = dns.CanonicalName(name)
+	i0 := 0
+	for i := 0; i < len(name); i++ {
+		switch name[i] {
+		case '\\':
+			i++
+		case '.':
+			if i != 0 {
+				labels = append(labels, name[i0:i])
+			}
+			i0 = i + 1
+		}
+	}
+	return
+}
+
+// Sort sorts r using Compare.
+func (r RRs) Sort() {
+	sort.Sort(Sorter{r, Compare})
+}
+
+func (r RRs) String() string {
 	a := make([]string, len(r))
 	for i, rec := range r {
 		a[i] = rec.String()
@@ -3400,13 +4959,97 @@ func (r RRs) String() string {
 	return strings.Join(a, "\n")
 }
 
+// StringAligned returns r in the master file format with the owner name,
+// TTL, class and type columns padded to a common width, so that the RDATA of
+// all records starts in the same column, e.g.
+//
+//	example.com.     3600 IN SOA   ns.example.com. hostmaster.example.com. 1 2 3 4 5
+//	www.example.com. 300  IN CNAME example.com.
+func (r RRs) StringAligned() string {
+	rows := make([][5]string, len(r))
+	var w [4]int
+	for i, rec := range r {
+		row := &rows[i]
+		row[0] = escapeName(rec.Name)
+		row[1] = strconv.FormatInt(int64(rec.TTL), 10)
+		row[2] = rec.Class.String()
+		row[3] = rec.Type.String()
+		row[4] = fmt.Sprint(rec.RData)
+		for j := range w {
+			if n := len(row[j]); n > w[j] {
+				w[j] = n
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	for i, row := range rows {
+		if i != 0 {
+			buf.WriteByte('\n')
+		}
+		line := fmt.Sprintf("%-*s %-*s %-*s %-*s %s", w[0], row[0], w[1], row[1], w[2], row[2], w[3], row[3], row[4])
+		buf.WriteString(strings.TrimRight(line, " "))
+	}
+	return buf.String()
+}
+
+// StringZone returns r in the master file format (RFC 1035/5.1) as written
+// by BIND. If origin is not empty, the output starts with an $ORIGIN
+// directive, owner names at or below origin are written relative to it and
+// the origin itself is written as "@". An owner name repeating the previous
+// one is omitted, i.e. the line starts with a blank, and the class is omitted
+// while it doesn't change.
+func (r RRs) StringZone(origin string) string {
+	var buf bytes.Buffer
+	var suffix string
+	if origin != "" {
+		origin = dns.CanonicalName(origin)
+		fmt.Fprintf(&buf, "$ORIGIN %s\n", origin)
+		if suffix = "." + origin; origin == "." {
+			suffix = origin
+		}
+	}
+
+	var last *RR
+	for _, rec := range r {
+		switch name := dns.RootedName(escapeName(rec.Name)); {
+		case last != nil && dns.NamesEqual(rec.Name, last.Name):
+			// nop
+		case origin != "" && dns.CanonicalName(name) == origin:
+			buf.WriteString("@")
+		case origin != "" && strings.HasSuffix(dns.CanonicalName(name), suffix) && dns.IsRooted(name[:len(name)-len(suffix)+1]):
+			// The suffix must start on a label boundary, not at an
+			// escaped dot.
+			buf.WriteString(name[:len(name)-len(suffix)])
+		default:
+			buf.WriteString(name)
+		}
+
+		fmt.Fprintf(&buf, "\t%d", rec.TTL)
+		if last == nil || rec.Class != last.Class {
+			fmt.Fprintf(&buf, "\t%s", rec.Class)
+		}
+		fmt.Fprintf(&buf, "\t%s %s\n", rec.Type, rec.RData)
+		last = rec
+	}
+	return buf.String()
+}
+
 // SetAdd computes a set union of r and rrs. Set membership predicate is RR.Equal,
 // i.e. only resource records from rrs not comparing equal to any resource records
-// in r are added/merged into the result set.
+// in r are added/merged into the result set. Records are added as copies, see
+// RR.Copy, so later changes to rrs don't affect r.
 func (r *RRs) SetAdd(rrs RRs) {
+	owners := make(map[string]RRs, len(*r))
+	for _, oldrec := range *r {
+		k := dns.CanonicalName(oldrec.Name)
+		owners[k] = append(owners[k], oldrec)
+	}
+
 	for _, newrec := range rrs {
+		k := dns.CanonicalName(newrec.Name)
 		isnew := true
-		for _, oldrec := range *r {
+		for _, oldrec := range owners[k] {
 			if newrec.Equal(oldrec) {
 				isnew = false
 				break
@@ -3414,11 +5057,72 @@ func (r *RRs) SetAdd(rrs RRs) {
 		}
 
 		if isnew {
+			newrec = newrec.Copy()
 			*r = append(*r, newrec)
+			owners[k] = append(owners[k], newrec)
 		}
 	}
 }
 
+// Diff returns the records of s not present in r (added) and the records of r
+// not present in s (removed). Set membership predicate is RR.Equal extended
+// to compare also the TTLs, so a record which changed only its TTL is
+// reported as both removed and added (RFC 2181/5.2). See also DiffTTL.
+func (r RRs) Diff(s RRs) (added, removed RRs) {
+	return r.DiffTTL(s, true)
+}
+
+// DiffTTL is like Diff but TTLs are compared only if ttl is true.
+func (r RRs) DiffTTL(s RRs, ttl bool) (added, removed RRs) {
+	return r.missing(s, ttl), s.missing(r, ttl)
+}
+
+// missing returns the records of s not present in r.
+func (r RRs) missing(s RRs, ttl bool) (y RRs) {
+	owners := r.GroupByName()
+	for _, rec := range s {
+		found := false
+		for _, v := range owners[dns.CanonicalName(rec.Name)] {
+			if rec.Equal(v) && (!ttl || rec.TTL == v.TTL) {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			y = append(y, rec)
+		}
+	}
+	return
+}
+
+// Apply applies a changeset to r: all records Equal to any record in remove
+// are removed from r and then add is merged into r using SetAdd. This mirrors
+// applying an RFC 2136 update or an IXFR difference sequence to an in-memory
+// zone. Applying the same changeset again doesn't change the set of records
+// in r.
+func (r *RRs) Apply(add, remove RRs) {
+	if len(remove) != 0 {
+		owners := remove.GroupByName()
+		y := (*r)[:0]
+		for _, rec := range *r {
+			found := false
+			for _, v := range owners[dns.CanonicalName(rec.Name)] {
+				if rec.Equal(v) {
+					found = true
+					break
+				}
+			}
+
+			if !found {
+				y = append(y, rec)
+			}
+		}
+		*r = y
+	}
+	r.SetAdd(add)
+}
+
 // Unique filters out any records from r which are Equal to any other record in r.
 func (r *RRs) Unique() {
 	y := RRs{}
@@ -3426,6 +5130,35 @@ func (r *RRs) Unique() {
 	*r = y
 }
 
+// Coalesce is like Unique, but the TTL of every record kept is the minimum of
+// the TTLs of all the records of r Equal to it (RFC 2181/5.2), e.g. when
+// merging records from different sources. Records are kept as copies, see
+// RR.Copy, in the order of their first occurrence.
+func (r *RRs) Coalesce() {
+	y := RRs{}
+	owners := map[string]RRs{}
+	for _, rec := range *r {
+		k := dns.CanonicalName(rec.Name)
+		isnew := true
+		for _, v := range owners[k] {
+			if rec.Equal(v) {
+				if rec.TTL < v.TTL {
+					v.TTL = rec.TTL
+				}
+				isnew = false
+				break
+			}
+		}
+
+		if isnew {
+			rec = rec.Copy()
+			y = append(y, rec)
+			owners[k] = append(owners[k], rec)
+		}
+	}
+	*r = y
+}
+
 // Partition groups resource record of the same type.
 // If unique == true then the result parts are processed by Unique.
 func (r RRs) Partition(unique bool) (parts Parts) {
@@ -3442,12 +5175,415 @@ func (r RRs) Partition(unique bool) (parts Parts) {
 	return
 }
 
+// GroupByName groups resource records by their owner name. The map is keyed
+// by dns.CanonicalName of the owner names and the records of every group keep
+// their order in r.
+func (r RRs) GroupByName() (groups map[string]RRs) {
+	groups = map[string]RRs{}
+	for _, v := range r {
+		k := dns.CanonicalName(v.Name)
+		groups[k] = append(groups[k], v)
+	}
+	return
+}
+
+// Addresses returns the addresses of the A and AAAA records of r in the order
+// of the records, see RR.IP.
+func (r RRs) Addresses() (ips []net.IP) {
+	for _, v := range r {
+		if ip, ok := v.IP(); ok {
+			ips = append(ips, ip)
+		}
+	}
+	return
+}
+
+// Names returns the distinct owner names of r in the order of their first
+// occurrence. Names differing only in ASCII case or in the trailing dot are
+// considered the same name, the first seen form is returned.
+func (r RRs) Names() (names []string) {
+	seen := map[string]bool{}
+	for _, v := range r {
+		if k := dns.CanonicalName(v.Name); !seen[k] {
+			seen[k] = true
+			names = append(names, v.Name)
+		}
+	}
+	return
+}
+
+// Stats returns the number of records in r per RR type.
+func (r RRs) Stats() map[Type]int {
+	parts := r.Partition(false)
+	stats := make(map[Type]int, len(parts))
+	for t, part := range parts {
+		stats[t] = len(part)
+	}
+	return stats
+}
+
+// Summary returns a human readable breakdown of r: the total number of
+// records and of distinct owner names on the first line, followed by a line
+// per RR type, ordered by type value, e.g.
+//
+//	5 records, 2 names
+//	A	2
+//	NS	2
+//	SOA	1
+func (r RRs) Summary() string {
+	stats := r.Stats()
+	types := make([]int, 0, len(stats))
+	for t := range stats {
+		types = append(types, int(t))
+	}
+	sort.Ints(types)
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "%d records, %d names", len(r), len(r.GroupByName()))
+	for _, t := range types {
+		fmt.Fprintf(&buf, "\n%s\t%d", Type(t), stats[Type(t)])
+	}
+	return buf.String()
+}
+
+// rrsetKey identifies the RRset of a resource record. RRSIGs covering
+// different types belong to different RRsets.
+type rrsetKey struct {
+	name    string
+	typ     Type
+	class   Class
+	covered Type
+}
+
+func keyOf(rec *RR) (k rrsetKey) {
+	k = rrsetKey{dns.CanonicalName(rec.Name), rec.Type, rec.Class, 0}
+	if x, ok := rec.RData.(*RRSIG); ok {
+		k.covered = x.Type
+	}
+	return
+}
+
+// ResolveCNAME follows the CNAME records of r starting at name until reaching
+// a name with no CNAME record or with records of type qtype. Chain holds the
+// CNAME records followed and target the name reached. If a name is reached
+// twice, loop is true and target is the name closing the loop. Names are
+// compared case-insensitively.
+func (r RRs) ResolveCNAME(name string, qtype Type) (chain RRs, target string, loop bool) {
+	seen := map[string]bool{}
+	for target = name; ; {
+		k := dns.CanonicalName(target)
+		if seen[k] {
+			return chain, target, true
+		}
+
+		seen[k] = true
+		at := r.ByName(k)
+		if len(at.ByType(qtype)) != 0 {
+			return
+		}
+
+		cname := at.ByType(TYPE_CNAME)
+		if len(cname) == 0 {
+			return
+		}
+
+		chain = append(chain, cname[0])
+		target = cname[0].RData.(*CNAME).Name
+	}
+}
+
+// Classify sorts the records of r into the sections of a response to the
+// query for qname and qtype (RFC 1034/4.3.2). The answer section gets the
+// records of qname and qtype, or of any type for the QTYPE * (255), following
+// CNAMEs when qtype is not CNAME. The authority section gets the SOA and NS
+// records not in the answer section. The additional section gets the A and
+// AAAA records of the names referred to by NS, MX and SRV records of the
+// answer and authority sections, i.e. glue. Other records are discarded.
+func (r RRs) Classify(qname string, qtype Type) (answer, authority, additional RRs) {
+	in := map[*RR]bool{}
+	name, loop := qname, false
+	if qtype != TYPE_CNAME && qtype != 255 {
+		answer, name, loop = r.ResolveCNAME(qname, qtype)
+	}
+	if !loop {
+		for _, v := range r.ByName(name) {
+			if v.Type == qtype || qtype == 255 {
+				answer = append(answer, v)
+			}
+		}
+	}
+	for _, v := range answer {
+		in[v] = true
+	}
+
+	for _, v := range r {
+		if !in[v] && (v.Type == TYPE_SOA || v.Type == TYPE_NS) {
+			authority, in[v] = append(authority, v), true
+		}
+	}
+
+	targets := map[string]bool{}
+	for _, v := range append(append(RRs{}, answer...), authority...) {
+		switch x := v.RData.(type) {
+		case *NS:
+			targets[dns.CanonicalName(x.NSDName)] = true
+		case *MX:
+			targets[dns.CanonicalName(x.Exchange)] = true
+		case *SRV:
+			targets[dns.CanonicalName(x.Target)] = true
+		}
+	}
+	for _, v := range r {
+		if !in[v] && (v.Type == TYPE_A || v.Type == TYPE_AAAA) && targets[dns.CanonicalName(v.Name)] {
+			additional = append(additional, v)
+		}
+	}
+	return
+}
+
+// SortMX returns the MX records of r ordered by preference, lower values
+// first (RFC 5321/5.1). Records of equal preference keep their order in r.
+// Records of other types are not included, r is not modified.
+func (r RRs) SortMX() (y RRs) {
+	y, _ = r.Filter(func(rec *RR) bool {
+		_, ok := rec.RData.(*MX)
+		return ok
+	})
+	sort.Stable(Sorter{y, func(a, b *RR) int {
+		return int(a.RData.(*MX).Preference) - int(b.RData.(*MX).Preference)
+	}})
+	return
+}
+
+// SelectSRV returns the SRV records of r in the order in which a client
+// should try their targets (RFC 2782). The records are ordered by priority,
+// lower values first. Records of equal priority are ordered by repeated
+// weighted random selection using rnd, a record is selected with a
+// probability proportional to its weight. Records of weight 0 have a small
+// chance to be selected first. If rnd is nil, the math/rand top level
+// functions are used. Records with the target "." and records of other types
+// are not included, r is not modified.
+func (r RRs) SelectSRV(rnd *rand.Rand) (y []*RR) {
+	intn := rand.Intn
+	if rnd != nil {
+		intn = rnd.Intn
+	}
+
+	srvs, _ := r.Filter(func(rec *RR) bool {
+		x, ok := rec.RData.(*SRV)
+		return ok && x.Target != "."
+	})
+	sort.Stable(Sorter{srvs, func(a, b *RR) int {
+		return int(a.RData.(*SRV).Priority) - int(b.RData.(*SRV).Priority)
+	}})
+
+	for len(srvs) != 0 {
+		n := 1
+		for n < len(srvs) && srvs[n].RData.(*SRV).Priority == srvs[0].RData.(*SRV).Priority {
+			n++
+		}
+
+		// RFC 2782: records of weight 0 are placed at the beginning of
+		// the list, then the weights are summed up.
+		set := append(RRs{}, srvs[:n]...)
+		sort.Stable(Sorter{set, func(a, b *RR) int {
+			if a.RData.(*SRV).Weight == 0 && b.RData.(*SRV).Weight != 0 {
+				return -1
+			}
+
+			return 0
+		}})
+		for len(set) != 0 {
+			sum := 0
+			for _, v := range set {
+				sum += int(v.RData.(*SRV).Weight)
+			}
+
+			x, i := intn(sum+1), 0
+			for run := 0; ; i++ {
+				if run += int(set[i].RData.(*SRV).Weight); run >= x {
+					break
+				}
+			}
+			y = append(y, set[i])
+			set = append(set[:i], set[i+1:]...)
+		}
+		srvs = srvs[n:]
+	}
+	return
+}
+
+// RRsets returns the records of r grouped by RRset, i.e. by owner name, type
+// and class, in the order of the first occurrence of each RRset. Owner names
+// are compared case-insensitively. RRSIGs are grouped by the type they cover
+// as well.
+func (r RRs) RRsets() (sets []RRs) {
+	index := map[rrsetKey]int{}
+	for _, v := range r {
+		k := keyOf(v)
+		i, ok := index[k]
+		if !ok {
+			i = len(sets)
+			index[k] = i
+			sets = append(sets, nil)
+		}
+		sets[i] = append(sets[i], v)
+	}
+	return
+}
+
+// NormalizeTTL sets the TTL of every record of an RRset in r to the minimum
+// TTL found in that RRset, as required by RFC 2181/5.2. OPT pseudo records,
+// which use the TTL field for other purposes, are left untouched.
+func (r RRs) NormalizeTTL() {
+	min := map[rrsetKey]int32{}
+	for _, v := range r {
+		if v.Type == TYPE_OPT {
+			continue
+		}
+
+		k := keyOf(v)
+		if ttl, ok := min[k]; !ok || v.TTL < ttl {
+			min[k] = v.TTL
+		}
+	}
+
+	for _, v := range r {
+		if v.Type != TYPE_OPT {
+			v.TTL = min[keyOf(v)]
+		}
+	}
+}
+
+// CheckTTL returns an error listing all RRsets in r having records with
+// different TTLs, see NormalizeTTL. r is not modified.
+func (r RRs) CheckTTL() (err error) {
+	ttls := map[rrsetKey]int32{}
+	var bad []rrsetKey
+	reported := map[rrsetKey]bool{}
+	for _, v := range r {
+		if v.Type == TYPE_OPT {
+			continue
+		}
+
+		k := keyOf(v)
+		ttl, ok := ttls[k]
+		if !ok {
+			ttls[k] = v.TTL
+			continue
+		}
+
+		if ttl != v.TTL && !reported[k] {
+			reported[k] = true
+			bad = append(bad, k)
+		}
+	}
+
+	if len(bad) == 0 {
+		return
+	}
+
+	a := make([]string, len(bad))
+	for i, k := range bad {
+		a[i] = fmt.Sprintf("%s %s %s", k.name, k.class, k.typ)
+		if k.typ == TYPE_RRSIG {
+			a[i] += " " + k.covered.String()
+		}
+	}
+	return fmt.Errorf("(RRs).CheckTTL: inconsistent TTLs in RRset(s) %s", strings.Join(a, ", "))
+}
+
+// Encode encodes r to b. All records share the compression table of b, so
+// names may be compressed using pointers to names of the preceding records.
+func (r RRs) Encode(b *dns.Wirebuf) {
+	for _, rec := range r {
+		rec.Encode(b)
+	}
+}
+
 // Pack packs r to Bytes
 func (r RRs) Pack() (y Bytes) {
 	y.Pack(r)
 	return
 }
 
+// DecodeRRs decodes count consecutive resource records found in b at pos,
+// e.g. a message section. On error, the records decoded so far are returned
+// together with the error and pos is left at the start of the record which
+// failed to decode.
+func DecodeRRs(b []byte, pos *int, count int) (y RRs, err error) {
+	for i := 0; i < count; i++ {
+		p := *pos
+		if p >= len(b) {
+			return y, fmt.Errorf("DecodeRRs: record %d of %d: buffer underflow", i+1, count)
+		}
+
+		r := &RR{}
+		if err = r.Decode(b, pos, nil); err != nil {
+			*pos = p
+			return y, fmt.Errorf("DecodeRRs: record %d of %d at offset %d: %s", i+1, count, p, err)
+		}
+
+		y = append(y, r)
+	}
+	return
+}
+
+// PackUncompressed packs r to Bytes like Pack, but with name compression
+// disabled for all records, e.g. for DNSSEC canonical forms or for peers
+// mishandling compression pointers.
+func (r RRs) PackUncompressed() (y Bytes) {
+	w := dns.NewWirebuf()
+	w.DisableCompression()
+	r.Encode(w)
+	y = make(Bytes, len(w.Buf))
+	copy(y, w.Buf)
+	return
+}
+
+// WireLen returns the length of the wire form of r with name compression
+// disabled. It's an upper bound of the space r takes in a message.
+func (r RRs) WireLen() int {
+	w := dns.NewWirebuf()
+	w.DisableCompression()
+	r.Encode(w)
+	return len(w.Buf)
+}
+
+// FitInto splits r into fit, the leading RRsets of r whose WireLen doesn't
+// exceed maxBytes, and dropped, the rest of r. RRsets are kept together, the
+// records of r are grouped by RRset in the order of their first occurrence.
+// Only if not even the first RRset fits, fit holds its leading records which
+// do. A server dropping records from a UDP response must set the TC bit.
+func (r RRs) FitInto(maxBytes int) (fit, dropped RRs) {
+	sets := r.RRsets()
+	n := 0
+	for i, set := range sets {
+		if m := set.WireLen(); n+m <= maxBytes {
+			fit = append(fit, set...)
+			n += m
+			continue
+		}
+
+		if i == 0 {
+			for len(set) != 0 {
+				m := (RRs{set[0]}).WireLen()
+				if n+m > maxBytes {
+					break
+				}
+
+				fit, set, n = append(fit, set[0]), set[1:], n+m
+			}
+		}
+		dropped = append(dropped, set...)
+		for _, set := range sets[i+1:] {
+			dropped = append(dropped, set...)
+		}
+		break
+	}
+	return
+}
+
 // Parts is the type returned by Partition()
 type Parts map[Type]RRs
 
@@ -3521,7 +5657,7 @@ func (rd *RP) Decode(b []byte, pos *int, sniffer dns.WireDecodeSniffer) (err err
 }
 
 func (rd *RP) String() string {
-	return fmt.Sprintf("%s %s", rd.Mbox, rd.Txt)
+	return fmt.Sprintf("%s %s", escapeName(rd.Mbox), escapeName(rd.Txt))
 }
 
 // RRSIG holds the zone RRSIG RData (RFC4034)
@@ -3558,6 +5694,11 @@ type RRSIG struct {
 
 // Implementation of dns.Wirer
 func (rd *RRSIG) Encode(b *dns.Wirebuf) {
+	rd.encode(b)
+}
+
+// encode encodes the wire layout shared by RRSIG and SIG.
+func (rd *RRSIG) encode(b *dns.Wirebuf) {
 	dns.Octets2(rd.Type).Encode(b)
 	dns.Octet(rd.Algorithm).Encode(b)
 	dns.Octet(rd.Labels).Encode(b)
@@ -3574,6 +5715,18 @@ func (rd *RRSIG) Encode(b *dns.Wirebuf) {
 // Implementation of dns.Wirer
 func (rd *RRSIG) Decode(b []byte, pos *int, sniffer dns.WireDecodeSniffer) (err error) {
 	p0 := &b[*pos]
+	if err = rd.decode(b, pos, sniffer); err != nil {
+		return fmt.Errorf("(*RRSIG).Decode: %s", err)
+	}
+
+	if sniffer != nil {
+		sniffer(p0, &b[*pos-1], dns.SniffRDataRRSIG, rd)
+	}
+	return
+}
+
+// decode decodes the wire layout shared by RRSIG and SIG.
+func (rd *RRSIG) decode(b []byte, pos *int, sniffer dns.WireDecodeSniffer) (err error) {
 	if err = (*dns.Octets2)(&rd.Type).Decode(b, pos, sniffer); err != nil {
 		return
 	}
@@ -3605,21 +5758,22 @@ func (rd *RRSIG) Decode(b []byte, pos *int, sniffer dns.WireDecodeSniffer) (err
 		return
 	}
 
+	if err = checkUncompressed(b, *pos); err != nil {
+		return
+	}
+
 	if err = (*dns.DomainName)(&rd.Name).Decode(b, pos, sniffer); err != nil {
 		return
 	}
 
 	n := len(b) - *pos
 	if n <= 0 {
-		return fmt.Errorf("(*RRSIG).Decode: no signature data")
+		return fmt.Errorf("no signature data")
 	}
 
 	rd.Signature = make([]byte, n)
 	copy(rd.Signature, b[*pos:])
 	*pos += n
-	if sniffer != nil {
-		sniffer(p0, &b[*pos-1], dns.SniffRDataRRSIG, rd)
-	}
 	return
 }
 
@@ -3632,11 +5786,81 @@ func (rd *RRSIG) String() string {
 		dns.Seconds2String(int64(rd.Expiration)),
 		dns.Seconds2String(int64(rd.Inception)),
 		rd.KeyTag,
-		rd.Name,
+		escapeName(rd.Name),
 		strutil.Base64Encode(rd.Signature),
 	)
 }
 
+// ParseRRSIG parses the RRSIG RDATA presentation format fields (RFC 4034/3.2)
+// as returned by String: the type covered, algorithm, see AlgorithmByName,
+// labels, original TTL, signature expiration and inception, see ParseSigTime,
+// key tag and signer's name followed by the base64 encoded signature, which
+// may be split into more tokens. Grouping parentheses are ignored.
+func ParseRRSIG(tokens []string) (rd *RRSIG, err error) {
+	f := rdataFields(tokens)
+	if len(f) < 9 {
+		return nil, fmt.Errorf("ParseRRSIG(%q): expected <type> <algorithm> <labels> <ttl> <expiration> <inception> <key tag> <signer> <signature>", tokens)
+	}
+
+	rd = &RRSIG{Name: f[7]}
+	var ok bool
+	if rd.Type, ok = TypeByName(f[0]); !ok {
+		return nil, fmt.Errorf("ParseRRSIG(%q): unknown type %q", tokens, f[0])
+	}
+
+	if rd.Algorithm, err = parseAlgorithm(f[1]); err != nil {
+		return nil, fmt.Errorf("ParseRRSIG(%q): %s", tokens, err)
+	}
+
+	var n [3]uint64
+	for i, v := range []struct {
+		name  string
+		field string
+		bits  int
+	}{{"labels", f[2], 8}, {"ttl", f[3], 32}, {"key tag", f[6], 16}} {
+		if n[i], err = parseUint(v.name, v.field, v.bits); err != nil {
+			return nil, fmt.Errorf("ParseRRSIG(%q): %s", tokens, err)
+		}
+	}
+	rd.Labels, rd.TTL, rd.KeyTag = byte(n[0]), int32(n[1]), uint16(n[2])
+
+	if rd.Expiration, err = ParseSigTime(f[4]); err != nil {
+		return nil, fmt.Errorf("ParseRRSIG(%q): %s", tokens, err)
+	}
+
+	if rd.Inception, err = ParseSigTime(f[5]); err != nil {
+		return nil, fmt.Errorf("ParseRRSIG(%q): %s", tokens, err)
+	}
+
+	if rd.Signature, err = strutil.Base64Decode([]byte(strings.Join(f[8:], ""))); err != nil {
+		return nil, fmt.Errorf("ParseRRSIG(%q): %s", tokens, err)
+	}
+
+	return
+}
+
+// ParseSigTime parses the RRSIG/SIG signature expiration or inception time
+// s (RFC 4034/3.2). s is either in the YYYYMMDDHHmmSS format, in UTC, or a
+// plain unsigned decimal number of seconds < 2^32. Times in the former format
+// are converted to seconds modulo 2^32.
+func ParseSigTime(s string) (secs uint32, err error) {
+	if len(s) == 14 {
+		var n int64
+		if n, err = dns.String2Seconds(s); err != nil {
+			return 0, fmt.Errorf("ParseSigTime: %s", err)
+		}
+
+		return uint32(n), nil
+	}
+
+	n, err := strconv.ParseUint(s, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("ParseSigTime: invalid time %q", s)
+	}
+
+	return uint32(n), nil
+}
+
 // The RT resource record provides a route-through binding for hosts that do
 // not have their own direct wide area network addresses.  It is used in much
 // the same way as the MX RR.
@@ -3678,7 +5902,7 @@ func (rd *RT) Decode(b []byte, pos *int, sniffer dns.WireDecodeSniffer) (err err
 }
 
 func (rd *RT) String() string {
-	return fmt.Sprintf("%d %s", rd.Preference, rd.Hostname)
+	return fmt.Sprintf("%d %s", rd.Preference, escapeName(rd.Hostname))
 }
 
 // The SIG or "signature" resource record (RR) is the fundamental way that data
@@ -3692,90 +5916,41 @@ type SIG struct {
 	// to create the signature.
 	Algorithm AlgorithmType
 	// The Labels field specifies the number of labels in the original SIG RR owner name.
-	Labels byte
-	// The Original TTL field specifies the TTL of the covered RRset as it appears
-	// in the authoritative zone.
-	TTL int32
-	// The Signature Expiration field specifies a validity period for the signature.
-	// The SIG record MUST NOT be used for authentication after the expiration date.
-	Expiration uint32
-	// The Signature Inception field specifies a validity period for the signature.
-	// The SIG record MUST NOT be used for authentication prior to the inception date.
-	Inception uint32
-	// The Key Tag field contains the key tag value of the DNSKEY RR that validates
-	// this signature, in network byte order.
-	KeyTag uint16
-	// The Signer's Name field value identifies the owner name of the DNSKEY
-	// RR that a validator is supposed to use to validate this signature.
-	Name string
-	// The Signature field contains the cryptographic signature that covers
-	// the SIG RDATA (excluding the Signature field) and the RRset
-	// specified by the SIG owner name, SIG class, and SIG Type
-	// Covered field.
-	Signature []byte
-}
-
-// Implementation of dns.Wirer
-func (rd *SIG) Encode(b *dns.Wirebuf) {
-	dns.Octets2(rd.Type).Encode(b)
-	dns.Octet(rd.Algorithm).Encode(b)
-	dns.Octet(rd.Labels).Encode(b)
-	dns.Octets4(rd.TTL).Encode(b)
-	dns.Octets4(rd.Expiration).Encode(b)
-	dns.Octets4(rd.Inception).Encode(b)
-	dns.Octets2(rd.KeyTag).Encode(b)
-	b.DisableCompression()
-	(*dns.DomainName)(&rd.Name).Encode(b)
-	b.EnableCompression()
-	b.Buf = append(b.Buf, rd.Signature...)
-}
-
-// Implementation of dns.Wirer
-func (rd *SIG) Decode(b []byte, pos *int, sniffer dns.WireDecodeSniffer) (err error) {
-	p0 := &b[*pos]
-	if err = (*dns.Octets2)(&rd.Type).Decode(b, pos, sniffer); err != nil {
-		return
-	}
-
-	if err = (*dns.Octet)(&rd.Algorithm).Decode(b, pos, sniffer); err != nil {
-		return
-	}
-
-	if err = (*dns.Octet)(&rd.Labels).Decode(b, pos, sniffer); err != nil {
-		return
-	}
-
-	var ttl dns.Octets4
-	if err = ttl.Decode(b, pos, sniffer); err != nil {
-		return
-	}
-
-	rd.TTL = int32(ttl)
-
-	if err = (*dns.Octets4)(&rd.Expiration).Decode(b, pos, sniffer); err != nil {
-		return
-	}
-
-	if err = (*dns.Octets4)(&rd.Inception).Decode(b, pos, sniffer); err != nil {
-		return
-	}
-
-	if err = (*dns.Octets2)(&rd.KeyTag).Decode(b, pos, sniffer); err != nil {
-		return
-	}
+	Labels byte
+	// The Original TTL field specifies the TTL of the covered RRset as it appears
+	// in the authoritative zone.
+	TTL int32
+	// The Signature Expiration field specifies a validity period for the signature.
+	// The SIG record MUST NOT be used for authentication after the expiration date.
+	Expiration uint32
+	// The Signature Inception field specifies a validity period for the signature.
+	// The SIG record MUST NOT be used for authentication prior to the inception date.
+	Inception uint32
+	// The Key Tag field contains the key tag value of the DNSKEY RR that validates
+	// this signature, in network byte order.
+	KeyTag uint16
+	// The Signer's Name field value identifies the owner name of the DNSKEY
+	// RR that a validator is supposed to use to validate this signature.
+	Name string
+	// The Signature field contains the cryptographic signature that covers
+	// the SIG RDATA (excluding the Signature field) and the RRset
+	// specified by the SIG owner name, SIG class, and SIG Type
+	// Covered field.
+	Signature []byte
+}
 
-	if err = (*dns.DomainName)(&rd.Name).Decode(b, pos, sniffer); err != nil {
-		return
-	}
+// Implementation of dns.Wirer
+func (rd *SIG) Encode(b *dns.Wirebuf) {
+	(*RRSIG)(rd).encode(b)
+}
 
-	n := len(b) - *pos
-	if n <= 0 {
-		return fmt.Errorf("(*SIG).Decode: no signature data")
+// Implementation of dns.Wirer
+func (rd *SIG) Decode(b []byte, pos *int, sniffer dns.WireDecodeSniffer) (err error) {
+	p0 := &b[*pos]
+	if err = (*RRSIG)(rd).decode(b, pos, sniffer); err != nil {
+		return fmt.Errorf("(*SIG).Decode: %s", err)
 	}
 
-	rd.Signature = make([]byte, n)
-	copy(rd.Signature, b[*pos:])
-	*pos += n
 	if sniffer != nil {
 		sniffer(p0, &b[*pos-1], dns.SniffRDataSIG, rd)
 	}
@@ -3791,11 +5966,43 @@ func (rd *SIG) String() string {
 		dns.Seconds2String(int64(rd.Expiration)),
 		dns.Seconds2String(int64(rd.Inception)),
 		rd.KeyTag,
-		rd.Name,
+		escapeName(rd.Name),
 		strutil.Base64Encode(rd.Signature),
 	)
 }
 
+// SMIMEA holds the SMIMEA RR RData (RFC 8162). It associates an S/MIME
+// certificate with the hashed local part of an e-mail address and has the
+// same fields and wire format as TLSA.
+type SMIMEA struct {
+	Usage        TLSAUsage
+	Selector     TLSASelector
+	MatchingType TLSAMatchingType
+	Certificate  []byte
+}
+
+// Implementation of dns.Wirer
+func (rd *SMIMEA) Encode(b *dns.Wirebuf) {
+	(*TLSA)(rd).encode(b)
+}
+
+// Implementation of dns.Wirer
+func (rd *SMIMEA) Decode(b []byte, pos *int, sniffer dns.WireDecodeSniffer) (err error) {
+	p0 := &b[*pos]
+	if err = (*TLSA)(rd).decode(b, pos, sniffer); err != nil {
+		return
+	}
+
+	if sniffer != nil {
+		sniffer(p0, &b[*pos-1], dns.SniffRDataSMIMEA, rd)
+	}
+	return
+}
+
+func (rd *SMIMEA) String() string {
+	return (*TLSA)(rd).String()
+}
+
 // SOA holds the zone SOA RData
 type SOA struct {
 	// The <domain-name> of the name server that was the
@@ -3844,16 +6051,16 @@ func (rd *SOA) Decode(b []byte, pos *int, sniffer dns.WireDecodeSniffer) (err er
 	if err = (*dns.DomainName)(&rd.RName).Decode(b, pos, sniffer); err != nil {
 		return
 	}
-	if (*dns.Octets4)(&rd.Serial).Decode(b, pos, sniffer); err != nil {
+	if err = (*dns.Octets4)(&rd.Serial).Decode(b, pos, sniffer); err != nil {
 		return
 	}
-	if (*dns.Octets4)(&rd.Refresh).Decode(b, pos, sniffer); err != nil {
+	if err = (*dns.Octets4)(&rd.Refresh).Decode(b, pos, sniffer); err != nil {
 		return
 	}
-	if (*dns.Octets4)(&rd.Retry).Decode(b, pos, sniffer); err != nil {
+	if err = (*dns.Octets4)(&rd.Retry).Decode(b, pos, sniffer); err != nil {
 		return
 	}
-	if (*dns.Octets4)(&rd.Expire).Decode(b, pos, sniffer); err != nil {
+	if err = (*dns.Octets4)(&rd.Expire).Decode(b, pos, sniffer); err != nil {
 		return
 	}
 	if err = (*dns.Octets4)(&rd.Minimum).Decode(b, pos, sniffer); err != nil {
@@ -3866,7 +6073,38 @@ func (rd *SOA) Decode(b []byte, pos *int, sniffer dns.WireDecodeSniffer) (err er
 }
 
 func (rd *SOA) String() string {
-	return fmt.Sprintf("%s %s %d %d %d %d %d", rd.MName, rd.RName, rd.Serial, rd.Refresh, rd.Retry, rd.Expire, rd.Minimum)
+	return fmt.Sprintf("%s %s %d %d %d %d %d", escapeName(rd.MName), escapeName(rd.RName), rd.Serial, rd.Refresh, rd.Retry, rd.Expire, rd.Minimum)
+}
+
+// SerialLess reports whether serial number a is less than b in the RFC 1982
+// serial number arithmetic with SERIAL_BITS == 32. Serial numbers exactly 2^31
+// apart are not comparable (RFC 1982/3.2), SerialLess returns false for both
+// orderings of such a pair.
+func SerialLess(a, b uint32) bool {
+	return a != b && b-a < 1<<31
+}
+
+// BumpSerial increments rd.Serial assuming the YYYYMMDDnn format. If the
+// serial is less than the date of now with counter 01, it's set to that
+// value. Otherwise, e.g. when the serial encodes the date of now already, the
+// serial is incremented as a plain integer, so the counter overflowing 99
+// moves the serial into the next day.
+func (rd *SOA) BumpSerial(now time.Time) {
+	y, m, d := now.Date()
+	today := uint32(y*1000000+int(m)*10000+d*100) + 1
+	if rd.Serial < today {
+		rd.Serial = today
+		return
+	}
+
+	rd.Serial++
+}
+
+// SerialAfter reports whether rd.Serial is greater than serial in the RFC
+// 1982 serial number arithmetic, i.e. whether a zone with this SOA is newer
+// than a zone with the serial number serial.
+func (rd *SOA) SerialAfter(serial uint32) bool {
+	return SerialLess(serial, rd.Serial)
 }
 
 // SPF represents SPF RR RDATA. The format of this type is identical to the TXT
@@ -3896,24 +6134,16 @@ type SPF struct {
 
 // Implementation of dns.Wirer
 func (rd *SPF) Encode(b *dns.Wirebuf) {
-	for _, s := range rd.S {
-		dns.CharString(s).Encode(b)
-	}
+	CharStrings(rd.S).Encode(b)
 }
 
 // Implementation of dns.Wirer
 func (rd *SPF) Decode(b []byte, pos *int, sniffer dns.WireDecodeSniffer) (err error) {
 	p0 := &b[*pos]
-	s := []string{}
-	for *pos < len(b) {
-		var part dns.CharString
-		if err = part.Decode(b, pos, sniffer); err != nil {
-			return
-		}
-
-		s = append(s, string(part))
+	if err = (*CharStrings)(&rd.S).Decode(b, pos, sniffer); err != nil {
+		return
 	}
-	rd.S = s
+
 	if sniffer != nil {
 		sniffer(p0, &b[*pos-1], dns.SniffRDataSPF, rd)
 	}
@@ -3921,11 +6151,7 @@ func (rd *SPF) Decode(b []byte, pos *int, sniffer dns.WireDecodeSniffer) (err er
 }
 
 func (rd *SPF) String() string {
-	a := []string{}
-	for _, s := range rd.S {
-		a = append(a, fmt.Sprintf(`"%s"`, quote(s)))
-	}
-	return strings.Join(a, " ")
+	return CharStrings(rd.S).String()
 }
 
 type SRV struct {
@@ -3984,132 +6210,475 @@ type SRV struct {
 }
 
 // Implementation of dns.Wirer
-func (rd *SRV) Encode(b *dns.Wirebuf) {
+func (rd *SRV) Encode(b *dns.Wirebuf) {
+	dns.Octets2(rd.Priority).Encode(b)
+	dns.Octets2(rd.Weight).Encode(b)
+	dns.Octets2(rd.Port).Encode(b)
+	dns.DomainName(rd.Target).Encode(b)
+}
+
+// Implementation of dns.Wirer
+func (rd *SRV) Decode(b []byte, pos *int, sniffer dns.WireDecodeSniffer) (err error) {
+	p0 := &b[*pos]
+	if err = (*dns.Octets2)(&rd.Priority).Decode(b, pos, sniffer); err != nil {
+		return
+	}
+
+	if err = (*dns.Octets2)(&rd.Weight).Decode(b, pos, sniffer); err != nil {
+		return
+	}
+
+	if err = (*dns.Octets2)(&rd.Port).Decode(b, pos, sniffer); err != nil {
+		return
+	}
+
+	if err = (*dns.DomainName)(&rd.Target).Decode(b, pos, sniffer); err != nil {
+		return
+	}
+
+	if sniffer != nil {
+		sniffer(p0, &b[*pos-1], dns.SniffRDataSRV, rd)
+	}
+	return
+}
+
+func (rd *SRV) String() string {
+	return fmt.Sprintf("%d %d %d %s", rd.Priority, rd.Weight, rd.Port, escapeName(rd.Target))
+}
+
+// SSHFPAlgorithm is the type of the SSHFP RData Algorithm field
+type SSHFPAlgorithm byte
+
+// Values of SSHFPAlgorithm
+const (
+	SSHFPAlgorithmReserved SSHFPAlgorithm = iota
+	SSHFPAlgorithmRSA
+	SSHFPAlgorithmDSA
+)
+
+// SSHFPType is the type of the SSHFP RData Type field
+type SSHFPType byte
+
+// Values of SSHFPType
+const (
+	SSHFPTypeReserved SSHFPType = iota
+	SSHFPTypeSHA1
+)
+
+// SSHFP type represents RData of a SSHFP RR.  The SSHFP resource record (RR)
+// is used to store a fingerprint of an    SSH public host key that is
+// associated with a Domain Name System (DNS) name.
+type SSHFP struct {
+	// This algorithm number octet describes the algorithm of the public
+	// key.  The following values are assigned:
+	//
+	//           Value    Algorithm name
+	//           -----    --------------
+	//           0        reserved
+	//           1        RSA
+	//           2        DSS
+	Algorithm SSHFPAlgorithm
+	// The fingerprint type octet describes the message-digest algorithm
+	// used to calculate the fingerprint of the public key.  The following
+	// values are assigned:
+	//
+	//           Value    Fingerprint type
+	//           -----    ----------------
+	//           0        reserved
+	//           1        SHA-1
+	Type SSHFPType
+	// The fingerprint is calculated over the public key blob as described
+	// in: Ylonen, T. and C. Lonvick, Ed., "The Secure Shell (SSH)
+	// Transport Layer Protocol", RFC 4253, January 2006.
+	//
+	// The message-digest algorithm is presumed to produce an opaque octet
+	// string output, which is placed as-is in the RDATA fingerprint field.
+	Fingerprint []byte
+}
+
+// Implementation of dns.Wirer
+func (rd *SSHFP) Encode(b *dns.Wirebuf) {
+	dns.Octet(rd.Algorithm).Encode(b)
+	dns.Octet(rd.Type).Encode(b)
+	b.Buf = append(b.Buf, rd.Fingerprint...)
+}
+
+// Implementation of dns.Wirer
+func (rd *SSHFP) Decode(b []byte, pos *int, sniffer dns.WireDecodeSniffer) (err error) {
+	p0 := &b[*pos]
+	if err = (*dns.Octet)(&rd.Algorithm).Decode(b, pos, sniffer); err != nil {
+		return
+	}
+
+	if err = (*dns.Octet)(&rd.Type).Decode(b, pos, sniffer); err != nil {
+		return
+	}
+
+	n := len(b) - *pos
+	if n <= 0 {
+		return fmt.Errorf("(*SSHFP).Decode: no fingerprint data")
+	}
+
+	rd.Fingerprint = make([]byte, n)
+	copy(rd.Fingerprint, b[*pos:])
+	*pos += n
+	if sniffer != nil {
+		sniffer(p0, &b[*pos-1], dns.SniffRDataSSHFP, rd)
+	}
+	return
+}
+
+func (rd *SSHFP) String() string {
+	return fmt.Sprintf("%d %d %x",
+		rd.Algorithm,
+		rd.Type,
+		rd.Fingerprint,
+	)
+}
+
+// SvcParamKey is the type of the SVCB/HTTPS SvcParam key (RFC 9460/14.3.2).
+type SvcParamKey uint16
+
+// Values of SvcParamKey
+const (
+	SvcMandatory     SvcParamKey = iota // Mandatory keys in this RR.
+	SvcALPN                             // Additional supported protocols.
+	SvcNoDefaultALPN                    // No support for default protocol.
+	SvcPort                             // Port for alternative endpoint.
+	SvcIPv4Hint                         // IPv4 address hints.
+	SvcECH                              // TLS Encrypted ClientHello configuration.
+	SvcIPv6Hint                         // IPv6 address hints.
+)
+
+var svcParamKeyStr = map[SvcParamKey]string{
+	SvcMandatory:     "mandatory",
+	SvcALPN:          "alpn",
+	SvcNoDefaultALPN: "no-default-alpn",
+	SvcPort:          "port",
+	SvcIPv4Hint:      "ipv4hint",
+	SvcECH:           "ech",
+	SvcIPv6Hint:      "ipv6hint",
+}
+
+func (k SvcParamKey) String() (s string) {
+	var ok bool
+	if s, ok = svcParamKeyStr[k]; !ok {
+		return fmt.Sprintf("key%d", uint16(k))
+	}
+	return
+}
+
+// SvcParam is a SVCB/HTTPS RR service parameter.
+type SvcParam struct {
+	Key   SvcParamKey
+	Value []byte // The SvcParamValue in wire format.
+}
+
+// String returns p in the presentation format (RFC 9460/2.1). Values of known
+// keys which are not well formed are formatted like those of unknown keys.
+func (p SvcParam) String() string {
+	if s, ok := p.value(); ok {
+		if s == "" {
+			return p.Key.String()
+		}
+
+		return p.Key.String() + "=" + s
+	}
+
+	if len(p.Value) == 0 {
+		return fmt.Sprintf("key%d", uint16(p.Key))
+	}
+
+	return fmt.Sprintf(`key%d="%s"`, uint16(p.Key), escapeCharString(string(p.Value)))
+}
+
+// value returns the presentation format of the value of a known key p. ok is
+// false for unknown keys and malformed values.
+func (p SvcParam) value() (s string, ok bool) {
+	v := p.Value
+	var a []string
+	switch p.Key {
+	case SvcMandatory:
+		if len(v) == 0 || len(v)%2 != 0 {
+			return
+		}
+
+		for ; len(v) != 0; v = v[2:] {
+			a = append(a, (SvcParamKey(v[0])<<8 | SvcParamKey(v[1])).String())
+		}
+	case SvcALPN:
+		ids, ok := svcValueList(v)
+		if !ok {
+			return "", false
+		}
+
+		for _, id := range ids {
+			id = strings.Replace(id, `\`, `\\`, -1)
+			a = append(a, strings.Replace(id, ",", `\,`, -1))
+		}
+		return `"` + escapeCharString(strings.Join(a, ",")) + `"`, true
+	case SvcNoDefaultALPN:
+		return "", len(v) == 0
+	case SvcPort:
+		if len(v) != 2 {
+			return
+		}
+
+		return strconv.Itoa(int(v[0])<<8 | int(v[1])), true
+	case SvcECH:
+		return string(strutil.Base64Encode(v)), true
+	case SvcIPv4Hint, SvcIPv6Hint:
+		ips, ok := svcHints(p.Key, v)
+		if !ok {
+			return "", false
+		}
+
+		for _, ip := range ips {
+			a = append(a, ip.String())
+		}
+	default:
+		return
+	}
+	return strings.Join(a, ","), true
+}
+
+// svcValueList decodes a sequence of length prefixed strings, the wire format
+// of an alpn SvcParamValue (RFC 9460/7.1.1).
+func svcValueList(b []byte) (a []string, ok bool) {
+	for len(b) != 0 {
+		n := int(b[0])
+		if n == 0 || 1+n > len(b) {
+			return nil, false
+		}
+
+		a = append(a, string(b[1:1+n]))
+		b = b[1+n:]
+	}
+	return a, len(a) != 0
+}
+
+// svcHints decodes the wire format of an ipv4hint or ipv6hint SvcParamValue
+// (RFC 9460/7.3).
+func svcHints(k SvcParamKey, b []byte) (ips []net.IP, ok bool) {
+	n := net.IPv4len
+	if k == SvcIPv6Hint {
+		n = net.IPv6len
+	}
+	if len(b) == 0 || len(b)%n != 0 {
+		return nil, false
+	}
+
+	for ; len(b) != 0; b = b[n:] {
+		ip := make(net.IP, n)
+		copy(ip, b)
+		ips = append(ips, ip)
+	}
+	return ips, true
+}
+
+type svcParams []SvcParam
+
+func (s svcParams) Len() int           { return len(s) }
+func (s svcParams) Less(i, j int) bool { return s[i].Key < s[j].Key }
+func (s svcParams) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+
+// SVCB holds the SVCB RR RData (RFC 9460).
+type SVCB struct {
+	// The priority of this record. Zero selects the AliasMode, other
+	// values select the ServiceMode and lower values are preferred.
+	Priority uint16
+	// The domain name of either the alias target (AliasMode) or the
+	// alternative endpoint (ServiceMode).
+	TargetName string
+	// The service parameters. The AliasMode has none. Params are encoded
+	// in ascending key order regardless of their order here.
+	Params []SvcParam
+}
+
+// Implementation of dns.Wirer
+func (rd *SVCB) Encode(b *dns.Wirebuf) {
 	dns.Octets2(rd.Priority).Encode(b)
-	dns.Octets2(rd.Weight).Encode(b)
-	dns.Octets2(rd.Port).Encode(b)
-	dns.DomainName(rd.Target).Encode(b)
+	b.DisableCompression()
+	dns.DomainName(rd.TargetName).Encode(b)
+	b.EnableCompression()
+	params := append(svcParams(nil), rd.Params...)
+	sort.Stable(params)
+	for _, p := range params {
+		dns.Octets2(p.Key).Encode(b)
+		dns.Octets2(len(p.Value)).Encode(b)
+		b.Buf = append(b.Buf, p.Value...)
+	}
 }
 
 // Implementation of dns.Wirer
-func (rd *SRV) Decode(b []byte, pos *int, sniffer dns.WireDecodeSniffer) (err error) {
+func (rd *SVCB) Decode(b []byte, pos *int, sniffer dns.WireDecodeSniffer) (err error) {
 	p0 := &b[*pos]
+	if err = rd.decode(b, pos, sniffer); err != nil {
+		return fmt.Errorf("(*SVCB).Decode: %s", err)
+	}
+
+	if sniffer != nil {
+		sniffer(p0, &b[*pos-1], dns.SniffRDataSVCB, rd)
+	}
+	return
+}
+
+// decode decodes the wire layout shared by SVCB and HTTPS.
+func (rd *SVCB) decode(b []byte, pos *int, sniffer dns.WireDecodeSniffer) (err error) {
 	if err = (*dns.Octets2)(&rd.Priority).Decode(b, pos, sniffer); err != nil {
 		return
 	}
 
-	if err = (*dns.Octets2)(&rd.Weight).Decode(b, pos, sniffer); err != nil {
+	if err = checkUncompressed(b, *pos); err != nil {
 		return
 	}
 
-	if err = (*dns.Octets2)(&rd.Port).Decode(b, pos, sniffer); err != nil {
+	if err = (*dns.DomainName)(&rd.TargetName).Decode(b, pos, sniffer); err != nil {
 		return
 	}
 
-	if err = (*dns.DomainName)(&rd.Target).Decode(b, pos, sniffer); err != nil {
-		return
+	rd.Params = nil
+	for *pos < len(b) {
+		var k, n dns.Octets2
+		if err = k.Decode(b, pos, sniffer); err != nil {
+			return
+		}
+
+		key := SvcParamKey(k)
+		if i := len(rd.Params); i != 0 && key <= rd.Params[i-1].Key {
+			return fmt.Errorf("SvcParam key %s not in ascending order", key)
+		}
+
+		if err = n.Decode(b, pos, sniffer); err != nil {
+			return
+		}
+
+		if *pos+int(n) > len(b) {
+			return fmt.Errorf("SvcParam %s value length %d overflows RDATA", key, n)
+		}
+
+		v := make([]byte, n)
+		copy(v, b[*pos:])
+		*pos += int(n)
+		rd.Params = append(rd.Params, SvcParam{key, v})
 	}
+	return
+}
 
-	if sniffer != nil {
-		sniffer(p0, &b[*pos-1], dns.SniffRDataSRV, rd)
+func (rd *SVCB) String() string {
+	a := []string{strconv.Itoa(int(rd.Priority)), escapeName(rd.TargetName)}
+	for _, p := range rd.Params {
+		a = append(a, p.String())
+	}
+	return strings.Join(a, " ")
+}
+
+// Param returns the value of the SvcParam with key k and whether rd has it.
+func (rd *SVCB) Param(k SvcParamKey) (v []byte, ok bool) {
+	for _, p := range rd.Params {
+		if p.Key == k {
+			return p.Value, true
+		}
 	}
 	return
 }
 
-func (rd *SRV) String() string {
-	return fmt.Sprintf("%d %d %d %s", rd.Priority, rd.Weight, rd.Port, rd.Target)
+// SetParam sets the value of the SvcParam with key k to v, adding the
+// SvcParam if rd doesn't have it yet.
+func (rd *SVCB) SetParam(k SvcParamKey, v []byte) {
+	for i, p := range rd.Params {
+		if p.Key == k {
+			rd.Params[i].Value = v
+			return
+		}
+	}
+	rd.Params = append(rd.Params, SvcParam{k, v})
 }
 
-// SSHFPAlgorithm is the type of the SSHFP RData Algorithm field
-type SSHFPAlgorithm byte
+// ALPN returns the protocol IDs of the alpn SvcParam of rd (RFC 9460/7.1), or
+// nil if rd has none or it is malformed.
+func (rd *SVCB) ALPN() []string {
+	v, _ := rd.Param(SvcALPN)
+	a, _ := svcValueList(v)
+	return a
+}
 
-// Values of SSHFPAlgorithm
-const (
-	SSHFPAlgorithmReserved SSHFPAlgorithm = iota
-	SSHFPAlgorithmRSA
-	SSHFPAlgorithmDSA
-)
+// SetALPN sets the alpn SvcParam of rd to the protocol IDs ids. Every ID must
+// be 1 to 255 bytes long.
+func (rd *SVCB) SetALPN(ids []string) {
+	var v []byte
+	for _, id := range ids {
+		v = append(append(v, byte(len(id))), id...)
+	}
+	rd.SetParam(SvcALPN, v)
+}
 
-// SSHFPType is the type of the SSHFP RData Type field
-type SSHFPType byte
+// Port returns the port SvcParam of rd (RFC 9460/7.2) and whether rd has a
+// well formed one.
+func (rd *SVCB) Port() (port uint16, ok bool) {
+	v, _ := rd.Param(SvcPort)
+	if len(v) != 2 {
+		return
+	}
 
-// Values of SSHFPType
-const (
-	SSHFPTypeReserved SSHFPType = iota
-	SSHFPTypeSHA1
-)
+	return uint16(v[0])<<8 | uint16(v[1]), true
+}
 
-// SSHFP type represents RData of a SSHFP RR.  The SSHFP resource record (RR)
-// is used to store a fingerprint of an    SSH public host key that is
-// associated with a Domain Name System (DNS) name.
-type SSHFP struct {
-	// This algorithm number octet describes the algorithm of the public
-	// key.  The following values are assigned:
-	//
-	//           Value    Algorithm name
-	//           -----    --------------
-	//           0        reserved
-	//           1        RSA
-	//           2        DSS
-	Algorithm SSHFPAlgorithm
-	// The fingerprint type octet describes the message-digest algorithm
-	// used to calculate the fingerprint of the public key.  The following
-	// values are assigned:
-	//
-	//           Value    Fingerprint type
-	//           -----    ----------------
-	//           0        reserved
-	//           1        SHA-1
-	Type SSHFPType
-	// The fingerprint is calculated over the public key blob as described
-	// in: Ylonen, T. and C. Lonvick, Ed., "The Secure Shell (SSH)
-	// Transport Layer Protocol", RFC 4253, January 2006.
-	//
-	// The message-digest algorithm is presumed to produce an opaque octet
-	// string output, which is placed as-is in the RDATA fingerprint field.
-	Fingerprint []byte
+// IPv4Hint returns the addresses of the ipv4hint SvcParam of rd (RFC
+// 9460/7.3), or nil if rd has none or it is malformed.
+func (rd *SVCB) IPv4Hint() []net.IP {
+	v, _ := rd.Param(SvcIPv4Hint)
+	ips, _ := svcHints(SvcIPv4Hint, v)
+	return ips
 }
 
-// Implementation of dns.Wirer
-func (rd *SSHFP) Encode(b *dns.Wirebuf) {
-	dns.Octet(rd.Algorithm).Encode(b)
-	dns.Octet(rd.Type).Encode(b)
-	b.Buf = append(b.Buf, rd.Fingerprint...)
+// IPv6Hint returns the addresses of the ipv6hint SvcParam of rd (RFC
+// 9460/7.3), or nil if rd has none or it is malformed.
+func (rd *SVCB) IPv6Hint() []net.IP {
+	v, _ := rd.Param(SvcIPv6Hint)
+	ips, _ := svcHints(SvcIPv6Hint, v)
+	return ips
 }
 
-// Implementation of dns.Wirer
-func (rd *SSHFP) Decode(b []byte, pos *int, sniffer dns.WireDecodeSniffer) (err error) {
-	p0 := &b[*pos]
-	if err = (*dns.Octet)(&rd.Algorithm).Decode(b, pos, sniffer); err != nil {
-		return
+// equal compares rd and y, ignoring the order of their Params.
+func (rd *SVCB) equal(y *SVCB) bool {
+	if rd.Priority != y.Priority ||
+		!dns.NamesEqual(rd.TargetName, y.TargetName) ||
+		len(rd.Params) != len(y.Params) {
+		return false
 	}
 
-	if err = (*dns.Octet)(&rd.Type).Decode(b, pos, sniffer); err != nil {
-		return
+	a := append(svcParams(nil), rd.Params...)
+	b := append(svcParams(nil), y.Params...)
+	sort.Stable(a)
+	sort.Stable(b)
+	for i, p := range a {
+		if p.Key != b[i].Key || !bytes.Equal(p.Value, b[i].Value) {
+			return false
+		}
 	}
+	return true
+}
 
-	n := len(b) - *pos
-	if n <= 0 {
-		return fmt.Errorf("(*SSHFP).Decode: no fingerprint data")
+// HTTPS holds the HTTPS RR RData (RFC 9460/9), which is the same as the SVCB
+// RR RData.
+type HTTPS struct {
+	SVCB
+}
+
+// Implementation of dns.Wirer
+func (rd *HTTPS) Decode(b []byte, pos *int, sniffer dns.WireDecodeSniffer) (err error) {
+	p0 := &b[*pos]
+	if err = rd.decode(b, pos, sniffer); err != nil {
+		return fmt.Errorf("(*HTTPS).Decode: %s", err)
 	}
 
-	rd.Fingerprint = make([]byte, n)
-	copy(rd.Fingerprint, b[*pos:])
-	*pos += n
 	if sniffer != nil {
-		sniffer(p0, &b[*pos-1], dns.SniffRDataSSHFP, rd)
+		sniffer(p0, &b[*pos-1], dns.SniffRDataHTTPS, rd)
 	}
 	return
 }
 
-func (rd *SSHFP) String() string {
-	return fmt.Sprintf("%d %d %x",
-		rd.Algorithm,
-		rd.Type,
-		rd.Fingerprint,
-	)
-}
-
 /*
 TA represent TA RR RDATA.
 
@@ -4235,12 +6804,14 @@ func (rd *TALINK) Encode(b *dns.Wirebuf) {
 // Implementation of dns.Wirer
 func (rd *TALINK) Decode(b []byte, pos *int, sniffer dns.WireDecodeSniffer) (err error) {
 	p0 := &b[*pos]
-	if err = (*dns.DomainName)(&rd.PrevName).Decode(b, pos, sniffer); err != nil {
-		return
-	}
+	for _, name := range []*string{&rd.PrevName, &rd.NextName} {
+		if err = checkUncompressed(b, *pos); err != nil {
+			return fmt.Errorf("(*TALINK).Decode: %s", err)
+		}
 
-	if err = (*dns.DomainName)(&rd.NextName).Decode(b, pos, sniffer); err != nil {
-		return
+		if err = (*dns.DomainName)(name).Decode(b, pos, sniffer); err != nil {
+			return
+		}
 	}
 
 	if sniffer != nil {
@@ -4250,7 +6821,7 @@ func (rd *TALINK) Decode(b []byte, pos *int, sniffer dns.WireDecodeSniffer) (err
 }
 
 func (rd *TALINK) String() string {
-	return fmt.Sprintf("%s %s", rd.PrevName, rd.NextName)
+	return fmt.Sprintf("%s %s", escapeName(rd.PrevName), escapeName(rd.NextName))
 }
 
 // TKEYMode type is the type of the TKEY Mode field.
@@ -4386,7 +6957,7 @@ func (rd *TKEY) Decode(b []byte, pos *int, sniffer dns.WireDecodeSniffer) (err e
 	}
 
 	n := int(u16)
-	if *pos+n > len(b)+1 {
+	if *pos+n > len(b) {
 		return fmt.Errorf("(*rr.TKEY).Decode() - buffer underflow")
 	}
 
@@ -4399,7 +6970,7 @@ func (rd *TKEY) Decode(b []byte, pos *int, sniffer dns.WireDecodeSniffer) (err e
 	}
 
 	n = int(u16)
-	if *pos+n > len(b)+1 {
+	if *pos+n > len(b) {
 		return fmt.Errorf("(*rr.TKEY).Decode() - buffer underflow")
 	}
 
@@ -4416,7 +6987,7 @@ func (rd *TKEY) Decode(b []byte, pos *int, sniffer dns.WireDecodeSniffer) (err e
 func (rd *TKEY) String() string {
 	return fmt.Sprintf(
 		"%s %s %s %s %s %x %x",
-		rd.Algorithm,
+		escapeName(rd.Algorithm),
 		time.Unix(rd.Inception.UTC().Unix(), 0),
 		time.Unix(rd.Expiration.UTC().Unix(), 0),
 		rd.Mode,
@@ -4518,6 +7089,11 @@ type TLSA struct {
 
 // Implementation of dns.Wirer
 func (rd *TLSA) Encode(b *dns.Wirebuf) {
+	rd.encode(b)
+}
+
+// encode encodes the wire layout shared by TLSA and SMIMEA.
+func (rd *TLSA) encode(b *dns.Wirebuf) {
 	dns.Octet(rd.Usage).Encode(b)
 	dns.Octet(rd.Selector).Encode(b)
 	dns.Octet(rd.MatchingType).Encode(b)
@@ -4527,6 +7103,18 @@ func (rd *TLSA) Encode(b *dns.Wirebuf) {
 // Implementation of dns.Wirer
 func (rd *TLSA) Decode(b []byte, pos *int, sniffer dns.WireDecodeSniffer) (err error) {
 	p0 := &b[*pos]
+	if err = rd.decode(b, pos, sniffer); err != nil {
+		return
+	}
+
+	if sniffer != nil {
+		sniffer(p0, &b[*pos-1], dns.SniffRDataTLSA, rd)
+	}
+	return
+}
+
+// decode decodes the wire layout shared by TLSA and SMIMEA.
+func (rd *TLSA) decode(b []byte, pos *int, sniffer dns.WireDecodeSniffer) (err error) {
 	if err = (*dns.Octet)(&rd.Usage).Decode(b, pos, sniffer); err != nil {
 		return
 	}
@@ -4540,15 +7128,11 @@ func (rd *TLSA) Decode(b []byte, pos *int, sniffer dns.WireDecodeSniffer) (err e
 	}
 
 	rd.Certificate = nil
-	if *pos < len(b)-1 {
+	if *pos < len(b) {
 		rd.Certificate = make([]byte, len(b[*pos:]))
 		copy(rd.Certificate, b[*pos:])
 		*pos = len(b)
 	}
-
-	if sniffer != nil {
-		sniffer(p0, &b[*pos-1], dns.SniffRDataTLSA, rd)
-	}
 	return
 }
 
@@ -4717,7 +7301,7 @@ func (rd *TSIG) Decode(b []byte, pos *int, sniffer dns.WireDecodeSniffer) (err e
 func (rd *TSIG) String() string {
 	return fmt.Sprintf(
 		"%s %s %s %x %d %s %x",
-		rd.AlgorithmName,
+		escapeName(rd.AlgorithmName),
 		time.Unix(rd.TimeSigned.UTC().Unix(), 0),
 		rd.Fudge,
 		rd.MAC,
@@ -4734,24 +7318,16 @@ type TXT struct {
 
 // Implementation of dns.Wirer
 func (rd *TXT) Encode(b *dns.Wirebuf) {
-	for _, s := range rd.S {
-		dns.CharString(s).Encode(b)
-	}
+	CharStrings(rd.S).Encode(b)
 }
 
 // Implementation of dns.Wirer
 func (rd *TXT) Decode(b []byte, pos *int, sniffer dns.WireDecodeSniffer) (err error) {
 	p0 := &b[*pos]
-	s := []string{}
-	for *pos < len(b) {
-		var part dns.CharString
-		if err = part.Decode(b, pos, sniffer); err != nil {
-			return
-		}
-
-		s = append(s, string(part))
+	if err = (*CharStrings)(&rd.S).Decode(b, pos, sniffer); err != nil {
+		return
 	}
-	rd.S = s
+
 	if sniffer != nil {
 		sniffer(p0, &b[*pos-1], dns.SniffRDataTXT, rd)
 	}
@@ -4759,11 +7335,7 @@ func (rd *TXT) Decode(b []byte, pos *int, sniffer dns.WireDecodeSniffer) (err er
 }
 
 func (rd *TXT) String() string {
-	a := []string{}
-	for _, s := range rd.S {
-		a = append(a, fmt.Sprintf(`"%s"`, quote(s)))
-	}
-	return strings.Join(a, " ")
+	return CharStrings(rd.S).String()
 }
 
 /*
@@ -4886,21 +7458,24 @@ type WKS struct {
 func (rd *WKS) Encode(b *dns.Wirebuf) {
 	ip4(rd.Address).Encode(b)
 	dns.Octet(rd.Protocol).Encode(b)
-	bits := make([]byte, 0, 1024/8)
+	var bits []byte
 	for k := range rd.Ports {
 		i := int(k)
 		x := i >> 3
-		mask := 1 << uint(i&7)
-		if x >= len(bits) {
-			bits = bits[:x+1]
+		for x >= len(bits) {
+			bits = append(bits, 0)
 		}
-		bits[x] |= byte(mask)
+		bits[x] |= 0x80 >> uint(i&7)
 	}
 	b.Buf = append(b.Buf, bits...)
 }
 
 // Implementation of dns.Wirer
 func (rd *WKS) Decode(b []byte, pos *int, sniffer dns.WireDecodeSniffer) (err error) {
+	if *pos+5 > len(b) {
+		return fmt.Errorf("(*rr.WKS).Decode() - buffer underflow")
+	}
+
 	p0 := &b[*pos]
 	if err = (*ip4)(&rd.Address).Decode(b, pos, sniffer); err != nil {
 		return
@@ -4911,23 +7486,14 @@ func (rd *WKS) Decode(b []byte, pos *int, sniffer dns.WireDecodeSniffer) (err er
 	}
 
 	rd.Ports = map[IP_Port]struct{}{}
-	n := len(b) - *pos
-	if n == 0 {
-		if sniffer != nil {
-			sniffer(p0, &b[*pos-1], dns.SniffRDataWKS, rd)
-		}
-		return
-	}
-
-	b = b[*pos:]
-	for i, v := range b {
-		for bit := 0; v != 0; bit, v = bit+1, v>>1 {
-			if v&1 != 0 {
+	for i, v := range b[*pos:] {
+		for bit := 0; v != 0; bit, v = bit+1, v<<1 {
+			if v&0x80 != 0 {
 				rd.Ports[IP_Port(i<<3+bit)] = struct{}{}
 			}
 		}
 	}
-	*pos += n
+	*pos = len(b)
 	if sniffer != nil {
 		sniffer(p0, &b[*pos-1], dns.SniffRDataWKS, rd)
 	}
@@ -4943,10 +7509,15 @@ func (rd *WKS) String() string {
 		proto = strconv.Itoa(int(rd.Protocol))
 	}
 	buf.WriteString(proto)
+	ports := make([]int, 0, len(rd.Ports))
 	for k := range rd.Ports {
-		port := IP_Ports[k]
+		ports = append(ports, int(k))
+	}
+	sort.Ints(ports)
+	for _, k := range ports {
+		port := IP_Ports[IP_Port(k)]
 		if port == "" {
-			port = strconv.Itoa(int(k))
+			port = strconv.Itoa(k)
 		}
 		buf.WriteString(" ")
 		buf.WriteString(port)
@@ -4992,10 +7563,10 @@ const (
 	TYPE_AAAA       // 28 IP6 Address                                 [RFC3596]
 	TYPE_LOC        // 29 Location Information                        [RFC1876]
 	TYPE_NXT        // 30 Next Domain - OBSOLETE                      [RFC3755][RFC2535]
-	TYPE_EID        // 31 Endpoint Identifier                         [Patton]*
-	TYPE_NIMLOC     // 32 Nimrod Locator                              [Patton]*
+	TYPE_EID        // 31 Endpoint Identifier                         [Patton]
+	TYPE_NIMLOC     // 32 Nimrod Locator                              [Patton]
 	TYPE_SRV        // 33 Server Selection                            [RFC2782]
-	TYPE_ATMA       // 34 ATM Address                                 [ATMDOC]*
+	TYPE_ATMA       // 34 ATM Address                                 [ATMDOC]
 	TYPE_NAPTR      // 35 Naming Authority Pointer                    [RFC2915][RFC2168][RFC3403]
 	TYPE_KX         // 36 Key Exchanger                               [RFC2230]
 	TYPE_CERT       // 37 CERT                                        [RFC4398]
@@ -5013,16 +7584,29 @@ const (
 	TYPE_DHCID      // 49 DHCID                                       [RFC4701]
 	TYPE_NSEC3      // 50 NSEC3                                       [RFC5155]
 	TYPE_NSEC3PARAM // 51 NSEC3PARAM                                  [RFC5155]
+	_               // 52 TLSA, see TYPE_TLSA                         [RFC6698]
+	TYPE_SMIMEA     // 53 S/MIME cert association                     [RFC8162]
 )
 
 const (
 	_ Type = iota + 54
 
-	TYPE_HIP    // 55 Host Identity Protocol                      [RFC5205]
-	TYPE_NINFO  // 56 NINFO                                       [Reid]*
-	TYPE_RKEY   // 57 RKEY                                        [Reid]*
-	TYPE_TALINK // 58 Trust Anchor LINK                           [Wijngaards]*
-	TYPE_CDS    // 59 Child DS                                    [Barwood]*
+	TYPE_HIP        // 55 Host Identity Protocol                      [RFC5205]
+	TYPE_NINFO      // 56 NINFO                                       [Reid]*
+	TYPE_RKEY       // 57 RKEY                                        [Reid]*
+	TYPE_TALINK     // 58 Trust Anchor LINK                           [Wijngaards]*
+	TYPE_CDS        // 59 Child DS                                    [Barwood]*
+	_               // 60 Child DNSKEY                                [RFC7344]
+	TYPE_OPENPGPKEY // 61 OpenPGP Key                                 [RFC7929]
+	TYPE_CSYNC      // 62 Child-To-Parent Synchronization             [RFC7477]
+	TYPE_ZONEMD     // 63 Message Digest Over Zone Data               [RFC8976]
+)
+
+const (
+	_ Type = iota + 63
+
+	TYPE_SVCB  // 64 General Purpose Service Binding             [RFC9460]
+	TYPE_HTTPS // 65 HTTPS Binding                               [RFC9460]
 )
 
 const (
@@ -5083,6 +7667,7 @@ var Types = map[Type]string{
 	TYPE_CDS:        "CDS",
 	TYPE_CERT:       "CERT",
 	TYPE_CNAME:      "CNAME",
+	TYPE_CSYNC:      "CSYNC",
 	TYPE_DHCID:      "DHCID",
 	TYPE_DLV:        "DLV",
 	TYPE_DNAME:      "DNAME",
@@ -5093,6 +7678,7 @@ var Types = map[Type]string{
 	TYPE_GPOS:       "GPOS",
 	TYPE_HINFO:      "HINFO",
 	TYPE_HIP:        "HIP",
+	TYPE_HTTPS:      "HTTPS",
 	TYPE_IPSECKEY:   "IPSECKEY",
 	TYPE_ISDN:       "ISDN",
 	TYPE_IXFR:       "IXFR",
@@ -5121,6 +7707,7 @@ var Types = map[Type]string{
 	TYPE_NULL:       "NULL",
 	TYPE_NXDOMAIN:   "NXDOMAIN",
 	TYPE_NXT:        "NXT",
+	TYPE_OPENPGPKEY: "OPENPGPKEY",
 	TYPE_OPT:        "OPT",
 	TYPE_PTR:        "PTR",
 	TYPE_PX:         "PX",
@@ -5130,10 +7717,12 @@ var Types = map[Type]string{
 	TYPE_RT:         "RT",
 	TYPE_SIG:        "SIG",
 	TYPE_SINK:       "SINK",
+	TYPE_SMIMEA:     "SMIMEA",
 	TYPE_SOA:        "SOA",
 	TYPE_SPF:        "SPF",
 	TYPE_SRV:        "SRV",
 	TYPE_SSHFP:      "SSHFP",
+	TYPE_SVCB:       "SVCB",
 	TYPE_TA:         "TA",
 	TYPE_TALINK:     "TALINK",
 	TYPE_TKEY:       "TKEY",
@@ -5146,6 +7735,7 @@ var Types = map[Type]string{
 	TYPE_URI:        "URI",
 	TYPE_WKS:        "WKS",
 	TYPE_X25:        "X25",
+	TYPE_ZONEMD:     "ZONEMD",
 }
 
 func (t Type) String() (s string) {
@@ -5156,6 +7746,63 @@ func (t Type) String() (s string) {
 	return
 }
 
+// IsPseudo reports whether t is one of the NODATA and NXDOMAIN pseudo types
+// used by this package for negative caching. Pseudo types never appear on the
+// wire.
+func (t Type) IsPseudo() bool {
+	return t == TYPE_NODATA || t == TYPE_NXDOMAIN
+}
+
+// IsMeta reports whether t is a meta type or a QTYPE (RFC 6895/3.1), i.e. OPT
+// or a type in the 128-255 range, like TSIG, AXFR or ANY.
+func (t Type) IsMeta() bool {
+	return t == TYPE_OPT || t >= 128 && t <= 255
+}
+
+// IsData reports whether RRs of type t can legitimately appear in a zone,
+// i.e. t is none of the reserved type 0, a meta type or a pseudo type.
+func (t Type) IsData() bool {
+	return t != 0 && !t.IsMeta() && !t.IsPseudo()
+}
+
+var typeByName = map[string]Type{}
+
+func init() {
+	for t, s := range Types {
+		typeByName[s] = t
+	}
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (t Type) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (t *Type) UnmarshalText(b []byte) (err error) {
+	var ok bool
+	if *t, ok = TypeByName(string(b)); !ok {
+		return fmt.Errorf("unknown type %q", b)
+	}
+
+	return
+}
+
+// TypeByName returns the Type which has the mnemonic s. The lookup is case
+// insensitive. The generic TYPEnnnn form (RFC 3597/5) is recognized as well.
+func TypeByName(s string) (t Type, ok bool) {
+	s = strings.ToUpper(s)
+	if t, ok = typeByName[s]; ok {
+		return
+	}
+
+	var n uint16
+	if n, ok = genericCode(s, "TYPE"); ok {
+		t = Type(n)
+	}
+	return
+}
+
 // Implementation of dns.Wirer
 func (t Type) Encode(b *dns.Wirebuf) {
 	dns.Octets2(t).Encode(b)
@@ -5206,3 +7853,75 @@ func (rd *X25) Decode(b []byte, pos *int, sniffer dns.WireDecodeSniffer) (err er
 func (rd *X25) String() string {
 	return fmt.Sprintf(`"%s"`, quote(rd.PSDN))
 }
+
+// Values of the ZONEMD Scheme and HashAlgorithm fields (RFC 8976/5)
+const (
+	ZONEMDSchemeSimple = 1
+
+	ZONEMDHashSHA384 = 1
+	ZONEMDHashSHA512 = 2
+)
+
+var zonemdDigestLen = map[byte]int{
+	ZONEMDHashSHA384: 48,
+	ZONEMDHashSHA512: 64,
+}
+
+// ZONEMD holds the ZONEMD RR RData (RFC 8976), a message digest of the zone
+// contents.
+type ZONEMD struct {
+	// The serial number of the zone's SOA RR the digest was computed for.
+	Serial uint32
+	// The method used to construct the digest, see ZONEMDSchemeSimple.
+	Scheme byte
+	// The cryptographic hash algorithm, see ZONEMDHashSHA384 and
+	// ZONEMDHashSHA512.
+	HashAlgorithm byte
+	// The zone digest.
+	Digest []byte
+}
+
+// Implementation of dns.Wirer
+func (rd *ZONEMD) Encode(b *dns.Wirebuf) {
+	dns.Octets4(rd.Serial).Encode(b)
+	dns.Octet(rd.Scheme).Encode(b)
+	dns.Octet(rd.HashAlgorithm).Encode(b)
+	b.Buf = append(b.Buf, rd.Digest...)
+}
+
+// Implementation of dns.Wirer
+func (rd *ZONEMD) Decode(b []byte, pos *int, sniffer dns.WireDecodeSniffer) (err error) {
+	p0 := &b[*pos]
+	if err = (*dns.Octets4)(&rd.Serial).Decode(b, pos, sniffer); err != nil {
+		return
+	}
+
+	if err = (*dns.Octet)(&rd.Scheme).Decode(b, pos, sniffer); err != nil {
+		return
+	}
+
+	if err = (*dns.Octet)(&rd.HashAlgorithm).Decode(b, pos, sniffer); err != nil {
+		return
+	}
+
+	n := len(b) - *pos
+	if e, ok := zonemdDigestLen[rd.HashAlgorithm]; ok && n != e {
+		return fmt.Errorf("(*ZONEMD).Decode: digest length %d, expected %d", n, e)
+	}
+
+	if n < 12 {
+		return fmt.Errorf("(*ZONEMD).Decode: digest length %d too short", n)
+	}
+
+	rd.Digest = make([]byte, n)
+	copy(rd.Digest, b[*pos:])
+	*pos += n
+	if sniffer != nil {
+		sniffer(p0, &b[*pos-1], dns.SniffRDataZONEMD, rd)
+	}
+	return
+}
+
+func (rd *ZONEMD) String() string {
+	return fmt.Sprintf("%d %d %d %x", rd.Serial, rd.Scheme, rd.HashAlgorithm, rd.Digest)
+}
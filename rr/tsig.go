@@ -0,0 +1,90 @@
+// Copyright (c) 2011 CZ.NIC z.s.p.o. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// blame: jnml, labs.nic.cz
+
+package rr
+
+import (
+	"crypto"
+	"crypto/hmac"
+	_ "crypto/md5"
+	"fmt"
+	"github.com/cznic/dns"
+	"time"
+)
+
+// TSIG algorithm names (RFC 4635/2)
+const (
+	HMAC_MD5    = "hmac-md5.sig-alg.reg.int."
+	HMAC_SHA1   = "hmac-sha1."
+	HMAC_SHA224 = "hmac-sha224."
+	HMAC_SHA256 = "hmac-sha256."
+	HMAC_SHA384 = "hmac-sha384."
+	HMAC_SHA512 = "hmac-sha512."
+)
+
+var tsigHash = map[string]crypto.Hash{
+	HMAC_MD5:    crypto.MD5,
+	HMAC_SHA1:   crypto.SHA1,
+	HMAC_SHA224: crypto.SHA224,
+	HMAC_SHA256: crypto.SHA256,
+	HMAC_SHA384: crypto.SHA384,
+	HMAC_SHA512: crypto.SHA512,
+}
+
+// variables returns the TSIG variables of rd (RFC 2845/3.4.2) which follow
+// the key name, class and TTL of the TSIG RR, i.e. the algorithm name in
+// canonical form, the time signed, fudge, error, other len and other data.
+func (rd *TSIG) variables() []byte {
+	b := dns.NewWirebuf()
+	b.DisableCompression()
+	dns.DomainName(dns.CanonicalName(rd.AlgorithmName)).Encode(b)
+	secs := rd.TimeSigned.UTC().Unix()
+	for i := 0; i < 6; i++ {
+		dns.Octet(secs >> 40).Encode(b)
+		secs <<= 8
+	}
+	dns.Octets2(rd.Fudge / time.Second).Encode(b)
+	dns.Octets2(rd.Error).Encode(b)
+	dns.Octets2(len(rd.OtherData)).Encode(b)
+	b.Buf = append(b.Buf, rd.OtherData...)
+	return b.Buf
+}
+
+// ComputeMAC returns the HMAC, keyed by secret, of msg followed by the TSIG
+// variables of rd (RFC 2845/3.4). The key name, class and TTL of the TSIG RR
+// are the first TSIG variables, but they are not a part of the RDATA, so msg
+// must already end with them, see (*RR).ComputeTSIGMAC. Supported algorithms
+// are HMAC_MD5, HMAC_SHA1, HMAC_SHA224, HMAC_SHA256, HMAC_SHA384 and
+// HMAC_SHA512.
+func (rd *TSIG) ComputeMAC(msg []byte, secret []byte) (mac []byte, err error) {
+	h, ok := tsigHash[dns.CanonicalName(rd.AlgorithmName)]
+	if !ok || !h.Available() {
+		return nil, fmt.Errorf("(*TSIG).ComputeMAC: unsupported algorithm %q", rd.AlgorithmName)
+	}
+
+	m := hmac.New(h.New, secret)
+	m.Write(msg)
+	m.Write(rd.variables())
+	return m.Sum(nil), nil
+}
+
+// ComputeTSIGMAC returns the MAC of msg, which is the DNS message without the
+// TSIG RR and with its ID set to the TSIG OriginalID, as computed by the
+// ComputeMAC method of rr's TSIG RData using rr's owner name as the key name.
+func (rr *RR) ComputeTSIGMAC(msg []byte, secret []byte) (mac []byte, err error) {
+	rd, ok := rr.RData.(*TSIG)
+	if !ok {
+		return nil, fmt.Errorf("(*RR).ComputeTSIGMAC: %T is not a TSIG", rr.RData)
+	}
+
+	b := dns.NewWirebuf()
+	b.DisableCompression()
+	b.Buf = append(b.Buf, msg...)
+	dns.DomainName(dns.CanonicalName(rr.Name)).Encode(b)
+	Class(255).Encode(b) // ANY
+	dns.Octets4(0).Encode(b)
+	return rd.ComputeMAC(b.Buf, secret)
+}
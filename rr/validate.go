@@ -0,0 +1,209 @@
+// Copyright (c) 2011 CZ.NIC z.s.p.o. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// blame: jnml, labs.nic.cz
+
+package rr
+
+import (
+	"fmt"
+	"net"
+)
+
+// Validator is implemented by RData types having semantic constraints which
+// their Encode methods don't check.
+type Validator interface {
+	// Validate returns an error describing the first violated constraint,
+	// if any.
+	Validate() error
+}
+
+// Validate checks the RData of rr if it implements Validator.
+func (rr *RR) Validate() (err error) {
+	if rr.RData == nil {
+		return fmt.Errorf("%s %s: missing RDATA", rr.Name, rr.Type)
+	}
+
+	if v, ok := rr.RData.(Validator); ok {
+		if err = v.Validate(); err != nil {
+			return fmt.Errorf("%s %s: %s", rr.Name, rr.Type, err)
+		}
+	}
+	return
+}
+
+// Check reports an error if the RR type of rr doesn't match the concrete
+// type of its RData, e.g. TYPE_A with a *MX RData. Generic *RDATA and RData
+// types unknown to this package match any RR type.
+func (rr *RR) Check() error {
+	if rr.RData == nil {
+		return fmt.Errorf("%s %s: missing RDATA", rr.Name, rr.Type)
+	}
+
+	if t, ok := rdataType(rr.RData); ok && t != rr.Type {
+		return fmt.Errorf("%s %s: %T is %s RDATA", rr.Name, rr.Type, rr.RData, t)
+	}
+
+	return nil
+}
+
+func validateCharStrings(a []string) error {
+	for _, s := range a {
+		if len(s) > 255 {
+			return fmt.Errorf("<character-string> %q, len > 255", s)
+		}
+	}
+	return nil
+}
+
+func validateDigest(d []byte) error {
+	if len(d) == 0 {
+		return fmt.Errorf("missing digest")
+	}
+
+	return nil
+}
+
+// Validate implements Validator. The address must be an IPv4 address.
+func (rd *A) Validate() error {
+	if net.IP(rd.Address).To4() == nil {
+		return fmt.Errorf("%s is not an IPv4 address", rd.Address)
+	}
+
+	return nil
+}
+
+// Validate implements Validator. The address must be an IPv6 address which is
+// not an IPv4 one, e.g. "::ffff:192.0.2.1" is rejected.
+func (rd *AAAA) Validate() error {
+	if ip := net.IP(rd.Address); len(ip) != net.IPv6len || ip.To4() != nil {
+		return fmt.Errorf("%s is not an IPv6 address", rd.Address)
+	}
+
+	return nil
+}
+
+// Validate implements Validator. An AESA address must be 20 octets long, an
+// E.164 address must be a non empty string of ASCII digits.
+func (rd *ATMA) Validate() error {
+	switch rd.Format {
+	case ATMAFormatAESA:
+		if n := len(rd.Address); n != 20 {
+			return fmt.Errorf("AESA address len %d != 20", n)
+		}
+	case ATMAFormatE164:
+		if len(rd.Address) == 0 {
+			return fmt.Errorf("empty E.164 address")
+		}
+
+		for _, c := range rd.Address {
+			if c < '0' || c > '9' {
+				return fmt.Errorf("invalid E.164 address %q", rd.Address)
+			}
+		}
+	default:
+		return fmt.Errorf("unknown ATMA format %d", rd.Format)
+	}
+	return nil
+}
+
+// Validate implements Validator. The protocol must be 3 (RFC 4034/2.1.2) and
+// the key must be present.
+func (rd *DNSKEY) Validate() error {
+	if rd.Protocol != 3 {
+		return fmt.Errorf("invalid DNSKEY protocol %d", rd.Protocol)
+	}
+
+	if len(rd.Key) == 0 {
+		return fmt.Errorf("missing key data")
+	}
+
+	return nil
+}
+
+// Validate implements Validator.
+func (rd *DLV) Validate() error {
+	return validateDigest(rd.Digest)
+}
+
+// Validate implements Validator.
+func (rd *DS) Validate() error {
+	return validateDigest(rd.Digest)
+}
+
+// Validate implements Validator. Each coordinate must be a real number, an
+// optional sign followed by decimal digits with an optional fraction.
+func (rd *GPOS) Validate() error {
+	for _, v := range []string{rd.Longitude, rd.Latitude, rd.Altitude} {
+		if !isGPOSReal(v) {
+			return fmt.Errorf("invalid GPOS coordinate %q", v)
+		}
+	}
+	return validateCharStrings([]string{rd.Longitude, rd.Latitude, rd.Altitude})
+}
+
+// Validate implements Validator.
+func (rd *HINFO) Validate() error {
+	return validateCharStrings([]string{rd.Cpu, rd.Os})
+}
+
+// Validate implements Validator.
+func (rd *NINFO) Validate() error {
+	return validateCharStrings(rd.ZSData)
+}
+
+// Validate implements Validator.
+func (rd *NSEC) Validate() (err error) {
+	_, err = TypesDecode(rd.TypeBitMaps)
+	return
+}
+
+// Validate implements Validator. Besides the NSEC3PARAM constraints the hash
+// must be 1 to 255 octets long and the type bit maps must be well formed.
+func (rd *NSEC3) Validate() (err error) {
+	if err = rd.NSEC3PARAM.Validate(); err != nil {
+		return
+	}
+
+	if n := len(rd.NextHashedOwnerName); n == 0 || n > 255 {
+		return fmt.Errorf("invalid NSEC3 hash length %d", n)
+	}
+
+	_, err = TypesDecode(rd.TypeBitMaps)
+	return
+}
+
+// Validate implements Validator. The salt must not be longer than 255
+// octets.
+func (rd *NSEC3PARAM) Validate() error {
+	if n := len(rd.Salt); n > 255 {
+		return fmt.Errorf("NSEC3 salt len %d > 255", n)
+	}
+
+	return nil
+}
+
+// Validate implements Validator.
+func (rd *NULL) Validate() error {
+	if n := len(rd.Data); n > 0xFFFF {
+		return fmt.Errorf("NULL RDATA len %d > 65535", n)
+	}
+
+	return nil
+}
+
+// Validate implements Validator.
+func (rd *SPF) Validate() error {
+	return validateCharStrings(rd.S)
+}
+
+// Validate implements Validator.
+func (rd *TA) Validate() error {
+	return validateDigest(rd.Digest)
+}
+
+// Validate implements Validator.
+func (rd *TXT) Validate() error {
+	return validateCharStrings(rd.S)
+}
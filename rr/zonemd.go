@@ -0,0 +1,103 @@
+// Copyright (c) 2011 CZ.NIC z.s.p.o. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// blame: jnml, labs.nic.cz
+
+package rr
+
+import (
+	"bytes"
+	"crypto"
+	"fmt"
+	"github.com/cznic/dns"
+	"sort"
+)
+
+// compareCanonical orders a and b in the DNSSEC canonical order (RFC
+// 4034/6.1, 6.3), i.e. by owner name, type and canonical RDATA.
+func compareCanonical(a, b *RR) int {
+	if n := compareCanonicalNames(a.Name, b.Name); n != 0 {
+		return n
+	}
+
+	switch {
+	case a.Type < b.Type:
+		return -1
+	case a.Type > b.Type:
+		return 1
+	}
+
+	return bytes.Compare(CanonicalRData(a.RData, a.Type), CanonicalRData(b.RData, b.Type))
+}
+
+// ZoneDigest returns the digest of the zone r as defined for the ZONEMD RR
+// (RFC 8976/3). r must contain the zone's SOA RR. Records not at or below the
+// apex, pseudo type records, the apex ZONEMD RRs and the RRSIGs covering them
+// are excluded, duplicate records are digested only once. The only supported
+// scheme is ZONEMDSchemeSimple, supported hash algorithms are ZONEMDHashSHA384
+// and ZONEMDHashSHA512.
+func (r RRs) ZoneDigest(scheme byte, hashAlg byte) (digest []byte, err error) {
+	if scheme != ZONEMDSchemeSimple {
+		return nil, fmt.Errorf("ZoneDigest: unsupported scheme %d", scheme)
+	}
+
+	var h crypto.Hash
+	switch hashAlg {
+	case ZONEMDHashSHA384:
+		h = crypto.SHA384
+	case ZONEMDHashSHA512:
+		h = crypto.SHA512
+	default:
+		return nil, fmt.Errorf("ZoneDigest: unsupported hash algorithm %d", hashAlg)
+	}
+
+	var apex string
+	for _, rr := range r {
+		if rr.Type == TYPE_SOA {
+			apex = dns.CanonicalName(rr.Name)
+			break
+		}
+	}
+	if apex == "" {
+		return nil, fmt.Errorf("ZoneDigest: missing SOA")
+	}
+
+	var y RRs
+	for _, rr := range r {
+		name := dns.CanonicalName(rr.Name)
+		if rr.Type.IsPseudo() || name != apex && !isSubdomain(name, apex) {
+			continue
+		}
+
+		if name == apex {
+			if rr.Type == TYPE_ZONEMD {
+				continue
+			}
+
+			if sig, ok := rr.RData.(*RRSIG); ok && sig.Type == TYPE_ZONEMD {
+				continue
+			}
+		}
+
+		y = append(y, rr)
+	}
+	sort.Sort(Sorter{y, compareCanonical})
+
+	hash := h.New()
+	var last []byte
+	for _, rr := range y {
+		x := *rr
+		x.Name = dns.CanonicalName(x.Name)
+		rd := RDATA(CanonicalRData(rr.RData, rr.Type))
+		x.RData = &rd
+		b := wireBytes(&x)
+		if bytes.Equal(b, last) {
+			continue
+		}
+
+		hash.Write(b)
+		last = b
+	}
+	return hash.Sum(nil), nil
+}
@@ -22,7 +22,7 @@ func (s CharString) Encode(b *Wirebuf) {
 	}
 
 	Octet(n).Encode(b)
-	b.Buf = append(b.Buf, []byte(string(s))...)
+	b.Buf = append(b.Buf, s...)
 }
 
 // Implementation of Wirer
@@ -52,35 +52,111 @@ func (s CharString) Quoted() string {
 	return strings.Replace(string(s), `"`, `\"`, -1)
 }
 
-// DomainName is a DNS <domain-name> (RFC 1035) implementing Wirer.
+// DomainName is a DNS <domain-name> (RFC 1035) implementing Wirer. It's held
+// in the RFC 1035/5.1 presentation format, i.e. a dot, a backslash or a non
+// printable octet within a label is escaped as `\.`, `\\` or `\DDD`.
 type DomainName string
 
+// escapeLabel returns the wire label s escaped as described for DomainName.
+func escapeLabel(s string) string {
+	i := 0
+	for ; i < len(s); i++ {
+		if c := s[i]; c == '.' || c == '\\' || c < ' ' || c > '~' {
+			break
+		}
+	}
+	if i == len(s) {
+		return s
+	}
+
+	b := make([]byte, i, len(s)+8)
+	copy(b, s)
+	for ; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '.' || c == '\\':
+			b = append(b, '\\', c)
+		case c < ' ' || c > '~':
+			b = append(b, '\\', '0'+c/100, '0'+c/10%10, '0'+c%10)
+		default:
+			b = append(b, c)
+		}
+	}
+	return string(b)
+}
+
+// unescapeLabel returns the first label of the presentation format name s with
+// the `\X` and `\DDD` escapes resolved and the length of the label within s,
+// not including the dot separating it from the rest of s.
+func unescapeLabel(s string) (label string, n int) {
+	for ; n < len(s) && s[n] != '.' && s[n] != '\\'; n++ {
+	}
+	if n == len(s) || s[n] == '.' {
+		return s[:n], n
+	}
+
+	b := []byte(s[:n])
+	for n < len(s) && s[n] != '.' {
+		c := s[n]
+		n++
+		if c == '\\' && n < len(s) {
+			if n+2 < len(s) && isDigit(s[n]) && isDigit(s[n+1]) && isDigit(s[n+2]) {
+				if v := int(s[n]-'0')*100 + int(s[n+1]-'0')*10 + int(s[n+2]-'0'); v <= 255 {
+					b = append(b, byte(v))
+					n += 3
+					continue
+				}
+			}
+
+			c = s[n]
+			n++
+		}
+		b = append(b, c)
+	}
+	return string(b), n
+}
+
 // Implementation of Wirer
 func (s DomainName) Encode(b *Wirebuf) {
 	name := RootedName(string(s))
-	labels, err := Labels(name)
-	if err != nil {
-		panic(err)
-	}
+	// name is the not yet encoded rest, the labels are not collected in a
+	// slice to avoid allocations. n is the wire length of the name so far.
+	n := 0
+	for {
+		label, i := unescapeLabel(name)
+		if len(label) > 63 {
+			panic(fmt.Errorf("invalid label %q, len > 63", label))
+		}
+
+		if n += len(label) + 1; n > 255 { // RFC 1035/2.3.4
+			panic(fmt.Errorf("invalid name %q, len > 255", string(s)))
+		}
+
+		if label == "" {
+			if name != "" && name != "." {
+				panic(fmt.Errorf("invalid name %q, empty label", string(s)))
+			}
+
+			Octet(0).Encode(b)
+			return
+		}
 
-	for _, label := range labels {
-		if b.zip >= 0 && label != "" {
+		if b.zip >= 0 {
 			if pos, ok := b.names[name]; ok { // RFC 1034/4.1.4. Message compression
 				Octets2(0xC000 | pos).Encode(b)
 				return
 			}
 		}
 
-		pos := len(b.Buf)
-		if name != "" && pos < 0x4000 {
+		if pos := len(b.Buf); pos < 0x4000 {
 			b.names[name] = pos
-			name = name[len(label)+1:]
 		}
 		CharString(label).Encode(b)
+		name = name[i+1:]
 	}
 }
 
 func (s *DomainName) decode(b []byte, pos *int) (err error) {
+	start := *pos
 	labels := []string{}
 	label := CharString("")
 	for {
@@ -95,6 +171,10 @@ func (s *DomainName) decode(b []byte, pos *int) (err error) {
 			}
 
 			p := int(ptr) ^ 0xC000
+			if p >= start { // RFC 1035/4.1.4: pointers go to a prior occurrence
+				return fmt.Errorf("DomainName.Decode() - invalid compression pointer %#x at %#x", p, *pos-2)
+			}
+
 			var name DomainName
 			if err = name.decode(b, &p); err != nil {
 				return
@@ -109,7 +189,7 @@ func (s *DomainName) decode(b []byte, pos *int) (err error) {
 			return
 		}
 
-		labels = append(labels, string(label))
+		labels = append(labels, escapeLabel(string(label)))
 		if label == "" {
 			if len(labels) != 1 {
 				*s = DomainName(strings.Join(labels, "."))
@@ -229,6 +309,27 @@ func (w *Wirebuf) DisableCompression() {
 	w.zip--
 }
 
+// Truncate discards w.Buf[n:] and forgets any names at offsets >= n, so they
+// are not used as compression targets later.
+func (w *Wirebuf) Truncate(n int) {
+	w.Buf = w.Buf[:n]
+	for name, pos := range w.names {
+		if pos >= n {
+			delete(w.names, name)
+		}
+	}
+}
+
+// Reset empties w for reuse, keeping the capacity of w.Buf and of the
+// compression table. The compression state is reset to enabled.
+func (w *Wirebuf) Reset() {
+	w.Buf = w.Buf[:0]
+	for name := range w.names {
+		delete(w.names, name)
+	}
+	w.zip = 0
+}
+
 // WireDecodeSniffed tags data passed to WireDecodeSniffer
 type WireDecodeSniffed int
 
@@ -306,6 +407,17 @@ const (
 	SniffRDataX25                          // X25 resource record data
 	SniffRR                                // Any or unknown/unsupported type resource record
 	SniffType                              // A TYPE
+	SniffRDataHTTPS                        // HTTPS resource record data
+	SniffRDataSVCB                         // SVCB resource record data
+	SniffRDataAPL                          // APL resource record data
+	SniffRDataOPENPGPKEY                   // OPENPGPKEY resource record data
+	SniffRDataSMIMEA                       // SMIMEA resource record data
+	SniffRDataCSYNC                        // CSYNC resource record data
+	SniffRDataZONEMD                       // ZONEMD resource record data
+	SniffRDataNINFO                        // NINFO resource record data
+	SniffRDataEID                          // EID resource record data
+	SniffRDataNIMLOC                       // NIMLOC resource record data
+	SniffRDataATMA                         // ATMA resource record data
 ) //TODO +test
 
 // WireDecodeSniffer is the type of the hook called by Wirer.Decode.  p0 points
@@ -9,8 +9,10 @@ package zone
 import (
 	"errors"
 	"flag"
+	"fmt"
 	"github.com/cznic/dns/rr"
 	"io/ioutil"
+	"net"
 	"os"
 	"strings"
 	"testing"
@@ -108,6 +110,106 @@ func TestLoadBinary(t *testing.T) {
 	t.Log("TODO") //TODO
 }
 
+func TestLoadStringZone(t *testing.T) {
+	zone := rr.RRs{
+		{"example.com.", rr.TYPE_SOA, rr.CLASS_IN, 3600, &rr.SOA{"ns.example.com.", "hostmaster.example.com.", 1, 2, 3, 4, 5}},
+		{"example.com.", rr.TYPE_NS, rr.CLASS_IN, 3600, &rr.NS{"ns.example.com."}},
+		{"example.com.", rr.TYPE_MX, rr.CLASS_IN, 3600, &rr.MX{10, "mail.example.org."}},
+		{"ns.example.com.", rr.TYPE_A, rr.CLASS_IN, 86400, &rr.A{net.ParseIP("192.0.2.1")}},
+		{"www.sub.example.com.", rr.TYPE_TXT, rr.CLASS_IN, 300, &rr.TXT{[]string{`a\b`, `"q"`}}},
+		{"www.sub.example.com.", rr.TYPE_TXT, rr.CLASS_CH, 300, &rr.TXT{[]string{"chaos"}}},
+		{"example.net.", rr.TYPE_CNAME, rr.CLASS_CH, 60, &rr.CNAME{"example.com."}},
+		{`foo\.example.com.`, rr.TYPE_A, rr.CLASS_IN, 60, &rr.A{net.ParseIP("192.0.2.2")}},
+		{`bar\\.example.com.`, rr.TYPE_A, rr.CLASS_IN, 60, &rr.A{net.ParseIP("192.0.2.3")}},
+	}
+
+	f, err := ioutil.TempFile("", "gotest")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fn := f.Name()
+	defer os.Remove(fn)
+	_, err = f.WriteString(zone.StringZone("example.com."))
+	if ec := f.Close(); err == nil {
+		err = ec
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got rr.RRs
+	if err = Load(
+		fn,
+		func(e string) bool {
+			t.Error(e)
+			return false
+		},
+		func(r *rr.RR) bool {
+			got = append(got, r)
+			return true
+		},
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	if g, e := len(got), len(zone); g != e {
+		t.Fatalf("%d != %d", g, e)
+	}
+
+	for i, r := range got {
+		if !r.Equal(zone[i]) || r.TTL != zone[i].TTL {
+			t.Errorf("%d: %s != %s", i, r, zone[i])
+		}
+	}
+}
+
+func TestLoadOrigin(t *testing.T) {
+	f, err := ioutil.TempFile("", "gotest")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fn := f.Name()
+	defer os.Remove(fn)
+	_, err = f.WriteString(`@ 1 IN A 192.0.2.1
+$ORIGIN example.com.
+@ 2 IN A 192.0.2.2
+ 3 CH A 192.0.2.3
+www 4 A 192.0.2.4
+$ORIGIN sub ; relative to example.com.
+mail 5 A 192.0.2.5
+x.example.net. 6 IN A 192.0.2.6
+$ORIGIN .
+7 7 A 192.0.2.7
+`)
+	if ec := f.Close(); err == nil {
+		err = ec
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	if err = Load(
+		fn,
+		func(e string) bool {
+			t.Error(e)
+			return false
+		},
+		func(r *rr.RR) bool {
+			got = append(got, fmt.Sprintf("%s %s", r.Name, r.Class))
+			return true
+		},
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	if g, e := strings.Join(got, "|"), "@ IN|example.com. IN|example.com. CH|www.example.com. CH|mail.sub.example.com. CH|x.example.net. IN|7. IN"; g != e {
+		t.Fatalf("\n%s\n%s", g, e)
+	}
+}
+
 func BenchmarkParser(b *testing.B) {
 	b.StopTimer()
 	fn := *optZone
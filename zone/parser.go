@@ -2089,7 +2089,11 @@ yydefault:
 		}
 	case 295:
 		{
-			yyVAL.rrd = &rr.GPOS{yyS[yypt-2].float, yyS[yypt-1].float, yyS[yypt-0].float}
+			rd := &rr.GPOS{yyS[yypt-2].str, yyS[yypt-1].str, yyS[yypt-0].str}
+			if err := rd.Validate(); err != nil {
+				yylex.Error(err.Error())
+			}
+			yyVAL.rrd = rd
 		}
 	case 296:
 		{
@@ -1581,7 +1581,11 @@ gpos:
 	}
 	tFLOAT tFLOAT tFLOAT
 	{
-		$$ = &rr.GPOS{$3, $4, $5}
+		rd := &rr.GPOS{$<str>3, $<str>4, $<str>5}
+		if err := rd.Validate(); err != nil {
+			yylex.Error(err.Error())
+		}
+		$$ = rd
 	}
 
 
@@ -11,7 +11,6 @@ package zone
 
 import (
 	"bufio"
-	"bytes"
 	"encoding/hex"
 	"errors"
 	"fmt"
@@ -20,6 +19,7 @@ import (
 	"strings"
 	"unicode"
 
+	"github.com/cznic/dns"
 	"github.com/cznic/dns/rr"
 	"github.com/cznic/fileutil"
 )
@@ -37,10 +37,13 @@ type lexStackItem struct {
 	src        *bufio.Reader
 	prev       *lexStackItem
 	inParen    bool
+	origin     string
 }
 
 type lex struct {
 	lexStackItem
+	owner string
+	class rr.Class
 }
 
 func (l *lex) include(name string, source *bufio.Reader) {
@@ -83,7 +86,10 @@ func (l *lex) getc(c byte) byte {
 func newLex(name string, source *bufio.Reader, errHandler func(e string) bool, rrHandler func(*rr.RR) bool) (l *lex) {
 	l = &lex{}
 	l.errHandler = errHandler
-	l.rrHandler = rrHandler
+	l.rrHandler = func(r *rr.RR) bool {
+		l.resolve(r)
+		return rrHandler(r)
+	}
 	l.line = 1
 	l.name = name
 	l.src = source
@@ -91,6 +97,37 @@ func newLex(name string, source *bufio.Reader, errHandler func(e string) bool, r
 	return
 }
 
+// absName returns name made absolute with respect to the current $ORIGIN.
+// If there's no $ORIGIN, name is returned as written.
+func (l *lex) absName(name string) string {
+	switch {
+	case l.origin == "" || dns.IsRooted(name):
+		return name
+	case name == "@":
+		return l.origin
+	case l.origin == ".":
+		return name + "."
+	}
+	return name + "." + l.origin
+}
+
+// resolve completes the owner name and class of r the way RFC 1035/5.1
+// specifies: a blank owner repeats the previous one, @ and relative names are
+// resolved against the current $ORIGIN and an omitted class repeats the
+// previous one.
+func (l *lex) resolve(r *rr.RR) {
+	if r.Name == "" {
+		r.Name = l.owner
+	} else {
+		r.Name = l.absName(r.Name)
+	}
+	l.owner = r.Name
+	if r.Class == 0 {
+		r.Class = l.class
+	}
+	l.class = r.Class
+}
+
 func (l *lex) Error(e string) {
 	e = fmt.Sprintf("%s:%d:%d - %s", l.name, l.line, l.column, e)
 	if l.errHandler != nil {
@@ -3351,7 +3388,7 @@ yystart312:
 	case c == ';':
 		goto yystate4
 	case c == '\\':
-		goto yystate11
+		goto yystate353
 	case c == '\n' || c == '\r':
 		goto yystate5
 	case c == '\t' || c == ' ':
@@ -3538,6 +3575,8 @@ yystate333:
 		goto yystate332
 	case c == '_':
 		goto yystate336
+	case c == '\\':
+		goto yystate353
 	case c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z':
 		goto yystate334
 	}
@@ -3551,6 +3590,8 @@ yystate334:
 		goto yystate335
 	case c == '.':
 		goto yystate333
+	case c == '\\':
+		goto yystate353
 	case c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z':
 		goto yystate334
 	}
@@ -3562,6 +3603,8 @@ yystate335:
 		goto yyabort
 	case c == '-':
 		goto yystate335
+	case c == '\\':
+		goto yystate353
 	case c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z':
 		goto yystate334
 	}
@@ -3571,6 +3614,8 @@ yystate336:
 	switch {
 	default:
 		goto yyabort
+	case c == '\\':
+		goto yystate353
 	case c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z':
 		goto yystate334
 	}
@@ -4064,6 +4109,15 @@ yystart352:
 		goto yystate311
 	}
 
+yystate353:
+	c = l.getc(c)
+	switch {
+	default:
+		goto yyabort
+	case c >= '\x01' && c <= '\t' || c >= '\v' && c <= '\f' || c >= '\x0e' && c <= 'ÿ':
+		goto yystate334
+	}
+
 yyrule1: // ^[ \t]+$
 yyrule2: // [ \t]+$
 yyrule3: // [ \t]*;.*
@@ -4075,8 +4129,22 @@ yyrule4: // ^$INCLUDE
 
 	goto yystate0
 yyrule5: // ^$ORIGIN
+	{
 
-	goto yystate0
+		for c != '\n' && c != '\r' && c != 0 {
+			c = l.getc(c)
+		}
+		arg := string(l.buf[len("$ORIGIN"):])
+		if i := strings.Index(arg, ";"); i >= 0 {
+			arg = arg[:i]
+		}
+		if f := strings.Fields(arg); len(f) == 1 && dns.IsRooted(l.absName(f[0])) {
+			l.origin = l.absName(f[0])
+		} else {
+			l.Error("invalid $ORIGIN directive")
+		}
+		goto yystate0
+	}
 yyrule6: // ^$TTL
 	{
 
@@ -4110,7 +4178,7 @@ yyrule9: // \)
 		ret = '\n'
 		goto yystate0
 	}
-yyrule10: // ^{domain-name}
+yyrule10: // ^{owner-name}
 	{
 
 		ret = tDOMAIN_NAME
@@ -4131,7 +4199,9 @@ yyrule12: // \\#
 yyrule13: // \"(\\.|[^\\"\n\r])*\"
 	{
 
-		lval.str = string(bytes.Replace(l.buf[1:len(l.buf)-1], []byte(`\"`), []byte(`"`), -1))
+		if lval.str, err = rr.UnescapeCharString(string(l.buf[1 : len(l.buf)-1])); err != nil {
+			l.Error(err.Error())
+		}
 		return tQSTR
 	}
 yyrule14: // \.
@@ -177,6 +177,8 @@ func (c *Compiler) Write(r *rr.RR) (err error) {
 // On unrecoverable errors like file not found the load is aborted
 // and Error returned.
 // rrHandler is invoked for every resource record found in the zone file.
+// Owner names are resolved against the current $ORIGIN, if any, a blank owner
+// name and an omitted class repeat those of the previous record.
 // If rrHandler returns false the loading is aborted and returns nil Error.
 func Load(fname string, errHandler func(e string) bool, rrHandler func(rr *rr.RR) bool) (err error) {
 